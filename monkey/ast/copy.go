@@ -0,0 +1,284 @@
+package ast
+
+// Copy returns a deep copy of the tree rooted at node, so that the copy can
+// be changed with Modify without affecting the original.
+func Copy(node Node) Node {
+	switch node := node.(type) {
+	case *Program:
+		c := &Program{Statements: make([]Statement, len(node.Statements))}
+		for i, statement := range node.Statements {
+			c.Statements[i] = copyStatement(statement)
+		}
+		return c
+
+	case *ExpressionStatement:
+		c := *node
+		c.Expression = copyExpression(node.Expression)
+		return &c
+
+	case *LetStatement:
+		c := *node
+		c.Name = copyIdentifier(node.Name)
+		c.Pattern = copyExpression(node.Pattern)
+		c.Value = copyExpression(node.Value)
+		return &c
+
+	case *ReturnStatement:
+		c := *node
+		c.ReturnValue = copyExpression(node.ReturnValue)
+		return &c
+
+	case *YieldStatement:
+		c := *node
+		c.Value = copyExpression(node.Value)
+		return &c
+
+	case *ThrowStatement:
+		c := *node
+		c.Value = copyExpression(node.Value)
+		return &c
+
+	case *ImportStatement:
+		c := *node
+		if node.Path != nil {
+			path := *node.Path
+			c.Path = &path
+		}
+		c.Alias = copyIdentifier(node.Alias)
+		return &c
+
+	case *ExportStatement:
+		c := *node
+		if node.Statement != nil {
+			c.Statement = Copy(node.Statement).(*LetStatement)
+		}
+		return &c
+
+	case *BlockStatement:
+		return copyBlock(node)
+
+	case *Identifier:
+		return copyIdentifier(node)
+
+	case *NullExpression:
+		c := *node
+		return &c
+
+	case *IntegerLiteral:
+		c := *node
+		return &c
+
+	case *FloatLiteral:
+		c := *node
+		return &c
+
+	case *StringLiteral:
+		c := *node
+		return &c
+
+	case *Boolean:
+		c := *node
+		return &c
+
+	case *PrefixExpression:
+		c := *node
+		c.Right = copyExpression(node.Right)
+		return &c
+
+	case *InfixExpression:
+		c := *node
+		c.Left = copyExpression(node.Left)
+		c.Right = copyExpression(node.Right)
+		return &c
+
+	case *IfExpression:
+		c := *node
+		c.Condition = copyExpression(node.Condition)
+		c.Consequence = copyBlock(node.Consequence)
+		c.Alternative = copyBlock(node.Alternative)
+		return &c
+
+	case *TryExpression:
+		c := *node
+		c.Block = copyBlock(node.Block)
+		c.CatchParam = copyExpression(node.CatchParam)
+		c.Catch = copyBlock(node.Catch)
+		c.Finally = copyBlock(node.Finally)
+		return &c
+
+	case *ForExpression:
+		c := *node
+		c.Pattern = copyExpression(node.Pattern)
+		c.Iterable = copyExpression(node.Iterable)
+		c.Body = copyBlock(node.Body)
+		return &c
+
+	case *FunctionLiteral:
+		c := *node
+		c.Parameters = copyExpressions(node.Parameters)
+		c.Body = copyBlock(node.Body)
+		return &c
+
+	case *MacroLiteral:
+		c := *node
+		c.Parameters = make([]*Identifier, len(node.Parameters))
+		for i, param := range node.Parameters {
+			c.Parameters[i] = copyIdentifier(param)
+		}
+		c.Body = copyBlock(node.Body)
+		return &c
+
+	case *CallExpression:
+		c := *node
+		c.Function = copyExpression(node.Function)
+		c.Arguments = copyExpressions(node.Arguments)
+		return &c
+
+	case *ArrayLiteral:
+		c := *node
+		c.Elements = copyExpressions(node.Elements)
+		return &c
+
+	case *SpreadExpression:
+		c := *node
+		c.Value = copyExpression(node.Value)
+		return &c
+
+	case *SpawnExpression:
+		c := *node
+		c.Call = copyExpression(node.Call)
+		return &c
+
+	case *AwaitExpression:
+		c := *node
+		c.Value = copyExpression(node.Value)
+		return &c
+
+	case *SetLiteral:
+		c := *node
+		c.Elements = copyExpressions(node.Elements)
+		return &c
+
+	case *TupleLiteral:
+		c := *node
+		c.Elements = copyExpressions(node.Elements)
+		return &c
+
+	case *IndexExpression:
+		c := *node
+		c.Left = copyExpression(node.Left)
+		c.Index = copyExpression(node.Index)
+		return &c
+
+	case *SliceExpression:
+		c := *node
+		c.Left = copyExpression(node.Left)
+		c.Start = copyExpression(node.Start)
+		c.End = copyExpression(node.End)
+		c.Step = copyExpression(node.Step)
+		return &c
+
+	case *MemberExpression:
+		c := *node
+		c.Object = copyExpression(node.Object)
+		c.Property = copyIdentifier(node.Property)
+		return &c
+
+	case *AssignExpression:
+		c := *node
+		c.Target = copyExpression(node.Target)
+		c.Value = copyExpression(node.Value)
+		return &c
+
+	case *HashLiteral:
+		c := *node
+		c.Pairs = make(map[Expression]Expression, len(node.Pairs))
+		for key, value := range node.Pairs {
+			c.Pairs[copyExpression(key)] = copyExpression(value)
+		}
+		return &c
+
+	case *MatchExpression:
+		c := *node
+		c.Subject = copyExpression(node.Subject)
+		c.Arms = make([]*MatchArm, len(node.Arms))
+		for i, arm := range node.Arms {
+			c.Arms[i] = Copy(arm).(*MatchArm)
+		}
+		return &c
+
+	case *MatchArm:
+		c := *node
+		c.Pattern = copyExpression(node.Pattern)
+		c.Guard = copyExpression(node.Guard)
+		c.Body = copyExpression(node.Body)
+		return &c
+
+	case *ArrayPattern:
+		c := *node
+		c.Elements = copyExpressions(node.Elements)
+		c.Rest = copyIdentifier(node.Rest)
+		return &c
+
+	case *HashPattern:
+		c := *node
+		c.Pairs = make([]*HashPatternPair, len(node.Pairs))
+		for i, pair := range node.Pairs {
+			key := copyExpression(pair.Key)
+			value := key
+			if pair.Value != pair.Key {
+				value = copyExpression(pair.Value)
+			}
+			c.Pairs[i] = &HashPatternPair{Key: key, Value: value}
+		}
+		c.Rest = copyIdentifier(node.Rest)
+		return &c
+	}
+
+	return node
+}
+
+func copyStatement(statement Statement) Statement {
+	if statement == nil {
+		return nil
+	}
+	return Copy(statement).(Statement)
+}
+
+func copyExpression(expression Expression) Expression {
+	if expression == nil {
+		return nil
+	}
+	return Copy(expression).(Expression)
+}
+
+func copyExpressions(expressions []Expression) []Expression {
+	if expressions == nil {
+		return nil
+	}
+	c := make([]Expression, len(expressions))
+	for i, expression := range expressions {
+		c[i] = copyExpression(expression)
+	}
+	return c
+}
+
+func copyBlock(block *BlockStatement) *BlockStatement {
+	if block == nil {
+		return nil
+	}
+	c := *block
+	c.Statements = make([]Statement, len(block.Statements))
+	for i, statement := range block.Statements {
+		c.Statements[i] = copyStatement(statement)
+	}
+	return &c
+}
+
+func copyIdentifier(ident *Identifier) *Identifier {
+	if ident == nil {
+		return nil
+	}
+	c := *ident
+	return &c
+}
@@ -0,0 +1,595 @@
+package ast
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"monkey/token"
+)
+
+// EncodeJSON encodes the tree rooted at node as JSON. Every node becomes an
+// object holding its "kind" (the Go type name, e.g. "InfixExpression"), its
+// "pos" and "token", followed by its children and values under the names of
+// the corresponding struct fields in lower camel case. Missing optional
+// children are null. The Program node has no token or position.
+//
+// DecodeJSON turns the encoding back into an equivalent tree.
+func EncodeJSON(node Node) ([]byte, error) {
+	return json.Marshal(encodeNode(node))
+}
+
+// object is a JSON object whose fields are written in order.
+type object []field
+
+type field struct {
+	name  string
+	value any
+}
+
+func (o object) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, f := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, err := json.Marshal(f.name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(f.value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+type jsonPos struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+type jsonToken struct {
+	Type    string `json:"type"`
+	Literal string `json:"literal"`
+}
+
+func header(kind string, tok token.Token) object {
+	return object{
+		{"kind", kind},
+		{"pos", jsonPos{tok.Pos.Line, tok.Pos.Column}},
+		{"token", jsonToken{string(tok.Type), tok.Literal}},
+	}
+}
+
+func encodeNode(node Node) any {
+	switch n := node.(type) {
+	case *Program:
+		return object{{"kind", "Program"}, {"statements", encodeStatements(n.Statements)}}
+	case *ExpressionStatement:
+		return append(header("ExpressionStatement", n.Token), field{"expression", encodeNode(n.Expression)})
+	case *LetStatement:
+		return append(header("LetStatement", n.Token),
+			field{"name", encodeIdentifier(n.Name)},
+			field{"pattern", encodeNode(n.Pattern)},
+			field{"value", encodeNode(n.Value)})
+	case *ReturnStatement:
+		return append(header("ReturnStatement", n.Token), field{"returnValue", encodeNode(n.ReturnValue)})
+	case *YieldStatement:
+		return append(header("YieldStatement", n.Token), field{"value", encodeNode(n.Value)})
+	case *ThrowStatement:
+		return append(header("ThrowStatement", n.Token), field{"value", encodeNode(n.Value)})
+	case *ImportStatement:
+		var path any
+		if n.Path != nil {
+			path = encodeNode(n.Path)
+		}
+		return append(header("ImportStatement", n.Token), field{"path", path}, field{"alias", encodeIdentifier(n.Alias)})
+	case *ExportStatement:
+		var statement any
+		if n.Statement != nil {
+			statement = encodeNode(n.Statement)
+		}
+		return append(header("ExportStatement", n.Token), field{"statement", statement})
+	case *BlockStatement:
+		return append(header("BlockStatement", n.Token),
+			field{"statements", encodeStatements(n.Statements)},
+			field{"rbrace", jsonPos{n.Rbrace.Line, n.Rbrace.Column}})
+	case *Identifier:
+		return append(header("Identifier", n.Token), field{"value", n.Value})
+	case *NullExpression:
+		return header("NullExpression", n.Token)
+	case *IntegerLiteral:
+		return append(header("IntegerLiteral", n.Token), field{"value", n.Value})
+	case *FloatLiteral:
+		return append(header("FloatLiteral", n.Token), field{"value", n.Value})
+	case *StringLiteral:
+		return append(header("StringLiteral", n.Token), field{"value", n.Value})
+	case *Boolean:
+		return append(header("Boolean", n.Token), field{"value", n.Value})
+	case *PrefixExpression:
+		return append(header("PrefixExpression", n.Token),
+			field{"operator", n.Operator},
+			field{"right", encodeNode(n.Right)})
+	case *InfixExpression:
+		return append(header("InfixExpression", n.Token),
+			field{"left", encodeNode(n.Left)},
+			field{"operator", n.Operator},
+			field{"right", encodeNode(n.Right)})
+	case *IfExpression:
+		return append(header("IfExpression", n.Token),
+			field{"condition", encodeNode(n.Condition)},
+			field{"consequence", encodeBlock(n.Consequence)},
+			field{"alternative", encodeBlock(n.Alternative)})
+	case *TryExpression:
+		return append(header("TryExpression", n.Token),
+			field{"block", encodeBlock(n.Block)},
+			field{"catchParam", encodeNode(n.CatchParam)},
+			field{"catch", encodeBlock(n.Catch)},
+			field{"finally", encodeBlock(n.Finally)})
+	case *ForExpression:
+		return append(header("ForExpression", n.Token),
+			field{"pattern", encodeNode(n.Pattern)},
+			field{"iterable", encodeNode(n.Iterable)},
+			field{"body", encodeBlock(n.Body)})
+	case *FunctionLiteral:
+		return append(header("FunctionLiteral", n.Token),
+			field{"parameters", encodeExpressions(n.Parameters)},
+			field{"body", encodeBlock(n.Body)},
+			field{"generator", n.Generator})
+	case *MacroLiteral:
+		params := make([]any, len(n.Parameters))
+		for i, param := range n.Parameters {
+			params[i] = encodeIdentifier(param)
+		}
+		return append(header("MacroLiteral", n.Token),
+			field{"parameters", params},
+			field{"body", encodeBlock(n.Body)})
+	case *CallExpression:
+		return append(header("CallExpression", n.Token),
+			field{"function", encodeNode(n.Function)},
+			field{"arguments", encodeExpressions(n.Arguments)})
+	case *ArrayLiteral:
+		return append(header("ArrayLiteral", n.Token), field{"elements", encodeExpressions(n.Elements)})
+	case *SpreadExpression:
+		return append(header("SpreadExpression", n.Token), field{"value", encodeNode(n.Value)})
+	case *SpawnExpression:
+		return append(header("SpawnExpression", n.Token), field{"call", encodeNode(n.Call)})
+	case *AwaitExpression:
+		return append(header("AwaitExpression", n.Token), field{"value", encodeNode(n.Value)})
+	case *SetLiteral:
+		return append(header("SetLiteral", n.Token), field{"elements", encodeExpressions(n.Elements)})
+	case *TupleLiteral:
+		return append(header("TupleLiteral", n.Token), field{"elements", encodeExpressions(n.Elements)})
+	case *IndexExpression:
+		return append(header("IndexExpression", n.Token),
+			field{"left", encodeNode(n.Left)},
+			field{"index", encodeNode(n.Index)})
+	case *SliceExpression:
+		return append(header("SliceExpression", n.Token),
+			field{"left", encodeNode(n.Left)},
+			field{"start", encodeNode(n.Start)},
+			field{"end", encodeNode(n.End)},
+			field{"step", encodeNode(n.Step)})
+	case *MemberExpression:
+		return append(header("MemberExpression", n.Token),
+			field{"object", encodeNode(n.Object)},
+			field{"property", encodeIdentifier(n.Property)})
+	case *AssignExpression:
+		return append(header("AssignExpression", n.Token),
+			field{"target", encodeNode(n.Target)},
+			field{"value", encodeNode(n.Value)})
+	case *HashLiteral:
+		pairs := []any{}
+		for _, key := range n.Keys() {
+			pairs = append(pairs, object{{"key", encodeNode(key)}, {"value", encodeNode(n.Pairs[key])}})
+		}
+		return append(header("HashLiteral", n.Token), field{"pairs", pairs})
+	case *MatchExpression:
+		arms := make([]any, len(n.Arms))
+		for i, arm := range n.Arms {
+			arms[i] = encodeNode(arm)
+		}
+		return append(header("MatchExpression", n.Token),
+			field{"subject", encodeNode(n.Subject)},
+			field{"arms", arms})
+	case *MatchArm:
+		return append(header("MatchArm", n.Token),
+			field{"pattern", encodeNode(n.Pattern)},
+			field{"guard", encodeNode(n.Guard)},
+			field{"body", encodeNode(n.Body)})
+	case *ArrayPattern:
+		return append(header("ArrayPattern", n.Token),
+			field{"elements", encodeExpressions(n.Elements)},
+			field{"rest", encodeIdentifier(n.Rest)})
+	case *HashPattern:
+		pairs := make([]any, len(n.Pairs))
+		for i, pair := range n.Pairs {
+			if pair.Key == pair.Value {
+				pairs[i] = object{{"key", encodeNode(pair.Key)}, {"shorthand", true}}
+			} else {
+				pairs[i] = object{{"key", encodeNode(pair.Key)}, {"value", encodeNode(pair.Value)}}
+			}
+		}
+		return append(header("HashPattern", n.Token),
+			field{"pairs", pairs},
+			field{"rest", encodeIdentifier(n.Rest)})
+	}
+	return nil
+}
+
+func encodeStatements(statements []Statement) []any {
+	encoded := make([]any, len(statements))
+	for i, statement := range statements {
+		encoded[i] = encodeNode(statement)
+	}
+	return encoded
+}
+
+func encodeExpressions(expressions []Expression) []any {
+	encoded := make([]any, len(expressions))
+	for i, expression := range expressions {
+		encoded[i] = encodeNode(expression)
+	}
+	return encoded
+}
+
+func encodeBlock(block *BlockStatement) any {
+	if block == nil {
+		return nil
+	}
+	return encodeNode(block)
+}
+
+func encodeIdentifier(ident *Identifier) any {
+	if ident == nil {
+		return nil
+	}
+	return encodeNode(ident)
+}
+
+// DecodeJSON rebuilds a tree from the output of EncodeJSON.
+func DecodeJSON(data []byte) (Node, error) {
+	d := &decoder{}
+	node := d.node(json.RawMessage(data))
+	if d.err != nil {
+		return nil, d.err
+	}
+	if node == nil {
+		return nil, fmt.Errorf("ast: no node to decode")
+	}
+	return node, nil
+}
+
+// decoder keeps the first error it runs into; once it is set every method
+// returns zero values.
+type decoder struct {
+	err error
+}
+
+func (d *decoder) fail(format string, a ...any) {
+	if d.err == nil {
+		d.err = fmt.Errorf("ast: "+format, a...)
+	}
+}
+
+type fields map[string]json.RawMessage
+
+func (d *decoder) node(raw json.RawMessage) Node {
+	if d.err != nil || len(raw) == 0 || string(raw) == "null" {
+		return nil
+	}
+
+	var obj fields
+	if err := json.Unmarshal(raw, &obj); err != nil {
+		d.fail("%s", err)
+		return nil
+	}
+	var kind string
+	d.value(obj, "kind", &kind)
+	if kind == "Program" {
+		return &Program{Statements: d.statements(obj, "statements")}
+	}
+
+	var pos jsonPos
+	var tok jsonToken
+	d.value(obj, "pos", &pos)
+	d.value(obj, "token", &tok)
+	t := token.Token{
+		Type:    token.TokenType(tok.Type),
+		Literal: tok.Literal,
+		Pos:     token.Position{Line: pos.Line, Column: pos.Column},
+	}
+
+	switch kind {
+	case "ExpressionStatement":
+		return &ExpressionStatement{Token: t, Expression: d.expression(obj, "expression")}
+	case "LetStatement":
+		return &LetStatement{
+			Token:   t,
+			Name:    d.identifier(obj, "name"),
+			Pattern: d.expression(obj, "pattern"),
+			Value:   d.expression(obj, "value"),
+		}
+	case "ReturnStatement":
+		return &ReturnStatement{Token: t, ReturnValue: d.expression(obj, "returnValue")}
+	case "YieldStatement":
+		return &YieldStatement{Token: t, Value: d.expression(obj, "value")}
+	case "ThrowStatement":
+		return &ThrowStatement{Token: t, Value: d.expression(obj, "value")}
+	case "ImportStatement":
+		n := &ImportStatement{Token: t, Alias: d.identifier(obj, "alias")}
+		if path := d.expression(obj, "path"); path != nil {
+			n.Path = d.assert(path, "StringLiteral").(*StringLiteral)
+		}
+		return n
+	case "ExportStatement":
+		n := &ExportStatement{Token: t}
+		if statement := d.statement(obj, "statement"); statement != nil {
+			n.Statement = d.assert(statement, "LetStatement").(*LetStatement)
+		}
+		return n
+	case "BlockStatement":
+		n := &BlockStatement{Token: t, Statements: d.statements(obj, "statements")}
+		var rbrace jsonPos
+		d.value(obj, "rbrace", &rbrace)
+		n.Rbrace = token.Position{Line: rbrace.Line, Column: rbrace.Column}
+		return n
+	case "Identifier":
+		n := &Identifier{Token: t}
+		d.value(obj, "value", &n.Value)
+		return n
+	case "NullExpression":
+		return &NullExpression{Token: t}
+	case "IntegerLiteral":
+		n := &IntegerLiteral{Token: t}
+		d.value(obj, "value", &n.Value)
+		return n
+	case "FloatLiteral":
+		n := &FloatLiteral{Token: t}
+		d.value(obj, "value", &n.Value)
+		return n
+	case "StringLiteral":
+		n := &StringLiteral{Token: t}
+		d.value(obj, "value", &n.Value)
+		return n
+	case "Boolean":
+		n := &Boolean{Token: t}
+		d.value(obj, "value", &n.Value)
+		return n
+	case "PrefixExpression":
+		n := &PrefixExpression{Token: t, Right: d.expression(obj, "right")}
+		d.value(obj, "operator", &n.Operator)
+		return n
+	case "InfixExpression":
+		n := &InfixExpression{Token: t, Left: d.expression(obj, "left"), Right: d.expression(obj, "right")}
+		d.value(obj, "operator", &n.Operator)
+		return n
+	case "IfExpression":
+		return &IfExpression{
+			Token:       t,
+			Condition:   d.expression(obj, "condition"),
+			Consequence: d.block(obj, "consequence"),
+			Alternative: d.block(obj, "alternative"),
+		}
+	case "TryExpression":
+		return &TryExpression{
+			Token:      t,
+			Block:      d.block(obj, "block"),
+			CatchParam: d.expression(obj, "catchParam"),
+			Catch:      d.block(obj, "catch"),
+			Finally:    d.block(obj, "finally"),
+		}
+	case "ForExpression":
+		return &ForExpression{
+			Token:    t,
+			Pattern:  d.expression(obj, "pattern"),
+			Iterable: d.expression(obj, "iterable"),
+			Body:     d.block(obj, "body"),
+		}
+	case "FunctionLiteral":
+		n := &FunctionLiteral{Token: t, Parameters: d.expressions(obj, "parameters"), Body: d.block(obj, "body")}
+		d.value(obj, "generator", &n.Generator)
+		return n
+	case "MacroLiteral":
+		n := &MacroLiteral{Token: t, Parameters: []*Identifier{}}
+		for _, raw := range d.array(obj, "parameters") {
+			if param := d.node(raw); param != nil {
+				n.Parameters = append(n.Parameters, d.assert(param, "Identifier").(*Identifier))
+			}
+		}
+		n.Body = d.block(obj, "body")
+		return n
+	case "CallExpression":
+		return &CallExpression{Token: t, Function: d.expression(obj, "function"), Arguments: d.expressions(obj, "arguments")}
+	case "ArrayLiteral":
+		return &ArrayLiteral{Token: t, Elements: d.expressions(obj, "elements")}
+	case "SpreadExpression":
+		return &SpreadExpression{Token: t, Value: d.expression(obj, "value")}
+	case "SpawnExpression":
+		return &SpawnExpression{Token: t, Call: d.expression(obj, "call")}
+	case "AwaitExpression":
+		return &AwaitExpression{Token: t, Value: d.expression(obj, "value")}
+	case "SetLiteral":
+		return &SetLiteral{Token: t, Elements: d.expressions(obj, "elements")}
+	case "TupleLiteral":
+		return &TupleLiteral{Token: t, Elements: d.expressions(obj, "elements")}
+	case "IndexExpression":
+		return &IndexExpression{Token: t, Left: d.expression(obj, "left"), Index: d.expression(obj, "index")}
+	case "SliceExpression":
+		return &SliceExpression{
+			Token: t,
+			Left:  d.expression(obj, "left"),
+			Start: d.expression(obj, "start"),
+			End:   d.expression(obj, "end"),
+			Step:  d.expression(obj, "step"),
+		}
+	case "MemberExpression":
+		return &MemberExpression{Token: t, Object: d.expression(obj, "object"), Property: d.identifier(obj, "property")}
+	case "AssignExpression":
+		return &AssignExpression{Token: t, Target: d.expression(obj, "target"), Value: d.expression(obj, "value")}
+	case "HashLiteral":
+		n := &HashLiteral{Token: t, Pairs: make(map[Expression]Expression)}
+		for _, raw := range d.array(obj, "pairs") {
+			var pair fields
+			d.unmarshal(raw, &pair)
+			n.Pairs[d.expression(pair, "key")] = d.expression(pair, "value")
+		}
+		return n
+	case "MatchExpression":
+		n := &MatchExpression{Token: t, Subject: d.expression(obj, "subject"), Arms: []*MatchArm{}}
+		for _, raw := range d.array(obj, "arms") {
+			if arm := d.node(raw); arm != nil {
+				n.Arms = append(n.Arms, d.assert(arm, "MatchArm").(*MatchArm))
+			}
+		}
+		return n
+	case "MatchArm":
+		return &MatchArm{
+			Token:   t,
+			Pattern: d.expression(obj, "pattern"),
+			Guard:   d.expression(obj, "guard"),
+			Body:    d.expression(obj, "body"),
+		}
+	case "ArrayPattern":
+		return &ArrayPattern{Token: t, Elements: d.expressions(obj, "elements"), Rest: d.identifier(obj, "rest")}
+	case "HashPattern":
+		n := &HashPattern{Token: t, Pairs: []*HashPatternPair{}}
+		for _, raw := range d.array(obj, "pairs") {
+			var pair fields
+			d.unmarshal(raw, &pair)
+			var shorthand bool
+			if _, ok := pair["shorthand"]; ok {
+				d.value(pair, "shorthand", &shorthand)
+			}
+			key := d.expression(pair, "key")
+			value := key
+			if !shorthand {
+				value = d.expression(pair, "value")
+			}
+			n.Pairs = append(n.Pairs, &HashPatternPair{Key: key, Value: value})
+		}
+		n.Rest = d.identifier(obj, "rest")
+		return n
+	}
+
+	d.fail("unknown node kind %q", kind)
+	return nil
+}
+
+func (d *decoder) unmarshal(raw json.RawMessage, target any) {
+	if d.err != nil {
+		return
+	}
+	if err := json.Unmarshal(raw, target); err != nil {
+		d.fail("%s", err)
+	}
+}
+
+func (d *decoder) value(obj fields, name string, target any) {
+	raw, ok := obj[name]
+	if !ok {
+		d.fail("missing field %q", name)
+		return
+	}
+	d.unmarshal(raw, target)
+}
+
+func (d *decoder) array(obj fields, name string) []json.RawMessage {
+	var elements []json.RawMessage
+	d.value(obj, name, &elements)
+	return elements
+}
+
+// assert fails unless node is of the named kind; it returns a zero value of
+// that kind's pointer type on failure so that callers can type-assert the
+// result unconditionally.
+func (d *decoder) assert(node Node, kind string) Node {
+	got := fmt.Sprintf("%T", node)
+	if got == "*ast."+kind {
+		return node
+	}
+	d.fail("expected %s, got %s", kind, got[len("*ast."):])
+	switch kind {
+	case "Identifier":
+		return &Identifier{}
+	case "StringLiteral":
+		return &StringLiteral{}
+	case "LetStatement":
+		return &LetStatement{}
+	case "BlockStatement":
+		return &BlockStatement{}
+	case "MatchArm":
+		return &MatchArm{}
+	}
+	panic("ast: assert called with unsupported kind " + kind)
+}
+
+func (d *decoder) expression(obj fields, name string) Expression {
+	node := d.node(obj[name])
+	if node == nil {
+		return nil
+	}
+	expression, ok := node.(Expression)
+	if !ok {
+		d.fail("%s: expected an expression, got %T", name, node)
+		return nil
+	}
+	return expression
+}
+
+func (d *decoder) statement(obj fields, name string) Statement {
+	node := d.node(obj[name])
+	if node == nil {
+		return nil
+	}
+	statement, ok := node.(Statement)
+	if !ok {
+		d.fail("%s: expected a statement, got %T", name, node)
+		return nil
+	}
+	return statement
+}
+
+func (d *decoder) expressions(obj fields, name string) []Expression {
+	expressions := []Expression{}
+	for _, raw := range d.array(obj, name) {
+		holder := fields{name: raw}
+		if expression := d.expression(holder, name); expression != nil {
+			expressions = append(expressions, expression)
+		}
+	}
+	return expressions
+}
+
+func (d *decoder) statements(obj fields, name string) []Statement {
+	statements := []Statement{}
+	for _, raw := range d.array(obj, name) {
+		holder := fields{name: raw}
+		if statement := d.statement(holder, name); statement != nil {
+			statements = append(statements, statement)
+		}
+	}
+	return statements
+}
+
+func (d *decoder) block(obj fields, name string) *BlockStatement {
+	node := d.node(obj[name])
+	if node == nil {
+		return nil
+	}
+	return d.assert(node, "BlockStatement").(*BlockStatement)
+}
+
+func (d *decoder) identifier(obj fields, name string) *Identifier {
+	node := d.node(obj[name])
+	if node == nil {
+		return nil
+	}
+	return d.assert(node, "Identifier").(*Identifier)
+}
@@ -0,0 +1,219 @@
+package ast
+
+// ModifierFunc is called by Modify for every node in a tree. The node it
+// returns replaces the one it was given.
+type ModifierFunc func(Node) Node
+
+// Modify rewrites the tree rooted at node bottom-up: the children of a node
+// are modified before the node itself is passed to modifier. Nodes are
+// changed in place and the (possibly replaced) root is returned.
+//
+// A replacement must fit the field it is stored in; one that does not, such
+// as a Statement returned for an Expression field, is dropped and the
+// original child is kept.
+func Modify(node Node, modifier ModifierFunc) Node {
+	switch node := node.(type) {
+	case *Program:
+		for i, statement := range node.Statements {
+			node.Statements[i] = modifyStatement(statement, modifier)
+		}
+
+	case *ExpressionStatement:
+		node.Expression = modifyExpression(node.Expression, modifier)
+
+	case *LetStatement:
+		node.Name = modifyIdentifier(node.Name, modifier)
+		node.Pattern = modifyExpression(node.Pattern, modifier)
+		node.Value = modifyExpression(node.Value, modifier)
+
+	case *ReturnStatement:
+		node.ReturnValue = modifyExpression(node.ReturnValue, modifier)
+
+	case *YieldStatement:
+		node.Value = modifyExpression(node.Value, modifier)
+
+	case *ThrowStatement:
+		node.Value = modifyExpression(node.Value, modifier)
+
+	case *ImportStatement:
+		if node.Path != nil {
+			if path, ok := Modify(node.Path, modifier).(*StringLiteral); ok {
+				node.Path = path
+			}
+		}
+		node.Alias = modifyIdentifier(node.Alias, modifier)
+
+	case *ExportStatement:
+		if node.Statement != nil {
+			if let, ok := Modify(node.Statement, modifier).(*LetStatement); ok {
+				node.Statement = let
+			}
+		}
+
+	case *BlockStatement:
+		for i, statement := range node.Statements {
+			node.Statements[i] = modifyStatement(statement, modifier)
+		}
+
+	case *PrefixExpression:
+		node.Right = modifyExpression(node.Right, modifier)
+
+	case *InfixExpression:
+		node.Left = modifyExpression(node.Left, modifier)
+		node.Right = modifyExpression(node.Right, modifier)
+
+	case *IfExpression:
+		node.Condition = modifyExpression(node.Condition, modifier)
+		node.Consequence = modifyBlock(node.Consequence, modifier)
+		node.Alternative = modifyBlock(node.Alternative, modifier)
+
+	case *TryExpression:
+		node.Block = modifyBlock(node.Block, modifier)
+		node.CatchParam = modifyExpression(node.CatchParam, modifier)
+		node.Catch = modifyBlock(node.Catch, modifier)
+		node.Finally = modifyBlock(node.Finally, modifier)
+
+	case *ForExpression:
+		node.Pattern = modifyExpression(node.Pattern, modifier)
+		node.Iterable = modifyExpression(node.Iterable, modifier)
+		node.Body = modifyBlock(node.Body, modifier)
+
+	case *FunctionLiteral:
+		for i, param := range node.Parameters {
+			node.Parameters[i] = modifyExpression(param, modifier)
+		}
+		node.Body = modifyBlock(node.Body, modifier)
+
+	case *MacroLiteral:
+		for i, param := range node.Parameters {
+			node.Parameters[i] = modifyIdentifier(param, modifier)
+		}
+		node.Body = modifyBlock(node.Body, modifier)
+
+	case *CallExpression:
+		node.Function = modifyExpression(node.Function, modifier)
+		for i, arg := range node.Arguments {
+			node.Arguments[i] = modifyExpression(arg, modifier)
+		}
+
+	case *ArrayLiteral:
+		for i, element := range node.Elements {
+			node.Elements[i] = modifyExpression(element, modifier)
+		}
+
+	case *SpreadExpression:
+		node.Value = modifyExpression(node.Value, modifier)
+
+	case *SpawnExpression:
+		node.Call = modifyExpression(node.Call, modifier)
+
+	case *AwaitExpression:
+		node.Value = modifyExpression(node.Value, modifier)
+
+	case *SetLiteral:
+		for i, element := range node.Elements {
+			node.Elements[i] = modifyExpression(element, modifier)
+		}
+
+	case *TupleLiteral:
+		for i, element := range node.Elements {
+			node.Elements[i] = modifyExpression(element, modifier)
+		}
+
+	case *IndexExpression:
+		node.Left = modifyExpression(node.Left, modifier)
+		node.Index = modifyExpression(node.Index, modifier)
+
+	case *SliceExpression:
+		node.Left = modifyExpression(node.Left, modifier)
+		node.Start = modifyExpression(node.Start, modifier)
+		node.End = modifyExpression(node.End, modifier)
+		node.Step = modifyExpression(node.Step, modifier)
+
+	case *MemberExpression:
+		node.Object = modifyExpression(node.Object, modifier)
+		node.Property = modifyIdentifier(node.Property, modifier)
+
+	case *AssignExpression:
+		node.Target = modifyExpression(node.Target, modifier)
+		node.Value = modifyExpression(node.Value, modifier)
+
+	case *HashLiteral:
+		pairs := make(map[Expression]Expression, len(node.Pairs))
+		for key, value := range node.Pairs {
+			pairs[modifyExpression(key, modifier)] = modifyExpression(value, modifier)
+		}
+		node.Pairs = pairs
+
+	case *MatchExpression:
+		node.Subject = modifyExpression(node.Subject, modifier)
+		for _, arm := range node.Arms {
+			Modify(arm, modifier)
+		}
+
+	case *MatchArm:
+		node.Pattern = modifyExpression(node.Pattern, modifier)
+		node.Guard = modifyExpression(node.Guard, modifier)
+		node.Body = modifyExpression(node.Body, modifier)
+
+	case *ArrayPattern:
+		for i, element := range node.Elements {
+			node.Elements[i] = modifyExpression(element, modifier)
+		}
+		node.Rest = modifyIdentifier(node.Rest, modifier)
+
+	case *HashPattern:
+		for _, pair := range node.Pairs {
+			shorthand := pair.Key == pair.Value
+			pair.Key = modifyExpression(pair.Key, modifier)
+			if shorthand {
+				pair.Value = pair.Key
+			} else {
+				pair.Value = modifyExpression(pair.Value, modifier)
+			}
+		}
+		node.Rest = modifyIdentifier(node.Rest, modifier)
+	}
+
+	return modifier(node)
+}
+
+func modifyStatement(statement Statement, modifier ModifierFunc) Statement {
+	if statement == nil {
+		return statement
+	}
+	if modified, ok := Modify(statement, modifier).(Statement); ok {
+		return modified
+	}
+	return statement
+}
+
+func modifyExpression(expression Expression, modifier ModifierFunc) Expression {
+	if expression == nil {
+		return expression
+	}
+	if modified, ok := Modify(expression, modifier).(Expression); ok {
+		return modified
+	}
+	return expression
+}
+
+func modifyBlock(block *BlockStatement, modifier ModifierFunc) *BlockStatement {
+	if block == nil {
+		return nil
+	}
+	if modified, ok := Modify(block, modifier).(*BlockStatement); ok {
+		return modified
+	}
+	return block
+}
+
+func modifyIdentifier(ident *Identifier, modifier ModifierFunc) *Identifier {
+	if ident == nil {
+		return nil
+	}
+	if modified, ok := Modify(ident, modifier).(*Identifier); ok {
+		return modified
+	}
+	return ident
+}
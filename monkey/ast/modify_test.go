@@ -0,0 +1,190 @@
+package ast
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestModify(t *testing.T) {
+	one := func() Expression { return &IntegerLiteral{Value: 1} }
+	two := func() Expression { return &IntegerLiteral{Value: 2} }
+	ident := func(name string) *Identifier { return &Identifier{Value: name} }
+
+	turnOneIntoTwo := func(node Node) Node {
+		integer, ok := node.(*IntegerLiteral)
+		if !ok || integer.Value != 1 {
+			return node
+		}
+		integer.Value = 2
+		return integer
+	}
+
+	tests := []struct {
+		input    Node
+		expected Node
+	}{
+		{one(), two()},
+		{
+			&Program{Statements: []Statement{&ExpressionStatement{Expression: one()}}},
+			&Program{Statements: []Statement{&ExpressionStatement{Expression: two()}}},
+		},
+		{
+			&InfixExpression{Left: one(), Operator: "+", Right: two()},
+			&InfixExpression{Left: two(), Operator: "+", Right: two()},
+		},
+		{
+			&PrefixExpression{Operator: "-", Right: one()},
+			&PrefixExpression{Operator: "-", Right: two()},
+		},
+		{
+			&IndexExpression{Left: one(), Index: one()},
+			&IndexExpression{Left: two(), Index: two()},
+		},
+		{
+			&IfExpression{
+				Condition:   one(),
+				Consequence: &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: one()}}},
+				Alternative: &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: one()}}},
+			},
+			&IfExpression{
+				Condition:   two(),
+				Consequence: &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: two()}}},
+				Alternative: &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: two()}}},
+			},
+		},
+		{&ReturnStatement{ReturnValue: one()}, &ReturnStatement{ReturnValue: two()}},
+		{&ThrowStatement{Value: one()}, &ThrowStatement{Value: two()}},
+		{&LetStatement{Name: ident("x"), Value: one()}, &LetStatement{Name: ident("x"), Value: two()}},
+		{
+			&FunctionLiteral{
+				Parameters: []Expression{},
+				Body:       &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: one()}}},
+			},
+			&FunctionLiteral{
+				Parameters: []Expression{},
+				Body:       &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: two()}}},
+			},
+		},
+		{
+			&CallExpression{Function: ident("f"), Arguments: []Expression{one(), two()}},
+			&CallExpression{Function: ident("f"), Arguments: []Expression{two(), two()}},
+		},
+		{&ArrayLiteral{Elements: []Expression{one(), one()}}, &ArrayLiteral{Elements: []Expression{two(), two()}}},
+		{
+			&MemberExpression{Object: &ArrayLiteral{Elements: []Expression{one()}}, Property: ident("x")},
+			&MemberExpression{Object: &ArrayLiteral{Elements: []Expression{two()}}, Property: ident("x")},
+		},
+		{
+			&AssignExpression{Target: &IndexExpression{Left: ident("a"), Index: one()}, Value: one()},
+			&AssignExpression{Target: &IndexExpression{Left: ident("a"), Index: two()}, Value: two()},
+		},
+		{
+			&TryExpression{
+				Block:   &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: one()}}},
+				Finally: &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: one()}}},
+			},
+			&TryExpression{
+				Block:   &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: two()}}},
+				Finally: &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: two()}}},
+			},
+		},
+		{
+			&MatchExpression{Subject: one(), Arms: []*MatchArm{{Pattern: one(), Guard: one(), Body: one()}}},
+			&MatchExpression{Subject: two(), Arms: []*MatchArm{{Pattern: two(), Guard: two(), Body: two()}}},
+		},
+		{
+			&ArrayPattern{Elements: []Expression{one(), ident("a")}, Rest: ident("rest")},
+			&ArrayPattern{Elements: []Expression{two(), ident("a")}, Rest: ident("rest")},
+		},
+	}
+
+	for _, tt := range tests {
+		modified := Modify(tt.input, turnOneIntoTwo)
+		if !reflect.DeepEqual(modified, tt.expected) {
+			t.Errorf("not equal. got=%#v, want=%#v", modified, tt.expected)
+		}
+	}
+
+	hashLiteral := &HashLiteral{
+		Pairs: map[Expression]Expression{
+			one(): one(),
+			one(): one(),
+		},
+	}
+	Modify(hashLiteral, turnOneIntoTwo)
+	for key, val := range hashLiteral.Pairs {
+		key, _ := key.(*IntegerLiteral)
+		if key.Value != 2 {
+			t.Errorf("value is not %d, got=%d", 2, key.Value)
+		}
+		val, _ := val.(*IntegerLiteral)
+		if val.Value != 2 {
+			t.Errorf("value is not %d, got=%d", 2, val.Value)
+		}
+	}
+}
+
+func TestModifyReplacesNodes(t *testing.T) {
+	// Replace every identifier x with the literal 5, but leave the let
+	// binding's name alone since an IntegerLiteral cannot stand there.
+	program := &Program{Statements: []Statement{
+		&LetStatement{Name: &Identifier{Value: "x"}, Value: &Identifier{Value: "x"}},
+	}}
+	modified := Modify(program, func(node Node) Node {
+		if ident, ok := node.(*Identifier); ok && ident.Value == "x" {
+			return &IntegerLiteral{Value: 5}
+		}
+		return node
+	})
+
+	let := modified.(*Program).Statements[0].(*LetStatement)
+	if let.Name.Value != "x" {
+		t.Errorf("let name replaced. got=%#v", let.Name)
+	}
+	if integer, ok := let.Value.(*IntegerLiteral); !ok || integer.Value != 5 {
+		t.Errorf("let value not replaced. got=%#v", let.Value)
+	}
+}
+
+func TestCopy(t *testing.T) {
+	name := &Identifier{Value: "name"}
+	original := &Program{Statements: []Statement{
+		&LetStatement{
+			Pattern: &HashPattern{Pairs: []*HashPatternPair{{Key: name, Value: name}}},
+			Value:   &IntegerLiteral{Value: 1},
+		},
+		&ExpressionStatement{Expression: &IfExpression{
+			Condition:   &IntegerLiteral{Value: 1},
+			Consequence: &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: &IntegerLiteral{Value: 1}}}},
+		}},
+	}}
+
+	copied := Copy(original)
+	if !reflect.DeepEqual(copied, original) {
+		t.Fatalf("copy differs from original. got=%#v", copied)
+	}
+
+	Modify(copied, func(node Node) Node {
+		if integer, ok := node.(*IntegerLiteral); ok {
+			integer.Value = 2
+		}
+		return node
+	})
+	let := original.Statements[0].(*LetStatement)
+	if let.Value.(*IntegerLiteral).Value != 1 {
+		t.Errorf("modifying the copy changed the original let value")
+	}
+	ifExp := original.Statements[1].(*ExpressionStatement).Expression.(*IfExpression)
+	body := ifExp.Consequence.Statements[0].(*ExpressionStatement).Expression
+	if ifExp.Condition.(*IntegerLiteral).Value != 1 || body.(*IntegerLiteral).Value != 1 {
+		t.Errorf("modifying the copy changed the original if expression")
+	}
+
+	pair := copied.(*Program).Statements[0].(*LetStatement).Pattern.(*HashPattern).Pairs[0]
+	if pair.Key != pair.Value {
+		t.Errorf("shorthand hash pattern pair not preserved")
+	}
+	if pair.Key == Expression(name) {
+		t.Errorf("identifier was shared with the original")
+	}
+}
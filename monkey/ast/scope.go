@@ -0,0 +1,43 @@
+package ast
+
+// Scope tells the evaluator where to find the variable an Identifier names.
+// It is filled in by the resolver in package evaluator; the zero value,
+// Dynamic, leaves the lookup to run time.
+type Scope int
+
+const (
+	Dynamic Scope = iota // looked up by name through the environment chain
+	Global               // looked up by name in the global environment
+	Builtin              // a builtin function
+	Local                // Slot is a slot of the running frame
+	Cell                 // Slot is a cell of the running frame
+	Free                 // Slot is a variable captured by the running function
+)
+
+var scopeNames = [...]string{"dynamic", "global", "builtin", "local", "cell", "free"}
+
+func (s Scope) String() string {
+	if s < 0 || int(s) >= len(scopeNames) {
+		return "unknown"
+	}
+	return scopeNames[s]
+}
+
+// Frame lays out the variables of a function body, or of the top-level code
+// of a program, for the evaluator. Variables that closures capture are kept
+// in cells so that the closures share them with the frame; all others are
+// plain slots.
+type Frame struct {
+	Slots int
+	Cells int
+
+	// Captures lists, for a function, where each of its free variables is
+	// taken from when the function value is created: a Cell of the frame it
+	// is created in or a Free variable of the function running that frame.
+	Captures []Capture
+}
+
+type Capture struct {
+	Scope Scope // Cell or Free
+	Slot  int
+}
@@ -0,0 +1,237 @@
+package ast
+
+// A Visitor's Visit method is invoked for each node encountered by Walk. If
+// the result visitor w is not nil, Walk visits each of the children of node
+// with w, followed by a call of w.Visit(nil).
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Walk traverses an AST in depth-first order: it starts by calling
+// v.Visit(node); node must not be nil. If the visitor w returned by
+// v.Visit(node) is not nil, Walk is invoked recursively with visitor w for
+// each of the non-nil children of node, followed by a call of w.Visit(nil).
+//
+// Children are visited in source order. The keys and values of a
+// HashLiteral are visited pairwise in the order of HashLiteral.Keys, and the
+// identifier of a shorthand hash pattern pair such as {name} is visited once.
+func Walk(v Visitor, node Node) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+
+	switch n := node.(type) {
+	case *Program:
+		walkStatements(v, n.Statements)
+
+	case *ExpressionStatement:
+		walkExpression(v, n.Expression)
+
+	case *LetStatement:
+		if n.Name != nil {
+			Walk(v, n.Name)
+		}
+		walkExpression(v, n.Pattern)
+		walkExpression(v, n.Value)
+
+	case *ReturnStatement:
+		walkExpression(v, n.ReturnValue)
+
+	case *YieldStatement:
+		walkExpression(v, n.Value)
+
+	case *ThrowStatement:
+		walkExpression(v, n.Value)
+
+	case *ImportStatement:
+		if n.Path != nil {
+			Walk(v, n.Path)
+		}
+		if n.Alias != nil {
+			Walk(v, n.Alias)
+		}
+
+	case *ExportStatement:
+		if n.Statement != nil {
+			Walk(v, n.Statement)
+		}
+
+	case *BlockStatement:
+		walkStatements(v, n.Statements)
+
+	case *Identifier, *NullExpression, *IntegerLiteral, *FloatLiteral, *StringLiteral, *Boolean:
+		// nothing to do
+
+	case *PrefixExpression:
+		walkExpression(v, n.Right)
+
+	case *InfixExpression:
+		walkExpression(v, n.Left)
+		walkExpression(v, n.Right)
+
+	case *IfExpression:
+		walkExpression(v, n.Condition)
+		if n.Consequence != nil {
+			Walk(v, n.Consequence)
+		}
+		if n.Alternative != nil {
+			Walk(v, n.Alternative)
+		}
+
+	case *TryExpression:
+		if n.Block != nil {
+			Walk(v, n.Block)
+		}
+		walkExpression(v, n.CatchParam)
+		if n.Catch != nil {
+			Walk(v, n.Catch)
+		}
+		if n.Finally != nil {
+			Walk(v, n.Finally)
+		}
+
+	case *ForExpression:
+		walkExpression(v, n.Pattern)
+		walkExpression(v, n.Iterable)
+		if n.Body != nil {
+			Walk(v, n.Body)
+		}
+
+	case *FunctionLiteral:
+		walkExpressions(v, n.Parameters)
+		if n.Body != nil {
+			Walk(v, n.Body)
+		}
+
+	case *MacroLiteral:
+		for _, param := range n.Parameters {
+			Walk(v, param)
+		}
+		if n.Body != nil {
+			Walk(v, n.Body)
+		}
+
+	case *CallExpression:
+		walkExpression(v, n.Function)
+		walkExpressions(v, n.Arguments)
+
+	case *ArrayLiteral:
+		walkExpressions(v, n.Elements)
+
+	case *SpreadExpression:
+		walkExpression(v, n.Value)
+
+	case *SpawnExpression:
+		walkExpression(v, n.Call)
+
+	case *AwaitExpression:
+		walkExpression(v, n.Value)
+
+	case *SetLiteral:
+		walkExpressions(v, n.Elements)
+
+	case *TupleLiteral:
+		walkExpressions(v, n.Elements)
+
+	case *IndexExpression:
+		walkExpression(v, n.Left)
+		walkExpression(v, n.Index)
+
+	case *SliceExpression:
+		walkExpression(v, n.Left)
+		walkExpression(v, n.Start)
+		walkExpression(v, n.End)
+		walkExpression(v, n.Step)
+
+	case *MemberExpression:
+		walkExpression(v, n.Object)
+		if n.Property != nil {
+			Walk(v, n.Property)
+		}
+
+	case *AssignExpression:
+		walkExpression(v, n.Target)
+		walkExpression(v, n.Value)
+
+	case *HashLiteral:
+		for _, key := range n.Keys() {
+			walkExpression(v, key)
+			walkExpression(v, n.Pairs[key])
+		}
+
+	case *MatchExpression:
+		walkExpression(v, n.Subject)
+		for _, arm := range n.Arms {
+			Walk(v, arm)
+		}
+
+	case *MatchArm:
+		walkExpression(v, n.Pattern)
+		walkExpression(v, n.Guard)
+		walkExpression(v, n.Body)
+
+	case *ArrayPattern:
+		walkExpressions(v, n.Elements)
+		if n.Rest != nil {
+			Walk(v, n.Rest)
+		}
+
+	case *HashPattern:
+		for _, pair := range n.Pairs {
+			walkExpression(v, pair.Key)
+			if pair.Value != pair.Key {
+				walkExpression(v, pair.Value)
+			}
+		}
+		if n.Rest != nil {
+			Walk(v, n.Rest)
+		}
+	}
+
+	v.Visit(nil)
+}
+
+func walkStatements(v Visitor, statements []Statement) {
+	for _, statement := range statements {
+		if statement != nil {
+			Walk(v, statement)
+		}
+	}
+}
+
+func walkExpression(v Visitor, expression Expression) {
+	if expression != nil {
+		Walk(v, expression)
+	}
+}
+
+func walkExpressions(v Visitor, expressions []Expression) {
+	for _, expression := range expressions {
+		walkExpression(v, expression)
+	}
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Inspect traverses an AST in depth-first order: it starts by calling
+// f(node); node must not be nil. If f returns true, Inspect invokes f
+// recursively for each of the non-nil children of node, followed by a call
+// of f(nil).
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
+
+// Rewrite returns a copy of the tree rooted at node in which every node has
+// been replaced by the result of calling f on it. Like Modify, children are
+// rewritten before their parent, and replacements that do not fit their
+// field are dropped; unlike Modify, the original tree is left untouched.
+func Rewrite(node Node, f func(Node) Node) Node {
+	return Modify(Copy(node), f)
+}
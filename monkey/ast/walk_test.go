@@ -0,0 +1,169 @@
+package ast_test
+
+import (
+	"fmt"
+	"monkey/ast"
+	"monkey/lexer"
+	"monkey/parser"
+	"strings"
+	"testing"
+)
+
+func parse(t *testing.T, input string) *ast.Program {
+	t.Helper()
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+	return program
+}
+
+// describe renders a node as its type name, plus its value for leaves.
+func describe(node ast.Node) string {
+	name := strings.TrimPrefix(fmt.Sprintf("%T", node), "*ast.")
+	switch node := node.(type) {
+	case *ast.Identifier:
+		return name + " " + node.Value
+	case *ast.IntegerLiteral, *ast.StringLiteral, *ast.Boolean:
+		return name + " " + node.String()
+	}
+	return name
+}
+
+func TestInspect(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{
+			`let x = -1 + y;`,
+			[]string{"Program", "LetStatement", "Identifier x", "InfixExpression",
+				"PrefixExpression", "IntegerLiteral 1", "Identifier y"},
+		},
+		{
+			`if (a) { 1 } else if (b) { 2 } else { 3 }`,
+			[]string{"Program", "ExpressionStatement", "IfExpression", "Identifier a",
+				"BlockStatement", "ExpressionStatement", "IntegerLiteral 1",
+				"BlockStatement", "ExpressionStatement", "IfExpression", "Identifier b",
+				"BlockStatement", "ExpressionStatement", "IntegerLiteral 2",
+				"BlockStatement", "ExpressionStatement", "IntegerLiteral 3"},
+		},
+		{
+			`{"a": 1, b: 2, "c": 3}`,
+			[]string{"Program", "ExpressionStatement", "HashLiteral",
+				"StringLiteral a", "IntegerLiteral 1", "Identifier b", "IntegerLiteral 2",
+				"StringLiteral c", "IntegerLiteral 3"},
+		},
+		{
+			`let f = fn([a, ...r], {k, v: w}) { return f(a)[0].p; };`,
+			[]string{"Program", "LetStatement", "Identifier f", "FunctionLiteral",
+				"ArrayPattern", "Identifier a", "Identifier r",
+				"HashPattern", "Identifier k", "Identifier v", "Identifier w",
+				"BlockStatement", "ReturnStatement", "MemberExpression", "IndexExpression",
+				"CallExpression", "Identifier f", "Identifier a", "IntegerLiteral 0", "Identifier p"},
+		},
+		{
+			`match x { 1 if y => true, _ => null }`,
+			[]string{"Program", "ExpressionStatement", "MatchExpression", "Identifier x",
+				"MatchArm", "IntegerLiteral 1", "Identifier y", "Boolean true",
+				"MatchArm", "Identifier _", "NullExpression"},
+		},
+		{
+			`try { throw e } catch (err) { a.b = [1] } finally { 2.5 }`,
+			[]string{"Program", "ExpressionStatement", "TryExpression",
+				"BlockStatement", "ThrowStatement", "Identifier e", "Identifier err",
+				"BlockStatement", "ExpressionStatement", "AssignExpression",
+				"MemberExpression", "Identifier a", "Identifier b", "ArrayLiteral", "IntegerLiteral 1",
+				"BlockStatement", "ExpressionStatement", "FloatLiteral"},
+		},
+		{
+			`import "lib" as l; export let m = macro(q) { q };`,
+			[]string{"Program", "ImportStatement", "StringLiteral lib", "Identifier l",
+				"ExportStatement", "LetStatement", "Identifier m", "MacroLiteral",
+				"Identifier q", "BlockStatement", "ExpressionStatement", "Identifier q"},
+		},
+	}
+
+	for _, tt := range tests {
+		program := parse(t, tt.input)
+
+		var visited []string
+		ast.Inspect(program, func(node ast.Node) bool {
+			if node != nil {
+				visited = append(visited, describe(node))
+			}
+			return true
+		})
+
+		if strings.Join(visited, "\n") != strings.Join(tt.expected, "\n") {
+			t.Errorf("%s: wrong nodes visited.\nexpected=%q\ngot=     %q", tt.input, tt.expected, visited)
+		}
+	}
+}
+
+func TestInspectPrunes(t *testing.T) {
+	program := parse(t, `let f = fn(x) { y }; z`)
+
+	var idents []string
+	ast.Inspect(program, func(node ast.Node) bool {
+		if ident, ok := node.(*ast.Identifier); ok {
+			idents = append(idents, ident.Value)
+		}
+		_, isFunction := node.(*ast.FunctionLiteral)
+		return !isFunction
+	})
+
+	if strings.Join(idents, ",") != "f,z" {
+		t.Errorf("function literal was not pruned. got=%v", idents)
+	}
+}
+
+type depthVisitor struct {
+	depth, max *int
+}
+
+func (v depthVisitor) Visit(node ast.Node) ast.Visitor {
+	if node == nil {
+		*v.depth--
+		return nil
+	}
+	*v.depth++
+	if *v.depth > *v.max {
+		*v.max = *v.depth
+	}
+	return v
+}
+
+func TestWalkPairsVisitWithNil(t *testing.T) {
+	depth, max := 0, 0
+	ast.Walk(depthVisitor{&depth, &max}, parse(t, `let a = [1, [2, [3]]];`))
+
+	if depth != 0 {
+		t.Errorf("Visit(nil) calls are unbalanced. depth=%d", depth)
+	}
+	// Program > LetStatement > ArrayLiteral > ArrayLiteral > ArrayLiteral > IntegerLiteral
+	if max != 6 {
+		t.Errorf("wrong maximum depth. got=%d", max)
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	program := parse(t, `let x = 1 + 2; if (x) { {"a": 1} } else { [1, 1] }`)
+	before := program.String()
+
+	rewritten := ast.Rewrite(program, func(node ast.Node) ast.Node {
+		if integer, ok := node.(*ast.IntegerLiteral); ok && integer.Value == 1 {
+			return &ast.Identifier{Token: integer.Token, Value: "one"}
+		}
+		return node
+	})
+
+	if program.String() != before {
+		t.Errorf("Rewrite changed the original. got=%q", program.String())
+	}
+	expected := `let x = (one + 2);ifx {a:one}else [one, one]`
+	if rewritten.String() != expected {
+		t.Errorf("wrong rewrite. expected=%q, got=%q", expected, rewritten.String())
+	}
+}
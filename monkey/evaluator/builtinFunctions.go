@@ -2,22 +2,33 @@ package evaluator
 
 import (
 	"fmt"
+	"math"
+	"monkey/lexer"
 	"monkey/object"
+	"monkey/token"
+	"strconv"
 	"strings"
+	"unicode/utf8"
 )
 
 func lenFn(args ...object.Object) object.Object {
 	if len(args) != 1 {
-		return newError("wrong number of arguments. got=%d, want=1", len(args))
+		return newKindError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=1", len(args))
 	}
 
 	switch arg := args[0].(type) {
 	case *object.Array:
 		return &object.Integer{Value: int64(len(arg.Elements))}
 	case *object.String:
-		return &object.Integer{Value: int64(len(arg.Value))}
+		return &object.Integer{Value: int64(utf8.RuneCountInString(arg.Value))}
+	case *object.Tuple:
+		return &object.Integer{Value: int64(len(arg.Elements))}
+	case *object.Set:
+		return &object.Integer{Value: int64(arg.Len())}
+	case *object.Range:
+		return &object.Integer{Value: arg.Len()}
 	default:
-		return newError("argument to `len` not supported, got %s", args[0].Type())
+		return newKindError(object.TYPE_ERROR, "argument to `len` not supported, got %s", args[0].Type())
 	}
 }
 
@@ -32,62 +43,356 @@ func printFn(args ...object.Object) object.Object {
 
 func firstFn(args ...object.Object) object.Object {
 	if len(args) != 1 {
-		return newError("wrong number of arguments. got=%d, want =1", len(args))
+		return newKindError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want =1", len(args))
 	}
 	if args[0].Type() != object.ARRAY_OBJ {
-		return newError("argument to `first` must be ARRAY, got=%s", args[0].Type())
+		return newKindError(object.TYPE_ERROR, "argument to `first` must be ARRAY, got=%s", args[0].Type())
 	}
 	arr := args[0].(*object.Array)
 	if len(arr.Elements) > 0 {
-		return arr.Elements[0]
+		return arr.Get(0)
 	}
 	return NULL
 }
 
 func lastFn(args ...object.Object) object.Object {
 	if len(args) != 1 {
-		return newError("wrong number of arguments. got=%d, want =1", len(args))
+		return newKindError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want =1", len(args))
 	}
 	if args[0].Type() != object.ARRAY_OBJ {
-		return newError("argument to `first` must be ARRAY, got=%s", args[0].Type())
+		return newKindError(object.TYPE_ERROR, "argument to `first` must be ARRAY, got=%s", args[0].Type())
 	}
 	arr := args[0].(*object.Array)
 	length := len(arr.Elements)
 	if len(arr.Elements) > 0 {
-		return arr.Elements[length-1]
+		return arr.Get(length - 1)
 	}
 	return NULL
 }
 
 func restFn(args ...object.Object) object.Object {
 	if len(args) != 1 {
-		return newError("wrong number of arguments. got=%d, want =1", len(args))
+		return newKindError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want =1", len(args))
 	}
 	if args[0].Type() != object.ARRAY_OBJ {
-		return newError("argument to `first` must be ARRAY, got=%s", args[0].Type())
+		return newKindError(object.TYPE_ERROR, "argument to `first` must be ARRAY, got=%s", args[0].Type())
 	}
 	arr := args[0].(*object.Array)
 	length := len(arr.Elements)
 	if length > 0 {
-		newElements := make([]object.Object, length-1)
-		copy(newElements, arr.Elements[1:length])
-		return &object.Array{Elements: newElements}
+		return &object.Array{Elements: arr.Snapshot()[1:length]}
 	}
 	return NULL
 }
 
 func push(args ...object.Object) object.Object {
 	if len(args) != 2 {
-		return newError("wrong number of arguments. got=%d, want =1", len(args))
+		return newKindError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want =1", len(args))
 	}
 	if args[0].Type() != object.ARRAY_OBJ {
-		return newError("argument to `first` must be ARRAY, got=%s", args[0].Type())
+		return newKindError(object.TYPE_ERROR, "argument to `first` must be ARRAY, got=%s", args[0].Type())
 	}
 	arr := args[0].(*object.Array)
-	length := len(arr.Elements)
-	newElements := make([]object.Object, length+1)
-	copy(newElements, arr.Elements)
-	newElements[length] = args[1]
+	newElements := append(arr.Snapshot(), args[1])
 
 	return &object.Array{Elements: newElements}
 }
+
+func splitFn(args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newKindError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=2", len(args))
+	}
+	str, ok := args[0].(*object.String)
+	if !ok {
+		return newKindError(object.TYPE_ERROR, "first argument to `split` must be STRING, got=%s", args[0].Type())
+	}
+	sep, ok := args[1].(*object.String)
+	if !ok {
+		return newKindError(object.TYPE_ERROR, "second argument to `split` must be STRING, got=%s", args[1].Type())
+	}
+
+	parts := strings.Split(str.Value, sep.Value)
+	elements := make([]object.Object, len(parts))
+	for i, part := range parts {
+		elements[i] = &object.String{Value: part}
+	}
+	return &object.Array{Elements: elements}
+}
+
+// joinFn concatenates the elements of an array with a separator. Elements
+// that are not strings are joined using their Inspect form.
+func joinFn(args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newKindError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=2", len(args))
+	}
+	arr, ok := args[0].(*object.Array)
+	if !ok {
+		return newKindError(object.TYPE_ERROR, "first argument to `join` must be ARRAY, got=%s", args[0].Type())
+	}
+	sep, ok := args[1].(*object.String)
+	if !ok {
+		return newKindError(object.TYPE_ERROR, "second argument to `join` must be STRING, got=%s", args[1].Type())
+	}
+
+	parts := make([]string, len(arr.Elements))
+	for i, el := range arr.Snapshot() {
+		parts[i] = el.Inspect()
+	}
+	return &object.String{Value: strings.Join(parts, sep.Value)}
+}
+
+// setMethod returns the method name of a set, bound to it. add and remove
+// return a copy of the set with or without an element, leaving the set
+// itself unchanged. A set also has the methods of an iterator.
+func setMethod(set *object.Set, name string) object.Object {
+	switch name {
+	case "add":
+		return &object.Builtin{Name: name, Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newKindError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=1", len(args))
+			}
+			return newSet(append(set.Elements(), args[0]))
+		}}
+	case "remove":
+		return &object.Builtin{Name: name, Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newKindError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=1", len(args))
+			}
+			elements := []object.Object{}
+			for _, el := range set.Elements() {
+				if !object.Equal(el, args[0]) {
+					elements = append(elements, el)
+				}
+			}
+			return newSet(elements)
+		}}
+	default:
+		return iteratorMethod(set, name)
+	}
+}
+
+// containsFn tests membership: elements of sets and other iterables, keys of
+// hashes and substrings of strings.
+func containsFn(args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newKindError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=2", len(args))
+	}
+	switch collection := args[0].(type) {
+	case *object.Set:
+		return nativeBoolToObj(object.IsHashable(args[1]) && collection.Contains(args[1].(object.Hashable)))
+	case *object.Hash:
+		if !object.IsHashable(args[1]) {
+			return FALSE
+		}
+		_, ok := collection.Get(args[1].(object.Hashable))
+		return nativeBoolToObj(ok)
+	case *object.String:
+		substr, ok := args[1].(*object.String)
+		if !ok {
+			return newKindError(object.TYPE_ERROR, "second argument to `contains` must be STRING, got=%s", args[1].Type())
+		}
+		return nativeBoolToObj(strings.Contains(collection.Value, substr.Value))
+	case *object.Range:
+		n, ok := args[1].(*object.Integer)
+		return nativeBoolToObj(ok && collection.Contains(n.Value))
+	case object.Iterable:
+		it := collection.Iter()
+		for {
+			el, ok := it.Next()
+			if !ok {
+				return FALSE
+			}
+			if isError(el) {
+				return el
+			}
+			if object.Equal(el, args[1]) {
+				return TRUE
+			}
+		}
+	default:
+		return newKindError(object.TYPE_ERROR, "argument to `contains` not supported, got %s", args[0].Type())
+	}
+}
+
+// setFn, tupleFn and arrayFn collect the elements of any iterable.
+func setFn(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newKindError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=1", len(args))
+	}
+	elements, err := elementsOf("set", args[0])
+	if err != nil {
+		return err
+	}
+	return newSet(elements)
+}
+
+func tupleFn(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newKindError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=1", len(args))
+	}
+	elements, err := elementsOf("tuple", args[0])
+	if err != nil {
+		return err
+	}
+	return &object.Tuple{Elements: elements}
+}
+
+func arrayFn(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newKindError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=1", len(args))
+	}
+	elements, err := elementsOf("array", args[0])
+	if err != nil {
+		return err
+	}
+	return &object.Array{Elements: elements}
+}
+
+// typeFn returns the name of the type of a value, as used in error
+// messages: "INTEGER", "STRING", "FUNCTION" and so on.
+func typeFn(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newKindError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=1", len(args))
+	}
+	return &object.String{Value: string(args[0].Type())}
+}
+
+// strFn converts a value to a string. Strings are returned as they are;
+// anything else is printed the way the REPL shows it.
+func strFn(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newKindError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=1", len(args))
+	}
+	if str, ok := args[0].(*object.String); ok {
+		return str
+	}
+	return &object.String{Value: args[0].Inspect()}
+}
+
+// intFn converts a number, boolean or string to an integer. Floats are
+// truncated toward zero. Strings are parsed the way integer literals are,
+// after an optional sign, so "0xff" and "1_000" are accepted but "010" is
+// not, and surrounding whitespace is ignored; anything else that is not an
+// integer is a ValueError.
+func intFn(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newKindError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=1", len(args))
+	}
+	switch arg := args[0].(type) {
+	case *object.Integer:
+		return arg
+	case *object.Float:
+		if math.IsNaN(arg.Value) || arg.Value < math.MinInt64 || arg.Value >= math.MaxInt64 {
+			return newKindError(object.VALUE_ERROR, "cannot convert %s to INTEGER", arg.Inspect())
+		}
+		return &object.Integer{Value: int64(arg.Value)}
+	case *object.Boolean:
+		if arg.Value {
+			return &object.Integer{Value: 1}
+		}
+		return &object.Integer{Value: 0}
+	case *object.String:
+		sign, literal := numberLiteral(arg.Value, token.INT)
+		value, err := strconv.ParseInt(sign+literal, 0, 64)
+		if literal == "" || err != nil {
+			return newKindError(object.VALUE_ERROR, "could not parse %q as integer", arg.Value)
+		}
+		return &object.Integer{Value: value}
+	default:
+		return newKindError(object.TYPE_ERROR, "argument to `int` not supported, got %s", args[0].Type())
+	}
+}
+
+// floatFn converts a number, boolean or string to a float. Strings are
+// parsed the way float and decimal integer literals are, after an optional
+// sign and ignoring surrounding whitespace, so "inf", "NaN" and hex floats
+// are ValueErrors.
+func floatFn(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newKindError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=1", len(args))
+	}
+	switch arg := args[0].(type) {
+	case *object.Float:
+		return arg
+	case *object.Integer:
+		return &object.Float{Value: float64(arg.Value)}
+	case *object.Boolean:
+		if arg.Value {
+			return &object.Float{Value: 1}
+		}
+		return &object.Float{Value: 0}
+	case *object.String:
+		sign, literal := numberLiteral(arg.Value, token.FLOAT)
+		if literal == "" {
+			// ParseFloat rejects 0x, 0o and 0b integers without a p exponent.
+			sign, literal = numberLiteral(arg.Value, token.INT)
+		}
+		value, err := strconv.ParseFloat(sign+literal, 64)
+		if literal == "" || err != nil {
+			return newKindError(object.VALUE_ERROR, "could not parse %q as float", arg.Value)
+		}
+		return &object.Float{Value: value}
+	default:
+		return newKindError(object.TYPE_ERROR, "argument to `float` not supported, got %s", args[0].Type())
+	}
+}
+
+// numberLiteral splits s, less surrounding whitespace, into an optional sign
+// and a literal, which is empty unless it is a single valid number literal
+// of type typ.
+func numberLiteral(s string, typ token.TokenType) (sign, literal string) {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "-") || strings.HasPrefix(s, "+") {
+		sign, s = s[:1], s[1:]
+	}
+	tok := lexer.New(s).NextToken()
+	if tok.Type != typ || tok.Literal != s {
+		return sign, ""
+	}
+	return sign, s
+}
+
+// boolFn tells whether a value counts as true in a condition: everything
+// but false and null does.
+func boolFn(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newKindError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=1", len(args))
+	}
+	return nativeBoolToObj(isTruthy(args[0]))
+}
+
+func isNullFn(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newKindError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=1", len(args))
+	}
+	return nativeBoolToObj(args[0] == NULL)
+}
+
+// isFnFn reports whether a value can be called: a function or a builtin.
+func isFnFn(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newKindError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=1", len(args))
+	}
+	switch args[0].(type) {
+	case *object.Function, *object.Builtin:
+		return TRUE
+	default:
+		return FALSE
+	}
+}
+
+// isIterableFn reports whether a for loop can run over a value.
+func isIterableFn(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newKindError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=1", len(args))
+	}
+	_, ok := args[0].(object.Iterable)
+	return nativeBoolToObj(ok)
+}
+
+// IsBuiltin reports whether name refers to a builtin function when no
+// binding hides it.
+func IsBuiltin(name string) bool {
+	_, ok := builtins[name]
+	return ok
+}
@@ -4,27 +4,71 @@ import (
 	"fmt"
 	"monkey/ast"
 	"monkey/object"
+	"strings"
 )
 
 var builtins = map[string]*object.Builtin{
-	"len":   {Fn: lenFn},
-	"print": {Fn: printFn},
-	"first": {Fn: firstFn},
-	"last":  {Fn: lastFn},
-	"rest":  {Fn: restFn},
-	"push":  {Fn: push},
+	"len":   {Name: "len", Fn: lenFn},
+	"print": {Name: "print", Fn: printFn},
+	"first": {Name: "first", Fn: firstFn},
+	"last":  {Name: "last", Fn: lastFn},
+	"rest":  {Name: "rest", Fn: restFn},
+	"push":  {Name: "push", Fn: push},
+	"split": {Name: "split", Fn: splitFn},
+	"join":  {Name: "join", Fn: joinFn},
+
+	"contains": {Name: "contains", Fn: containsFn},
+	"set":      {Name: "set", Fn: setFn},
+	"tuple":    {Name: "tuple", Fn: tupleFn},
+	"array":    {Name: "array", Fn: arrayFn},
+
+	"channel": {Name: "channel", Fn: channelFn},
+	"select":  {Name: "select", Fn: selectFn},
+
+	"type":        {Name: "type", Fn: typeFn},
+	"str":         {Name: "str", Fn: strFn},
+	"int":         {Name: "int", Fn: intFn},
+	"float":       {Name: "float", Fn: floatFn},
+	"bool":        {Name: "bool", Fn: boolFn},
+	"is_null":     {Name: "is_null", Fn: isNullFn},
+	"is_fn":       {Name: "is_fn", Fn: isFnFn},
+	"is_iterable": {Name: "is_iterable", Fn: isIterableFn},
+
+	"json_parse":     {Name: "json_parse", Fn: jsonParseFn},
+	"json_stringify": {Name: "json_stringify", Fn: jsonStringifyFn},
+
+	"read_file":   {Name: "read_file", Fn: readFileFn},
+	"write_file":  {Name: "write_file", Fn: writeFileFn},
+	"append_file": {Name: "append_file", Fn: appendFileFn},
+	"list_dir":    {Name: "list_dir", Fn: listDirFn},
+	"exists":      {Name: "exists", Fn: existsFn},
+	"remove":      {Name: "remove", Fn: removeFn},
 }
 
+// StrictIndexing makes indexing an array, tuple or string out of range an
+// IndexError instead of evaluating to null. Slices are clamped to the bounds
+// of the sequence either way.
+var StrictIndexing = false
+
 var (
 	TRUE  = &object.Boolean{Value: true}
 	FALSE = &object.Boolean{Value: false}
 	NULL  = &object.Null{}
 )
 
+// Eval evaluates node in env. Errors that do not know where they were raised
+// yet are stamped with the position of the innermost node that produced them.
 func Eval(node ast.Node, env *object.Environment) object.Object {
+	return withPos(eval(node, env), node)
+}
+
+func eval(node ast.Node, env *object.Environment) object.Object {
 	switch node := node.(type) {
 	// Statements
 	case *ast.Program:
+		if node.Frame != nil {
+			env = object.NewFrame(env, node.Frame.Slots, node.Frame.Cells, nil)
+		}
 		return evalProgram(node.Statements, env)
 	// --------------------------------
 	// --------------------------------
@@ -37,14 +81,43 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		if isError(val) {
 			return val
 		}
-		env.Set(node.Name.Value, val)
+		if node.Pattern != nil {
+			if err := bindPattern(node.Pattern, val, env); err != nil {
+				return err
+			}
+			return nil
+		}
+		bind(node.Name, val, env)
 	// --------------------------------
 	// --------------------------------
 	case *ast.ReturnStatement:
 		val := Eval(node.ReturnValue, env)
+		if isError(val) {
+			return val
+		}
 		return &object.ReturnValue{Value: val}
 	// --------------------------------
 	// --------------------------------
+	case *ast.ThrowStatement:
+		val := Eval(node.Value, env)
+		if isError(val) {
+			return val
+		}
+		return newThrownError(val)
+	// --------------------------------
+	// --------------------------------
+	case *ast.YieldStatement:
+		return evalYieldStatement(node, env)
+	// --------------------------------
+	// --------------------------------
+	case *ast.ImportStatement:
+		return evalImportStatement(node, env)
+	// --------------------------------
+	// --------------------------------
+	case *ast.ExportStatement:
+		return Eval(node.Statement, env)
+	// --------------------------------
+	// --------------------------------
 	case *ast.BlockStatement:
 		return evalBlockStatement(node, env)
 	// --------------------------------
@@ -52,18 +125,25 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 	case *ast.FunctionLiteral:
 		params := node.Parameters
 		body := node.Body
-		return &object.Function{Parameters: params, Body: body, Env: env}
+		if node.Frame != nil {
+			return newClosure(node, env)
+		}
+		return &object.Function{Parameters: params, Body: body, Env: env, Generator: node.Generator}
 	// --------------------------------
 	// --------------------------------
 	// Expressions
 	case *ast.NullExpression:
-		return &object.Null{}
+		return NULL
 	// --------------------------------
 	// --------------------------------
 	case *ast.IntegerLiteral:
 		return &object.Integer{Value: node.Value}
 	// --------------------------------
 	// --------------------------------
+	case *ast.FloatLiteral:
+		return &object.Float{Value: node.Value}
+	// --------------------------------
+	// --------------------------------
 	case *ast.StringLiteral:
 		return &object.String{Value: node.Value}
 	// --------------------------------
@@ -78,12 +158,21 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 	// --------------------------------
 	case *ast.PrefixExpression:
 		right := Eval(node.Right, env)
+		if isError(right) {
+			return right
+		}
 		return evalPrefixExpression(node.Operator, right)
 	// --------------------------------
 	// --------------------------------
 	case *ast.InfixExpression:
 		left := Eval(node.Left, env)
+		if isError(left) {
+			return left
+		}
 		right := Eval(node.Right, env)
+		if isError(right) {
+			return right
+		}
 		return evalInfixExpression(node.Operator, left, right)
 	// --------------------------------
 	// --------------------------------
@@ -91,6 +180,28 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		return evalIfExpression(node, env)
 	// --------------------------------
 	// --------------------------------
+	case *ast.MatchExpression:
+		return evalMatchExpression(node, env)
+	// --------------------------------
+	// --------------------------------
+	case *ast.TryExpression:
+		return evalTryExpression(node, env)
+	// --------------------------------
+	// --------------------------------
+	case *ast.ForExpression:
+		return evalForExpression(node, env)
+	case *ast.SpreadExpression:
+		return newError("spread is only allowed in array and set literals and argument lists")
+	// --------------------------------
+	// --------------------------------
+	case *ast.SpawnExpression:
+		return evalSpawnExpression(node, env)
+	// --------------------------------
+	// --------------------------------
+	case *ast.AwaitExpression:
+		return evalAwaitExpression(node, env)
+	// --------------------------------
+	// --------------------------------
 	case *ast.HashLiteral:
 		return evalHashLiteral(node, env)
 	// --------------------------------
@@ -101,6 +212,18 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 			return elements[0]
 		}
 		return &object.Array{Elements: elements}
+	case *ast.TupleLiteral:
+		elements := evalExpressions(node.Elements, env)
+		if len(elements) == 1 && isError(elements[0]) {
+			return elements[0]
+		}
+		return &object.Tuple{Elements: elements}
+	case *ast.SetLiteral:
+		elements := evalExpressions(node.Elements, env)
+		if len(elements) == 1 && isError(elements[0]) {
+			return elements[0]
+		}
+		return newSet(elements)
 	// --------------------------------
 	// --------------------------------
 	case *ast.IndexExpression:
@@ -113,16 +236,50 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 			return index
 		}
 		return evalIndexEpxression(left, index)
+	case *ast.SliceExpression:
+		left := Eval(node.Left, env)
+		if isError(left) {
+			return left
+		}
+		bounds := make([]object.Object, 3)
+		for i, bound := range []ast.Expression{node.Start, node.End, node.Step} {
+			if bound == nil {
+				continue
+			}
+			bounds[i] = Eval(bound, env)
+			if isError(bounds[i]) {
+				return bounds[i]
+			}
+		}
+		return evalSliceExpression(left, bounds[0], bounds[1], bounds[2])
+	// --------------------------------
+	// --------------------------------
+	case *ast.MemberExpression:
+		obj := Eval(node.Object, env)
+		if isError(obj) {
+			return obj
+		}
+		return evalMemberExpression(obj, node.Property.Value)
+	// --------------------------------
+	// --------------------------------
+	case *ast.AssignExpression:
+		return evalAssignExpression(node, env)
+	// --------------------------------
+	// --------------------------------
+	case *ast.MacroLiteral:
+		return newKindError(object.MACRO_ERROR, "macros can only be defined by a top-level let statement")
 	// --------------------------------
 	// --------------------------------
 	case *ast.CallExpression:
-		function := Eval(node.Function, env)
+		if ident, ok := node.Function.(*ast.Identifier); ok && ident.Value == "quote" {
+			return quote(node, env)
+		}
+		function, args := evalCall(node, env)
 		if isError(function) {
 			return function
 		}
-		args := evalExpressions(node.Arguments, env)
-		if len(args) == 1 && isError(args[0]) {
-			return args[0]
+		if fn, ok := function.(*object.Function); ok && node.Tail {
+			return &tailCall{function: fn, args: args, call: node}
 		}
 		return applyFunction(function, args)
 	}
@@ -148,6 +305,14 @@ func evalExpressions(expressions []ast.Expression, env *object.Environment) []ob
 	var results []object.Object
 
 	for _, e := range expressions {
+		if spread, ok := e.(*ast.SpreadExpression); ok {
+			elements, err := spreadElements(spread, env)
+			if err != nil {
+				return []object.Object{err}
+			}
+			results = append(results, elements...)
+			continue
+		}
 		evaluated := Eval(e, env)
 		if isError(evaluated) {
 			return []object.Object{evaluated}
@@ -182,7 +347,7 @@ func evalPrefixExpression(operator string, right object.Object) object.Object {
 	case "-":
 		return evalMinusPrefixOperatorExpression(right)
 	default:
-		return newError("unknown operator: %s%s", operator, right.Type())
+		return newKindError(object.TYPE_ERROR, "unknown operator: %s%s", operator, right.Type())
 	}
 
 }
@@ -201,27 +366,34 @@ func evalBangOperatorExpression(right object.Object) object.Object {
 }
 
 func evalMinusPrefixOperatorExpression(right object.Object) object.Object {
-	if right.Type() != object.INTEGER_OBJ {
-		return newError("unknown operator: -%s", right.Type())
+	switch right := right.(type) {
+	case *object.Integer:
+		return &object.Integer{Value: -right.Value}
+	case *object.Float:
+		return &object.Float{Value: -right.Value}
+	default:
+		return newKindError(object.TYPE_ERROR, "unknown operator: -%s", right.Type())
 	}
-	value := right.(*object.Integer).Value
-	return &object.Integer{Value: -value}
 }
 
 func evalInfixExpression(operator string, left, right object.Object) object.Object {
 	switch {
 	case left.Type() == object.INTEGER_OBJ && right.Type() == object.INTEGER_OBJ:
 		return evalIntegerInfixExpression(operator, left, right)
+	case isNumber(left) && isNumber(right):
+		return evalFloatInfixExpression(operator, left, right)
 	case left.Type() == object.STRING_OBJ && right.Type() == object.STRING_OBJ:
 		return evalStringInfixExpression(operator, left, right)
+	case left.Type() == object.SET_OBJ && right.Type() == object.SET_OBJ:
+		return evalSetInfixExpression(operator, left, right)
 	case operator == "==":
-		return nativeBoolToObj(left == right)
+		return nativeBoolToObj(object.Equal(left, right))
 	case operator == "!=":
-		return nativeBoolToObj(left != right)
+		return nativeBoolToObj(!object.Equal(left, right))
 	case right.Type() != left.Type():
-		return newError("type mismatch: %s %s %s", left.Type(), operator, right.Type())
+		return newKindError(object.TYPE_ERROR, "type mismatch: %s %s %s", left.Type(), operator, right.Type())
 	default:
-		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+		return newKindError(object.TYPE_ERROR, "unknown operator: %s %s %s", left.Type(), operator, right.Type())
 	}
 }
 
@@ -232,11 +404,48 @@ func evalStringInfixExpression(operator string, left, right object.Object) objec
 	switch operator {
 	case "+":
 		return &object.String{Value: leftVal + rightVal}
+	case "==":
+		return nativeBoolToObj(leftVal == rightVal)
+	case "!=":
+		return nativeBoolToObj(leftVal != rightVal)
 	default:
-		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+		return newKindError(object.TYPE_ERROR, "unknown operator: %s %s %s", left.Type(), operator, right.Type())
 	}
 }
 
+// evalSetInfixExpression implements union (|), intersection (&) and
+// difference (-). Elements keep the order of the left operand, followed by
+// those only found in the right one.
+func evalSetInfixExpression(operator string, left, right object.Object) object.Object {
+	leftSet := left.(*object.Set)
+	rightSet := right.(*object.Set)
+
+	var elements []object.Hashable
+	switch operator {
+	case "|":
+		for _, el := range leftSet.Elements() {
+			elements = append(elements, el.(object.Hashable))
+		}
+		for _, el := range rightSet.Elements() {
+			elements = append(elements, el.(object.Hashable))
+		}
+	case "&", "-":
+		keep := operator == "&"
+		for _, el := range leftSet.Elements() {
+			if rightSet.Contains(el.(object.Hashable)) == keep {
+				elements = append(elements, el.(object.Hashable))
+			}
+		}
+	case "==":
+		return nativeBoolToObj(object.Equal(left, right))
+	case "!=":
+		return nativeBoolToObj(!object.Equal(left, right))
+	default:
+		return newKindError(object.TYPE_ERROR, "unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+	return object.NewSet(elements...)
+}
+
 func evalIntegerInfixExpression(operator string, left, right object.Object) object.Object {
 	leftVal := left.(*object.Integer).Value
 	rightVal := right.(*object.Integer).Value
@@ -248,6 +457,9 @@ func evalIntegerInfixExpression(operator string, left, right object.Object) obje
 	case "*":
 		return &object.Integer{Value: leftVal * rightVal}
 	case "/":
+		if rightVal == 0 {
+			return newKindError(object.ZERO_DIVISION_ERROR, "division by zero")
+		}
 		return &object.Integer{Value: leftVal / rightVal}
 	case ">":
 		return nativeBoolToObj(leftVal > rightVal)
@@ -257,13 +469,47 @@ func evalIntegerInfixExpression(operator string, left, right object.Object) obje
 		return nativeBoolToObj(leftVal == rightVal)
 	case "!=":
 		return nativeBoolToObj(leftVal != rightVal)
+	case "..":
+		return &object.Range{Start: leftVal, End: rightVal}
+	case "..=":
+		return &object.Range{Start: leftVal, End: rightVal, Inclusive: true}
+	default:
+		return newKindError(object.TYPE_ERROR, "unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+}
+
+// evalFloatInfixExpression handles arithmetic where at least one side is a
+// float; integers are promoted.
+func evalFloatInfixExpression(operator string, left, right object.Object) object.Object {
+	leftVal := toFloat(left)
+	rightVal := toFloat(right)
+	switch operator {
+	case "+":
+		return &object.Float{Value: leftVal + rightVal}
+	case "-":
+		return &object.Float{Value: leftVal - rightVal}
+	case "*":
+		return &object.Float{Value: leftVal * rightVal}
+	case "/":
+		return &object.Float{Value: leftVal / rightVal}
+	case ">":
+		return nativeBoolToObj(leftVal > rightVal)
+	case "<":
+		return nativeBoolToObj(leftVal < rightVal)
+	case "==":
+		return nativeBoolToObj(leftVal == rightVal)
+	case "!=":
+		return nativeBoolToObj(leftVal != rightVal)
 	default:
-		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+		return newKindError(object.TYPE_ERROR, "unknown operator: %s %s %s", left.Type(), operator, right.Type())
 	}
 }
 
 func evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Object {
 	condition := Eval(ie.Condition, env)
+	if isError(condition) {
+		return condition
+	}
 	if isTruthy(condition) {
 		return Eval(ie.Consequence, env)
 	} else if ie.Alternative != nil {
@@ -273,86 +519,544 @@ func evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Obje
 	}
 }
 
+// evalMatchExpression tries each arm in order and evaluates the body of the
+// first one whose pattern matches and whose guard, if any, is truthy. The
+// bindings made by a pattern are only visible to its own guard and body.
+func evalMatchExpression(me *ast.MatchExpression, env *object.Environment) object.Object {
+	subject := Eval(me.Subject, env)
+	if isError(subject) {
+		return subject
+	}
+
+	for _, arm := range me.Arms {
+		armEnv := enclosedScope(env)
+		if err := bindPattern(arm.Pattern, subject, armEnv); err != nil {
+			continue
+		}
+
+		if arm.Guard != nil {
+			guard := Eval(arm.Guard, armEnv)
+			if isError(guard) {
+				return guard
+			}
+			if !isTruthy(guard) {
+				continue
+			}
+		}
+
+		return Eval(arm.Body, armEnv)
+	}
+
+	return newKindError(object.MATCH_ERROR, "no arm matched: %s", subject.Inspect())
+}
+
+// evalForExpression runs the body of a for loop once for each element of
+// the iterable. Every iteration has its own scope, so closures made in the
+// body keep the values the loop variables had when they were made.
+func evalForExpression(fe *ast.ForExpression, env *object.Environment) object.Object {
+	iterable := Eval(fe.Iterable, env)
+	if isError(iterable) {
+		return iterable
+	}
+	it, err := iterate(iterable)
+	if err != nil {
+		return err
+	}
+
+	for {
+		element, ok := it.Next()
+		if !ok {
+			return NULL
+		}
+		if isError(element) {
+			return element
+		}
+
+		loopEnv := enclosedScope(env)
+		if loopEnv.IsFrame() {
+			for _, cell := range fe.Fresh {
+				loopEnv.RenewCell(cell)
+			}
+		}
+		if err := bindPattern(fe.Pattern, element, loopEnv); err != nil {
+			return err
+		}
+
+		result := Eval(fe.Body, loopEnv)
+		if result != nil && (isError(result) || result.Type() == object.RETURN_VALUE_OBJ) {
+			return result
+		}
+	}
+}
+
+// evalTryExpression evaluates the try block and, if it failed, the catch block
+// with the error bound to the catch parameter. The finally block always runs;
+// an error or return raised inside it replaces the result of the other two.
+func evalTryExpression(te *ast.TryExpression, env *object.Environment) object.Object {
+	result := Eval(te.Block, env)
+
+	if err, ok := result.(*object.Error); ok && te.Catch != nil {
+		catchEnv := enclosedScope(env)
+		if te.CatchParam != nil {
+			if bindErr := bindPattern(te.CatchParam, errorToHash(err), catchEnv); bindErr != nil {
+				return bindErr
+			}
+		}
+		result = Eval(te.Catch, catchEnv)
+	}
+
+	if te.Finally != nil {
+		final := Eval(te.Finally, env)
+		if final != nil && (isError(final) || final.Type() == object.RETURN_VALUE_OBJ) {
+			return final
+		}
+	}
+
+	return result
+}
+
 func evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object {
-	if val, ok := env.Get(node.Value); ok {
-		return val
+	var val object.Object
+	switch node.Scope {
+	case ast.Local:
+		val = env.Slot(node.Slot)
+	case ast.Cell:
+		val = env.Cell(node.Slot).Get()
+	case ast.Free:
+		val = env.Free(node.Slot).Get()
+	case ast.Builtin:
+		return builtins[node.Value]
+	default:
+		if val, ok := env.Get(node.Value); ok {
+			return val
+		}
+		if val, ok := builtins[node.Value]; ok {
+			return val
+		}
 	}
-	if val, ok := builtins[node.Value]; ok {
-		return val
+	if val == nil {
+		return newKindError(object.NAME_ERROR, "identifier not found: %s", node.Value)
+	}
+	return val
+}
+
+// bind sets the variable name refers to.
+func bind(name *ast.Identifier, val object.Object, env *object.Environment) {
+	switch name.Scope {
+	case ast.Local:
+		env.SetSlot(name.Slot, val)
+	case ast.Cell:
+		env.Cell(name.Slot).Set(val)
+	default:
+		env.Set(name.Value, val)
 	}
-	return newError("identifier not found: %s", node.Value)
 }
 
+// newClosure creates the value of a resolved function literal, capturing
+// the variables it uses from the frame env.
+func newClosure(fl *ast.FunctionLiteral, env *object.Environment) *object.Function {
+	free := make([]*object.Cell, len(fl.Frame.Captures))
+	for i, capture := range fl.Frame.Captures {
+		if capture.Scope == ast.Cell {
+			free[i] = env.Cell(capture.Slot)
+		} else {
+			free[i] = env.Free(capture.Slot)
+		}
+	}
+	return &object.Function{Parameters: fl.Parameters, Body: fl.Body, Env: env.Global(), Frame: fl.Frame, Free: free, Generator: fl.Generator}
+}
+
+// enclosedScope returns the environment for a match arm, catch block or loop
+// iteration run in env. Resolved code keeps their variables in the frame it
+// runs in.
+func enclosedScope(env *object.Environment) *object.Environment {
+	if env.IsFrame() {
+		return env
+	}
+	return object.NewEnclosedEnvironment(env)
+}
+
+// evalCallee evaluates the function part of a call. For a method call such
+// as obj.method() it also returns obj, the receiver.
+func evalCallee(node ast.Expression, env *object.Environment) (function, receiver object.Object) {
+	member, ok := node.(*ast.MemberExpression)
+	if !ok {
+		return Eval(node, env), nil
+	}
+
+	receiver = Eval(member.Object, env)
+	if isError(receiver) {
+		return receiver, nil
+	}
+	return withPos(evalMemberExpression(receiver, member.Property.Value), member), receiver
+}
+
+// evalCall evaluates the function of a call and the arguments to call it
+// with, including the receiver if it wants one. It returns the error in
+// place of the function if either fails.
+func evalCall(call *ast.CallExpression, env *object.Environment) (function object.Object, args []object.Object) {
+	function, receiver := evalCallee(call.Function, env)
+	if isError(function) {
+		return function, nil
+	}
+	args = evalExpressions(call.Arguments, env)
+	if len(args) == 1 && isError(args[0]) {
+		return args[0], nil
+	}
+	if receiver != nil && takesReceiver(function) {
+		args = append([]object.Object{receiver}, args...)
+	}
+	return function, args
+}
+
+// takesReceiver reports whether fn wants the receiver of a method call: a
+// function whose first parameter is named self.
+func takesReceiver(fn object.Object) bool {
+	function, ok := fn.(*object.Function)
+	if !ok || len(function.Parameters) == 0 {
+		return false
+	}
+	self, ok := function.Parameters[0].(*ast.Identifier)
+	return ok && self.Value == "self"
+}
+
+// tailCall is what a call in tail position evaluates to: the function and
+// arguments of the call, which applyFunction makes in place of the function
+// returning it.
+type tailCall struct {
+	function *object.Function
+	args     []object.Object
+	call     *ast.CallExpression
+}
+
+func (tc *tailCall) Type() object.ObjectType { return "TAIL_CALL" }
+func (tc *tailCall) Inspect() string         { return "tail call " + tc.call.String() }
+
 func applyFunction(fn object.Object, args []object.Object) object.Object {
 	switch function := fn.(type) {
 	case *object.Function:
-		fnEnv := extendFuncEnv(function, args)
-		evaluated := Eval(function.Body, fnEnv)
-		return unwrapReturnValue(evaluated)
+		var site *ast.CallExpression // the tail call being made, if any
+		for {
+			fnEnv, err := extendFuncEnv(function, args)
+			if err != nil {
+				if site != nil {
+					err.Pos = site.Pos()
+				}
+				return err
+			}
+			if function.Generator {
+				return newGenerator(function, fnEnv)
+			}
+			evaluated := unwrapReturnValue(Eval(function.Body, fnEnv))
+
+			tail, ok := evaluated.(*tailCall)
+			if !ok {
+				return evaluated
+			}
+			function, args, site = tail.function, tail.args, tail.call
+		}
 	case *object.Builtin:
 		return function.Fn(args...)
 	default:
-		return newError("not a function: %s", function.Type())
+		return newKindError(object.TYPE_ERROR, "not a function: %s", function.Type())
 
 	}
 }
 
-func extendFuncEnv(fn *object.Function, args []object.Object) *object.Environment {
-	enclosedEnv := object.NewEnclosedEnvironment(fn.Env)
+func extendFuncEnv(fn *object.Function, args []object.Object) (*object.Environment, *object.Error) {
+	if len(args) != len(fn.Parameters) {
+		return nil, newKindError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=%d", len(args), len(fn.Parameters))
+	}
+
+	var enclosedEnv *object.Environment
+	if fn.Frame != nil {
+		enclosedEnv = object.NewFrame(fn.Env, fn.Frame.Slots, fn.Frame.Cells, fn.Free)
+	} else {
+		enclosedEnv = object.NewEnclosedEnvironment(fn.Env)
+	}
 	for i, param := range fn.Parameters {
-		arg := args[i]
-		enclosedEnv.Set(param.Value, arg)
+		if err := bindPattern(param, args[i], enclosedEnv); err != nil {
+			return nil, newKindError(err.Kind, "argument %d: %s", i+1, err.Messgae)
+		}
 	}
-	return enclosedEnv
+	return enclosedEnv, nil
 }
 
 func evalIndexEpxression(left, index object.Object) object.Object {
 	switch {
 	case left.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ:
-		return evalArrayIndexExpresion(left, index)
+		array := left.(*object.Array)
+		idx, ok := normalizeIndex(index.(*object.Integer).Value, len(array.Elements))
+		if !ok {
+			return indexOutOfRange(index)
+		}
+		return array.Get(int(idx))
+	case left.Type() == object.TUPLE_OBJ && index.Type() == object.INTEGER_OBJ:
+		return evalTupleIndexExpression(left.(*object.Tuple).Elements, index)
+	case left.Type() == object.STRING_OBJ && index.Type() == object.INTEGER_OBJ:
+		return evalStringIndexExpression(left, index)
 	case left.Type() == object.HASH_OBJ:
 		return evalHashIndexEpxpression(left, index)
 	default:
-		return newError("index operator not supported: %s", left.Type())
+		return newKindError(object.TYPE_ERROR, "index operator not supported: %s", left.Type())
+	}
+}
+
+// evalTupleIndexExpression indexes the elements of a tuple. Negative indices
+// count from the end.
+func evalTupleIndexExpression(elements []object.Object, index object.Object) object.Object {
+	idx, ok := normalizeIndex(index.(*object.Integer).Value, len(elements))
+	if !ok {
+		return indexOutOfRange(index)
+	}
+	return elements[idx]
+}
+
+// evalStringIndexExpression picks out a character, counting in runes rather
+// than bytes.
+func evalStringIndexExpression(str, index object.Object) object.Object {
+	runes := []rune(str.(*object.String).Value)
+	idx, ok := normalizeIndex(index.(*object.Integer).Value, len(runes))
+	if !ok {
+		return indexOutOfRange(index)
+	}
+	return &object.String{Value: string(runes[idx])}
+}
+
+// normalizeIndex turns a negative index into one counted from the start and
+// reports whether the result is within a sequence of the given length.
+func normalizeIndex(idx int64, length int) (int64, bool) {
+	if idx < 0 {
+		idx += int64(length)
+	}
+	return idx, idx >= 0 && idx < int64(length)
+}
+
+// indexOutOfRange is the result of reading outside a sequence: null, or an
+// IndexError in strict mode.
+func indexOutOfRange(index object.Object) object.Object {
+	if StrictIndexing {
+		return newKindError(object.INDEX_ERROR, "index out of range: %s", index.Inspect())
+	}
+	return NULL
+}
+
+// evalSliceExpression slices an array, tuple or string, whose positions are
+// those of its runes. Bounds left out are nil, and so are null bounds.
+func evalSliceExpression(left, start, end, step object.Object) object.Object {
+	var length int
+	var runes []rune
+	switch left := left.(type) {
+	case *object.Array:
+		length = len(left.Elements)
+	case *object.Tuple:
+		length = len(left.Elements)
+	case *object.String:
+		runes = []rune(left.Value)
+		length = len(runes)
+	default:
+		return newKindError(object.TYPE_ERROR, "slice operator not supported: %s", left.Type())
+	}
+
+	indices, err := sliceIndices(length, start, end, step)
+	if err != nil {
+		return err
+	}
+
+	switch left := left.(type) {
+	case *object.Array:
+		return &object.Array{Elements: pickElements(left.Snapshot(), indices)}
+	case *object.Tuple:
+		return &object.Tuple{Elements: pickElements(left.Elements, indices)}
+	default:
+		var out strings.Builder
+		for _, i := range indices {
+			out.WriteRune(runes[i])
+		}
+		return &object.String{Value: out.String()}
 	}
 }
 
-func evalArrayIndexExpresion(array, index object.Object) object.Object {
-	arrayObject := array.(*object.Array)
-	idx := index.(*object.Integer).Value
-	max := int64(len(arrayObject.Elements) - 1)
+// sliceIndices returns the positions a slice selects in a sequence of the
+// given length, following Python: negative bounds count from the end,
+// bounds past either end are clamped rather than errors, and a negative step
+// walks backwards starting from the last element.
+func sliceIndices(length int, start, end, step object.Object) ([]int, *object.Error) {
+	stepVal, hasStep, err := sliceBound(step)
+	if err != nil {
+		return nil, err
+	}
+	if !hasStep {
+		stepVal = 1
+	}
+	if stepVal == 0 {
+		return nil, newKindError(object.INDEX_ERROR, "slice step cannot be zero")
+	}
 
-	if idx < 0 || idx > max {
-		return NULL
+	n := int64(length)
+	// lower and upper are the smallest and largest positions a bound can be
+	// clamped to; -1 stands for "before the first element" when walking
+	// backwards.
+	lower, upper := int64(0), n
+	if stepVal < 0 {
+		lower, upper = -1, n-1
+	}
+	clamp := func(bound object.Object, fallback int64) (int64, *object.Error) {
+		idx, ok, err := sliceBound(bound)
+		if err != nil || !ok {
+			return fallback, err
+		}
+		if idx < 0 {
+			idx += n
+		}
+		return max(lower, min(idx, upper)), nil
+	}
+
+	first, last := lower, upper
+	if stepVal < 0 {
+		first, last = upper, lower
+	}
+	from, err := clamp(start, first)
+	if err != nil {
+		return nil, err
+	}
+	to, err := clamp(end, last)
+	if err != nil {
+		return nil, err
+	}
+
+	indices := []int{}
+	for i := from; (stepVal > 0 && i < to) || (stepVal < 0 && i > to); i += stepVal {
+		indices = append(indices, int(i))
+	}
+	return indices, nil
+}
+
+// sliceBound returns the value of a slice bound and whether it was given.
+func sliceBound(bound object.Object) (int64, bool, *object.Error) {
+	if bound == nil || bound == NULL {
+		return 0, false, nil
+	}
+	idx, ok := bound.(*object.Integer)
+	if !ok {
+		return 0, false, newKindError(object.TYPE_ERROR, "slice indices must be INTEGER, got %s", bound.Type())
 	}
+	return idx.Value, true, nil
+}
 
-	return arrayObject.Elements[idx]
+func pickElements(elements []object.Object, indices []int) []object.Object {
+	picked := make([]object.Object, len(indices))
+	for i, idx := range indices {
+		picked[i] = elements[idx]
+	}
+	return picked
 }
 
 func evalHashIndexEpxpression(hash, index object.Object) object.Object {
 	hashObject := hash.(*object.Hash)
-	hashableKey, ok := index.(object.Hashable)
-	if !ok {
-		return newError("unusable as hash key: %s", index.Type())
+	key, err := hashKey(index)
+	if err != nil {
+		return err
 	}
-	hashPair, ok := hashObject.Pairs[hashableKey.HashKey()]
+	value, ok := hashObject.Get(key)
 	if !ok {
 		return NULL
 	}
-	return hashPair.Value
+	return value
+}
+
+func evalMemberExpression(obj object.Object, name string) object.Object {
+	switch obj := obj.(type) {
+	case *object.Hash:
+		return evalHashIndexEpxpression(obj, &object.String{Value: name})
+	case *object.Module:
+		if val, ok := obj.Exports[name]; ok {
+			return val
+		}
+		return newKindError(object.NAME_ERROR, "module %s has no exported member %s", obj.Name, name)
+	case *object.Channel:
+		return channelMethod(obj, name)
+	case *object.Set:
+		return setMethod(obj, name)
+	case object.Iterable:
+		return iteratorMethod(obj, name)
+	default:
+		return newKindError(object.TYPE_ERROR, "member access not supported: %s", obj.Type())
+	}
+}
+
+// evalAssignExpression stores a value into a hash field or array element and
+// evaluates to the stored value.
+func evalAssignExpression(node *ast.AssignExpression, env *object.Environment) object.Object {
+	var container, key object.Object
+
+	switch target := node.Target.(type) {
+	case *ast.MemberExpression:
+		container = Eval(target.Object, env)
+		if isError(container) {
+			return container
+		}
+		key = &object.String{Value: target.Property.Value}
+	case *ast.IndexExpression:
+		container = Eval(target.Left, env)
+		if isError(container) {
+			return container
+		}
+		key = Eval(target.Index, env)
+		if isError(key) {
+			return key
+		}
+	default:
+		return newKindError(object.TYPE_ERROR, "cannot assign to %s", node.Target.String())
+	}
+
+	value := Eval(node.Value, env)
+	if isError(value) {
+		return value
+	}
+
+	switch container := container.(type) {
+	case *object.Hash:
+		if container.Frozen {
+			return newKindError(object.TYPE_ERROR, "cannot assign to frozen HASH")
+		}
+		hashable, err := hashKey(key)
+		if err != nil {
+			return err
+		}
+		container.Set(hashable, value)
+	case *object.Array:
+		if container.Frozen {
+			return newKindError(object.TYPE_ERROR, "cannot assign to frozen ARRAY")
+		}
+		index, ok := key.(*object.Integer)
+		if !ok {
+			return newKindError(object.TYPE_ERROR, "array index must be INTEGER, got %s", key.Type())
+		}
+		idx, ok := normalizeIndex(index.Value, len(container.Elements))
+		if !ok {
+			return newKindError(object.INDEX_ERROR, "index out of range: %d", index.Value)
+		}
+		container.Set(int(idx), value)
+	default:
+		return newKindError(object.TYPE_ERROR, "cannot assign to member of %s", container.Type())
+	}
+
+	return value
 }
 
 func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Object {
-	pairs := make(map[object.HashKey]object.HashPair)
-	for keyNode, valueNode := range node.Pairs {
+	hash := &object.Hash{}
+	for _, keyNode := range node.Keys() {
+		valueNode := node.Pairs[keyNode]
 		key := Eval(keyNode, env)
 		if isError(key) {
 			return key
 		}
 
-		hashKey, ok := key.(object.Hashable)
-		if !ok {
-			return newError("unusable as hash key: %s", key.Type())
+		hashable, err := hashKey(key)
+		if err != nil {
+			return err
 		}
 
 		value := Eval(valueNode, env)
@@ -360,11 +1064,10 @@ func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Obje
 			return value
 		}
 
-		hashed := hashKey.HashKey()
-		pairs[hashed] = object.HashPair{Key: key, Value: value}
+		hash.Set(hashable, value)
 	}
 
-	return &object.Hash{Pairs: pairs}
+	return hash
 }
 
 // ================================================
@@ -398,10 +1101,117 @@ func nativeBoolToObj(input bool) *object.Boolean {
 	}
 }
 
+// withPos stamps err with the position of node unless it already has one.
+func withPos(obj object.Object, node ast.Node) object.Object {
+	if err, ok := obj.(*object.Error); ok && err.Pos.Line == 0 {
+		err.Pos = node.Pos()
+	}
+	return obj
+}
+
 func newError(format string, a ...any) *object.Error {
-	return &object.Error{Messgae: fmt.Sprintf(format, a...)}
+	return newKindError(object.ERROR_KIND, format, a...)
+}
+
+func newKindError(kind string, format string, a ...any) *object.Error {
+	return &object.Error{Messgae: fmt.Sprintf(format, a...), Kind: kind}
+}
+
+// newThrownError turns the operand of a throw statement into an error. A
+// hash may carry its own "message" and "kind"; any other value becomes the
+// message through Inspect.
+func newThrownError(val object.Object) *object.Error {
+	err := &object.Error{Messgae: val.Inspect(), Kind: object.ERROR_KIND, Value: val}
+
+	if hash, ok := val.(*object.Hash); ok {
+		if message, ok := hashStringField(hash, "message"); ok {
+			err.Messgae = message
+		}
+		if kind, ok := hashStringField(hash, "kind"); ok {
+			err.Kind = kind
+		}
+	}
+	return err
+}
+
+// errorToHash exposes a caught error to scripts as a hash with the keys
+// message, kind, line, column and value.
+func errorToHash(err *object.Error) *object.Hash {
+	value := err.Value
+	if value == nil {
+		value = NULL
+	}
+	kind := err.Kind
+	if kind == "" {
+		kind = object.ERROR_KIND
+	}
+
+	fields := []struct {
+		key   string
+		value object.Object
+	}{
+		{"message", &object.String{Value: err.Messgae}},
+		{"kind", &object.String{Value: kind}},
+		{"line", &object.Integer{Value: int64(err.Pos.Line)}},
+		{"column", &object.Integer{Value: int64(err.Pos.Column)}},
+		{"value", value},
+	}
+
+	hash := &object.Hash{}
+	for _, field := range fields {
+		hash.Set(&object.String{Value: field.key}, field.value)
+	}
+	return hash
+}
+
+func hashStringField(hash *object.Hash, name string) (string, bool) {
+	value, ok := hash.Get(&object.String{Value: name})
+	if !ok {
+		return "", false
+	}
+	str, ok := value.(*object.String)
+	if !ok {
+		return "", false
+	}
+	return str.Value, true
+}
+
+// newSet builds a set of elements, or returns a TYPE_ERROR if one of them
+// cannot be a set element.
+func newSet(elements []object.Object) object.Object {
+	members := make([]object.Hashable, len(elements))
+	for i, el := range elements {
+		if !object.IsHashable(el) {
+			return newKindError(object.TYPE_ERROR, "unusable as set element: %s", el.Type())
+		}
+		members[i] = el.(object.Hashable)
+	}
+	return object.NewSet(members...)
+}
+
+// hashKey returns obj as a hash key, or a TYPE_ERROR if it cannot be one.
+func hashKey(obj object.Object) (object.Hashable, *object.Error) {
+	if !object.IsHashable(obj) {
+		return nil, newKindError(object.TYPE_ERROR, "unusable as hash key: %s", obj.Type())
+	}
+	return obj.(object.Hashable), nil
+}
+
+func isNumber(obj object.Object) bool {
+	return obj.Type() == object.INTEGER_OBJ || obj.Type() == object.FLOAT_OBJ
+}
+
+func toFloat(obj object.Object) float64 {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		return float64(obj.Value)
+	case *object.Float:
+		return obj.Value
+	default:
+		return 0
+	}
 }
 
 func isError(obj object.Object) bool {
-	return obj.Type() == object.ERROR_OBJ
+	return obj != nil && obj.Type() == object.ERROR_OBJ
 }
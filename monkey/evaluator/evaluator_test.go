@@ -4,6 +4,7 @@ import (
 	"monkey/lexer"
 	"monkey/object"
 	"monkey/parser"
+	"monkey/token"
 	"testing"
 )
 
@@ -37,6 +38,50 @@ func TestIntegerExpression(t *testing.T) {
 	}
 }
 
+func TestFloatExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected float64
+	}{
+		{"2.5", 2.5},
+		{"-2.5", -2.5},
+		{"1.5 + 1.5", 3},
+		{"1 + 0.5", 1.5},
+		{"0.5 * 4", 2},
+		{"7 / 2.0", 3.5},
+		{"1_000.5 - 0.5", 1000},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		result, ok := evaluated.(*object.Float)
+		if !ok {
+			t.Errorf("object is not object.Float. got=%T (%+v)", evaluated, evaluated)
+			continue
+		}
+		if result.Value != tt.expected {
+			t.Errorf("object.Value has wrong value. got=%g, want=%g", result.Value, tt.expected)
+		}
+	}
+}
+
+func TestNumericLiteralBases(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"0xff + 1", 256},
+		{"0o17", 15},
+		{"0b1010 * 2", 20},
+		{"1_000_000 / 1_000", 1000},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testIntegerObject(t, evaluated, tt.expected)
+	}
+}
+
 func TestStringObject(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -65,6 +110,23 @@ func TestStringConcatination(t *testing.T) {
 		testStringObject(t, evaluated, tt.expected)
 	}
 }
+
+func TestStringComparison(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{`"a" == "a"`, true},
+		{`"a" == "b"`, false},
+		{`"a" != "b"`, true},
+		{`"a" != "a"`, false},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testBooleanObject(t, evaluated, tt.expected)
+	}
+}
 func TestBooleanExpression(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -86,6 +148,8 @@ func TestBooleanExpression(t *testing.T) {
 		{"true != false", true},
 		{"null != null", false},
 		{"null == null", true},
+		{"1.5 < 2", true},
+		{"2.0 == 2", true},
 	}
 
 	for _, tt := range tests {
@@ -94,6 +158,48 @@ func TestBooleanExpression(t *testing.T) {
 	}
 }
 
+func TestStructuralEquality(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"[1, 2] == [1, 2]", true},
+		{"[1, 2] != [1, 2]", false},
+		{"[1, 2] == [2, 1]", false},
+		{"[1, 2] == [1, 2, 3]", false},
+		{"[1, [2, \"a\"]] == [1, [2, \"a\"]]", true},
+		{"[1] == [1.0]", true},
+		{"[] == []", true},
+		{"[null] == [null]", true},
+		{"{\"a\": 1, \"b\": [2]} == {\"b\": [2], \"a\": 1}", true},
+		{"{\"a\": 1} == {\"a\": 2}", false},
+		{"{\"a\": 1} == {\"b\": 1}", false},
+		{"{\"a\": 1} == {\"a\": 1, \"b\": 2}", false},
+		{"{} == []", false},
+		{"[1] == 1", false},
+		{"null == 0", false},
+		{"null == []", false},
+		{"let f = fn() {}; f == f", true},
+		{"fn() {} == fn() {}", false},
+		{"let a = [1]; a[0] = a; let b = [1]; b[0] = b; a == b", true},
+		{"let a = [1, 2]; a[0] = a; let b = [1, 3]; b[0] = b; a == b", false},
+		{"let h = {}; h[\"self\"] = h; let g = {}; g[\"self\"] = g; h == g", true},
+		{"let a = [1]; a[0] = a; a == a", true},
+		{"match [1, [2]] { [1, [2]] => true, _ => false }", true},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testBooleanObject(t, evaluated, tt.expected)
+	}
+}
+
+func TestNullIsASingleton(t *testing.T) {
+	if evaluated := testEval("null"); evaluated != NULL {
+		t.Errorf("null did not evaluate to NULL, got=%T (%+v)", evaluated, evaluated)
+	}
+}
+
 func TestBangOperator(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -138,6 +244,79 @@ func TestIfElseExpressions(t *testing.T) {
 	}
 }
 
+func TestElseIfExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"if (1 > 2) { 10 } else if (2 > 1) { 20 } else { 30 }", 20},
+		{"if (1 > 2) { 10 } else if (1 > 2) { 20 } else { 30 }", 30},
+		{"if (1 > 2) { 10 } else if (1 > 2) { 20 }", nil},
+		{"if (false) { 1 } else if (false) { 2 } else if (true) { 3 } else { 4 }", 3},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		expected, ok := tt.expected.(int)
+		if ok {
+			testIntegerObject(t, evaluated, int64(expected))
+		} else {
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestMatchExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{"match 1 { 1 => 10, 2 => 20 }", 10},
+		{"match 2 { 1 => 10, 2 => 20 }", 20},
+		{"match 3 { 1 => 10, _ => 30 }", 30},
+		{"match -1 { -1 => 10, _ => 30 }", 10},
+		{`match "b" { "a" => 1, "b" => 2 }`, 2},
+		{"match true { false => 1, true => 2 }", 2},
+		{"match null { 1 => 1, null => 2 }", 2},
+		{"match 5 { n => n * 2 }", 10},
+		{"match 5 { n if n > 10 => 1, n if n > 1 => 2, _ => 3 }", 2},
+		{"match [1, 2] { [a] => a, [a, b] => a + b }", 3},
+		{"match [1, [2, 3]] { [a, [b, c]] => a + b + c }", 6},
+		{"match [1, 2, 3, 4] { [first, ...rest] => len(rest) }", 3},
+		{"match [] { [a, ...rest] => 1, [] => 2 }", 2},
+		{"match 1 { [a] => 1, {a} => 2, _ => 3 }", 3},
+		{`match {"name": "Monkey", "age": 3} { {name, age: years} => years }`, 3},
+		{`match {"age": 3} { {name} => 1, {age} if age > 5 => 2, {age} => age }`, 3},
+		{`match {1: 10, true: 20} { {1: a, true: b} => a + b }`, 30},
+		{`match {"a": 1, "b": 2, "c": 3} { {a, ...others} => others["c"] + a }`, 4},
+		{`match {"a": 1, "b": 2} { {a, ...others} => others["a"] }`, nil},
+		{"let x = 1; match 2 { x => x }; x", 1},
+		{"match 3 { 1 => 10, 2 => 20 }", "no arm matched: 3"},
+		{"match [1] { [a, b] => a }", "no arm matched: [1]"},
+		{"match 1 { n if n + true => 1 }", "type mismatch: INTEGER + BOOLEAN"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Messgae != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Messgae)
+			}
+		default:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
 func TestReturnStatements(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -198,6 +377,139 @@ func TestErrorHandling(t *testing.T) {
 	}
 }
 
+func TestErrorKindsAndPositions(t *testing.T) {
+	tests := []struct {
+		input        string
+		expectedKind string
+		expectedPos  token.Position
+	}{
+		{"5 + true;", object.TYPE_ERROR, token.Position{Line: 1, Column: 3}},
+		{"let x = 1;\n  foobar;", object.NAME_ERROR, token.Position{Line: 2, Column: 3}},
+		{`len(1, 2)`, object.ARGUMENT_ERROR, token.Position{Line: 1, Column: 4}},
+		{"let f = fn() {\n  -true\n};\nf()", object.TYPE_ERROR, token.Position{Line: 2, Column: 3}},
+		{"match 1 { 2 => 2 }", object.MATCH_ERROR, token.Position{Line: 1, Column: 1}},
+		{"1 / 0", object.ZERO_DIVISION_ERROR, token.Position{Line: 1, Column: 3}},
+		{`throw "boom"`, object.ERROR_KIND, token.Position{Line: 1, Column: 1}},
+		{`throw {"kind": "ValueError", "message": "bad"}`, "ValueError", token.Position{Line: 1, Column: 1}},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned. got=%T (%+v)", evaluated, evaluated)
+			continue
+		}
+		if errObj.Kind != tt.expectedKind {
+			t.Errorf("wrong error kind for %q. expected=%q, got=%q", tt.input, tt.expectedKind, errObj.Kind)
+		}
+		if errObj.Pos != tt.expectedPos {
+			t.Errorf("wrong error position for %q. expected=%s, got=%s", tt.input, tt.expectedPos, errObj.Pos)
+		}
+	}
+}
+
+func TestTryCatchFinally(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{`try { 1 } catch (e) { 2 }`, 1},
+		{`try { throw "boom"; 1 } catch (e) { 2 }`, 2},
+		{`try { throw "boom" } catch (e) { e["message"] }`, "boom"},
+		{`try { throw "boom" } catch (e) { e["kind"] }`, "Error"},
+		{`try { throw 42 } catch (e) { e["value"] }`, 42},
+		{`try { 1 + true } catch (e) { e["kind"] }`, "TypeError"},
+		{`try { 1 + true } catch (e) { e["message"] }`, "type mismatch: INTEGER + BOOLEAN"},
+		{`try { len(1) } catch (e) { e["message"] }`, "argument to `len` not supported, got INTEGER"},
+		{`let kind = try { later } catch (e) { e["kind"] }; let later = 1; kind`, "NameError"},
+		{"try {\n  throw 1\n} catch (e) { e[\"line\"] * 10 + e[\"column\"] }", 23},
+		{`try { throw {"kind": "ValueError", "message": "bad"} } catch ({kind, message}) { kind + ": " + message }`, "ValueError: bad"},
+		{`try { throw "boom" } catch { 5 }`, 5},
+		{`let f = fn() { throw "inner" }; try { f() } catch (e) { e["message"] }`, "inner"},
+		{`try { try { throw "a" } catch (e) { throw e["message"] + "b" } } catch (e) { e["message"] }`, "ab"},
+		{`try { throw "a" } catch (e) { throw "b" }`, "b"},
+		{`try { 1 } finally { 2 }`, 1},
+		{`try { throw "a" } finally { 2 }`, "a"},
+		{`try { throw "a" } catch (e) { 1 } finally { throw "c" }`, "c"},
+		{`let x = 0; try { let x = 1 } finally { let y = 2 }; x + y`, 3},
+		{`let f = fn() { try { return 1 } finally { 2 } }; f()`, 1},
+		{`let f = fn() { try { return 1 } finally { return 2 } }; f()`, 2},
+		{`let f = fn() { try { throw "x" } catch (e) { return 3 }; 4 }; f()`, 3},
+		{`try { throw "x" } catch (e) { e }; 7`, 7},
+		{`try { (1 / 0) == 1 } catch (e) { e["kind"] }`, "ZeroDivisionError"},
+		{`try { 1 == (1 / 0) } catch (e) { e["kind"] }`, "ZeroDivisionError"},
+		{`try { !(1 / 0) } catch (e) { e["kind"] }`, "ZeroDivisionError"},
+		{`try { -(1 / 0) } catch (e) { e["kind"] }`, "ZeroDivisionError"},
+		{`try { if (1 / 0) { "yes" } } catch (e) { e["kind"] }`, "ZeroDivisionError"},
+		{`fn() { try { return 1 / 0 } catch (e) { "caught" } }()`, "caught"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			if errObj, ok := evaluated.(*object.Error); ok {
+				if errObj.Messgae != expected {
+					t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Messgae)
+				}
+				continue
+			}
+			testStringObject(t, evaluated, expected)
+		}
+	}
+}
+
+func TestMemberAccess(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{`let p = {"name": "Ada", "age": 36}; p.name`, "Ada"},
+		{`let p = {"name": "Ada", "age": 36}; p.age + 1`, 37},
+		{`let p = {"name": "Ada"}; p.missing`, nil},
+		{`let p = {"inner": {"x": 5}}; p.inner.x`, 5},
+		{`let p = {"age": 1}; p.age = 2; p.age`, 2},
+		{`let p = {}; p.age = 2`, 2},
+		{`let p = {}; p.name = "Ada"; p["name"]`, "Ada"},
+		{`let p = {"inner": {}}; p.inner.x = 3; p.inner.x`, 3},
+		{`let a = {}; let b = {}; a.x = b.x = 4; a.x + b.x`, 8},
+		{`let h = {}; h[1] = 10; h[1]`, 10},
+		{`let arr = [1, 2, 3]; arr[1] = 5; arr[1]`, 5},
+		{`let h = {"f": fn(x) { x * 2 }}; h.f(4)`, 8},
+		{`let c = {"n": 1, "inc": fn(self, by) { self.n = self.n + by }}; c.inc(2); c.inc(3); c.n`, 6},
+		{`let c = {"n": 1, "get": fn(self) { self.n }}; let get = c.get; get({"n": 9})`, 9},
+		{`let p = 1; p.x`, "member access not supported: INTEGER"},
+		{`let p = 1; p.x = 2`, "cannot assign to member of INTEGER"},
+		{`let arr = [1]; arr[5] = 1`, "index out of range: 5"},
+		{`let h = {}; h[fn() {}] = 1`, "unusable as hash key: FUNCTION"},
+		{`let h = {}; h.f()`, "not a function: NULL"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			if errObj, ok := evaluated.(*object.Error); ok {
+				if errObj.Messgae != expected {
+					t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Messgae)
+				}
+				continue
+			}
+			testStringObject(t, evaluated, expected)
+		default:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
 func TestLetStatements(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -215,6 +527,79 @@ func TestLetStatements(t *testing.T) {
 	}
 }
 
+func TestDestructuringLetStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{"let [a, b] = [1, 2]; a + b;", 3},
+		{"let [a, _, c] = [1, 2, 3]; a + c;", 4},
+		{"let [a, ...rest] = [1, 2, 3]; len(rest);", 2},
+		{"let [a, ...rest] = [1]; len(rest);", 0},
+		{"let [[a, b], [c]] = [[1, 2], [3]]; a + b + c;", 6},
+		{`let {name, age: years} = {"name": "Monkey", "age": 3}; years;`, 3},
+		{`let {name} = {"name": "Monkey", "age": 3}; len(name);`, 6},
+		{`let {tags: [first, ...others]} = {"tags": [1, 2, 3]}; first + len(others);`, 3},
+		{`let {address: {zip}} = {"address": {"zip": 1234}}; zip;`, 1234},
+		{`let {name, ...others} = {"name": "Monkey", "age": 3}; others["age"];`, 3},
+		{"let [a, b] = [1]; a;", "pattern mismatch: expected array of length 2, got 1"},
+		{"let [a, b, ...c] = [1]; a;", "pattern mismatch: expected array of at least length 2, got 1"},
+		{"let [a] = 1; a;", "pattern mismatch: expected ARRAY, got INTEGER"},
+		{`let {name} = {"age": 3}; name;`, "pattern mismatch: missing key name"},
+		{`let {name} = [1]; name;`, "pattern mismatch: expected HASH, got ARRAY"},
+		{`let [1, a] = [2, 3]; a;`, "pattern mismatch: expected 1, got 2"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Messgae != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Messgae)
+			}
+		}
+	}
+}
+
+func TestDestructuringParameters(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{"let add = fn([a, b]) { a + b }; add([1, 2]);", 3},
+		{`let age = fn({age}) { age }; age({"age": 3});`, 3},
+		{"let f = fn(x, [y, ...zs]) { x + y + len(zs) }; f(1, [2, 3, 4]);", 5},
+		{"let add = fn([a, b]) { a + b }; add(1);", "argument 1: pattern mismatch: expected ARRAY, got INTEGER"},
+		{"let add = fn(a, b) { a + b }; add(1);", "wrong number of arguments. got=1, want=2"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Messgae != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Messgae)
+			}
+		}
+	}
+}
+
 func TestFunctionObject(t *testing.T) {
 	input := "fn(x) { x + 2; }"
 
@@ -260,6 +645,7 @@ func TestBuiltinFunctions(t *testing.T) {
 		{`len("")`, 0},
 		{`len("four")`, 4},
 		{`len("hello world")`, 11},
+		{`len("héllo")`, 5},
 		{`len(1)`, "argument to `len` not supported, got INTEGER"},
 		{`len("one", "two")`, "wrong number of arguments. got=2, want=1"},
 	}
@@ -283,6 +669,31 @@ func TestBuiltinFunctions(t *testing.T) {
 	}
 }
 
+func TestStringBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`split("a,b,c", ",")`, "[a, b, c]"},
+		{`split("abc", ",")`, "[abc]"},
+		{`len(split("a,,b", ","))`, "3"},
+		{`join(["a", "b", "c"], "-")`, "a-b-c"},
+		{`join([], "-")`, ""},
+		{`join(["x", 1, true], " ")`, "x 1 true"},
+		{`join(split("a b c", " "), "")`, "abc"},
+		{`split(1, ",")`, "TypeError: first argument to `split` must be STRING, got=INTEGER"},
+		{`join("abc", ",")`, "TypeError: first argument to `join` must be ARRAY, got=STRING"},
+		{`join(["a"])`, "ArgumentError: wrong number of arguments. got=1, want=2"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%s: expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
 func TestArrayLiteral(t *testing.T) {
 	input := "[1, 2 * 2, 3 + 3]"
 
@@ -310,7 +721,10 @@ func TestArrayIndexExpressions(t *testing.T) {
 		{"let myArray = [1, 2, 3]; myArray[2];", 3},
 		{"let myArray = [1, 2, 3]; myArray[2] + myArray[1];", 5},
 		{"[1, 2, 3][3]", nil},
-		{"[1, 2, 3][-1]", nil},
+		{"[1, 2, 3][-1]", 3},
+		{"[1, 2, 3][-3]", 1},
+		{"[1, 2, 3][-4]", nil},
+		{"let a = [1, 2, 3]; a[-1] = 5; a[2]", 5},
 	}
 
 	for _, tt := range tests {
@@ -340,23 +754,24 @@ false: 6
 	if !ok {
 		t.Fatalf("Eval didn't return Hash. got=%T (%+v)", evaluated, evaluated)
 	}
-	expected := map[object.HashKey]int64{
-		(&object.String{Value: "one"}).HashKey():   1,
-		(&object.String{Value: "two"}).HashKey():   2,
-		(&object.String{Value: "three"}).HashKey(): 3,
-		(&object.Integer{Value: 4}).HashKey():      4,
-		TRUE.HashKey():                             5,
-		FALSE.HashKey():                            6,
+	expected := map[object.Hashable]int64{
+		&object.String{Value: "one"}:   1,
+		&object.String{Value: "two"}:   2,
+		&object.String{Value: "three"}: 3,
+		&object.Integer{Value: 4}:      4,
+		TRUE:                           5,
+		FALSE:                          6,
 	}
-	if len(result.Pairs) != len(expected) {
-		t.Fatalf("Hash has wrong num of pairs. got=%d", len(result.Pairs))
+	if result.Len() != len(expected) {
+		t.Fatalf("Hash has wrong num of pairs. got=%d", result.Len())
 	}
 	for expectedKey, expectedValue := range expected {
-		pair, ok := result.Pairs[expectedKey]
+		value, ok := result.Get(expectedKey)
 		if !ok {
-			t.Errorf("no pair for given key in Pairs")
+			t.Errorf("no pair for given key %s", expectedKey.Inspect())
+			continue
 		}
-		testIntegerObject(t, pair.Value, expectedValue)
+		testIntegerObject(t, value, expectedValue)
 	}
 }
 
@@ -384,6 +799,521 @@ func TestHashIndexExpressions(t *testing.T) {
 	}
 }
 
+func TestCompositeHashKeys(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{`{[1, 2]: 5}[[1, 2]]`, 5},
+		{`{[1, 2]: 5}[[2, 1]]`, nil},
+		{`{[1, [2, "a"]]: 5}[[1, [2, "a"]]]`, 5},
+		{`{{"a": 1, "b": 2}: 5}[{"b": 2, "a": 1}]`, 5},
+		{`{{"a": [1]}: 5}[{"a": [1]}]`, 5},
+		{`{[]: 5}[[]]`, 5},
+		{`let counts = {}; let key = ["ann", 1]; counts[key] = 1; counts[["ann", 1]] = counts[["ann", 1]] + 1; counts[key]`, 2},
+		{`let key = [1]; let h = {key: 5}; key[0] = 2; h[[1]]`, 5},
+		{`let key = [1]; let h = {key: 5}; key[0] = 2; h[[2]]`, nil},
+		{`let inner = [1]; let h = {[inner]: 5}; inner[0] = 2; h[[[1]]]`, 5},
+		{`{[1, 2]: 1, [1, 2]: 2}[[1, 2]]`, 2},
+		{`{[fn() {}]: 1}`, "unusable as hash key: ARRAY"},
+		{`{{"f": fn() {}}: 1}`, "unusable as hash key: HASH"},
+		{`{[1.5]: 1}`, "unusable as hash key: ARRAY"},
+		{`let a = [1]; a[0] = a; {a: 1}`, "unusable as hash key: ARRAY"},
+		{`match {"a": 1, [1]: 2} { {a, ...rest} => rest[[1]] }`, 2},
+		{`match {"a": 1, [1]: 2} { {a, ...rest} => rest["a"] }`, nil},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("%s: expected error, got=%T (%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Messgae != expected {
+				t.Errorf("%s: wrong error message. expected=%q, got=%q", tt.input, expected, errObj.Messgae)
+			}
+		default:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestHashKeysAreFrozen(t *testing.T) {
+	input := `let key = [1, [2]]; let h = {}; h[key] = "v"; key[1][0] = 3; h`
+	evaluated := testEval(input)
+	hash, ok := evaluated.(*object.Hash)
+	if !ok {
+		t.Fatalf("Eval didn't return Hash. got=%T (%+v)", evaluated, evaluated)
+	}
+	pairs := hash.Pairs()
+	if len(pairs) != 1 {
+		t.Fatalf("Hash has wrong num of pairs. got=%d", len(pairs))
+	}
+	stored, ok := pairs[0].Key.(*object.Array)
+	if !ok || !stored.Frozen {
+		t.Fatalf("key was not stored as a frozen array. got=%T (%+v)", pairs[0].Key, pairs[0].Key)
+	}
+	if stored.Inspect() != "[1, [2]]" {
+		t.Errorf("stored key changed with the original. got=%s", stored.Inspect())
+	}
+	if inner := stored.Elements[1].(*object.Array); !inner.Frozen {
+		t.Errorf("nested array of the key is not frozen")
+	}
+
+	env := object.NewEnvironment()
+	env.Set("key", stored)
+	l := lexer.New(`key[0] = 5`)
+	program := parser.New(l).ParseProgram()
+	evaluated = Eval(program, env)
+	errObj, ok := evaluated.(*object.Error)
+	if !ok || errObj.Messgae != "cannot assign to frozen ARRAY" {
+		t.Errorf("assigning into a frozen key did not fail. got=%T (%+v)", evaluated, evaluated)
+	}
+}
+
+func TestSliceExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"[1, 2, 3, 4, 5][1:3]", "[2, 3]"},
+		{"[1, 2, 3, 4, 5][:2]", "[1, 2]"},
+		{"[1, 2, 3, 4, 5][3:]", "[4, 5]"},
+		{"[1, 2, 3, 4, 5][:]", "[1, 2, 3, 4, 5]"},
+		{"[1, 2, 3, 4, 5][-2:]", "[4, 5]"},
+		{"[1, 2, 3, 4, 5][:-2]", "[1, 2, 3]"},
+		{"[1, 2, 3, 4, 5][::2]", "[1, 3, 5]"},
+		{"[1, 2, 3, 4, 5][1::2]", "[2, 4]"},
+		{"[1, 2, 3, 4, 5][::-1]", "[5, 4, 3, 2, 1]"},
+		{"[1, 2, 3, 4, 5][3:0:-1]", "[4, 3, 2]"},
+		{"[1, 2, 3, 4, 5][-1:-4:-2]", "[5, 3]"},
+		{"[1, 2, 3, 4, 5][10:]", "[]"},
+		{"[1, 2, 3, 4, 5][-10:2]", "[1, 2]"},
+		{"[1, 2, 3, 4, 5][3:1]", "[]"},
+		{"[1, 2, 3, 4, 5][10::-1]", "[5, 4, 3, 2, 1]"},
+		{"[1, 2, 3][null:2]", "[1, 2]"},
+		{"[][:]", "[]"},
+		{"let a = [1, 2]; let b = a[:]; b[0] = 5; a", "[1, 2]"},
+		{"let tail = fn(xs, n) { xs[n:] }; tail([1, 2, 3], 1)", "[2, 3]"},
+		{"(1, 2, 3)[1:]", "(2, 3)"},
+		{`"hello"[1:4]`, "ell"},
+		{`"hello"[::-1]`, "olleh"},
+		{`"hello"[-3:]`, "llo"},
+		{`"hello"[1]`, "e"},
+		{`"hello"[-1]`, "o"},
+		{`"hello"[5]`, "null"},
+		{`"héllo"[1]`, "é"},
+		{`"héllo"[-4]`, "é"},
+		{`"héllo"[::-1]`, "olléh"},
+		{`"日本語"[1:]`, "本語"},
+		{`"héllo"[5]`, "null"},
+		{"[1, 2, 3][::0]", "IndexError: slice step cannot be zero"},
+		{`[1, 2, 3]["a":]`, "TypeError: slice indices must be INTEGER, got STRING"},
+		{"{}[1:2]", "TypeError: slice operator not supported: HASH"},
+		{"let a = [1]; a[-2] = 1", "IndexError: index out of range: -2"},
+	}
+	for _, tt := range tests {
+		if got := testEval(tt.input).Inspect(); got != tt.expected {
+			t.Errorf("%s: expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestStrictIndexing(t *testing.T) {
+	StrictIndexing = true
+	defer func() { StrictIndexing = false }()
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"[1, 2, 3][3]", "IndexError: index out of range: 3"},
+		{"[1, 2, 3][-4]", "IndexError: index out of range: -4"},
+		{"(1,)[1]", "IndexError: index out of range: 1"},
+		{`"ab"[2]`, "IndexError: index out of range: 2"},
+		{"[1, 2, 3][-1]", "3"},
+		{"[1, 2, 3][1:10]", "[2, 3]"},
+		{`{"a": 1}["b"]`, "null"},
+		{"try { [][0] } catch (e) { e.kind }", "IndexError"},
+	}
+	for _, tt := range tests {
+		if got := testEval(tt.input).Inspect(); got != tt.expected {
+			t.Errorf("%s: expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestSets(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`#{1, 2, 2, 3}`, "#{1, 2, 3}"},
+		{`#{}`, "#{}"},
+		{`len(#{1, 1, [1], [1]})`, "2"},
+		{`#{1, 2} | #{2, 3}`, "#{1, 2, 3}"},
+		{`#{1, 2, 3} & #{3, 2, 5}`, "#{2, 3}"},
+		{`#{1, 2, 3} - #{2}`, "#{1, 3}"},
+		{`#{1, 2} | #{3} & #{3, 4}`, "#{1, 2, 3}"},
+		{`#{1, 2} == #{2, 1}`, "true"},
+		{`#{1, 2} != #{1}`, "true"},
+		{`#{1} == [1]`, "false"},
+		{`#{1}.add(2)`, "#{1, 2}"},
+		{`let s = #{1}; s.add(2); s`, "#{1}"},
+		{`#{1}.add(1)`, "#{1}"},
+		{`#{1, 2, 3}.remove(2)`, "#{1, 3}"},
+		{`#{1}.remove(5)`, "#{1}"},
+		{`let add = fn(a, b) { a + b }; #{1}.add(add(1, 1))`, "#{1, 2}"},
+		{`#{1, 2}.map(fn(x) { x * 10 }).collect()`, "[10, 20]"},
+		{`contains(#{1, [2, 3]}, [2, 3])`, "true"},
+		{`contains(#{1}, 2)`, "false"},
+		{`contains(#{1}, fn() {})`, "false"},
+		{`set([3, 1, 3, 2])`, "#{3, 1, 2}"},
+		{`len(set(split("a,b,a", ",")))`, "2"},
+		{`array(#{1, 2})`, "[1, 2]"},
+		{`let key = [1]; let s = #{key}; key[0] = 2; contains(s, [1])`, "true"},
+		{`{#{1, 2}: "a"}[#{2, 1}]`, "a"},
+		{`#{#{1}, #{1}}`, "#{#{1}}"},
+		{`#{fn() {}}`, "TypeError: unusable as set element: FUNCTION"},
+		{`#{}.add({"f": fn() {}})`, "TypeError: unusable as set element: HASH"},
+		{`#{1} + #{2}`, "TypeError: unknown operator: SET + SET"},
+		{`#{1} | [2]`, "TypeError: type mismatch: SET | ARRAY"},
+		{`1 | 2`, "TypeError: unknown operator: INTEGER | INTEGER"},
+		{`#{1}.add()`, "ArgumentError: wrong number of arguments. got=0, want=1"},
+		{`#{1}.push(2)`, "NameError: SET has no method push"},
+		{`#{1}[0]`, "TypeError: index operator not supported: SET"},
+	}
+	for _, tt := range tests {
+		if got := testEval(tt.input).Inspect(); got != tt.expected {
+			t.Errorf("%s: expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestTuples(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`(1, "a", [2])`, "(1, a, [2])"},
+		{`(1,)`, "(1,)"},
+		{`()`, "()"},
+		{`(1)`, "1"},
+		{`len((1, 2, 3))`, "3"},
+		{`(1, 2)[1]`, "2"},
+		{`(1, 2)[5]`, "null"},
+		{`(1, [2]) == (1, [2])`, "true"},
+		{`(1, 2) == [1, 2]`, "false"},
+		{`(1, 2) != (2, 1)`, "true"},
+		{`let t = (1, 2); t[0] = 5`, "TypeError: cannot assign to member of TUPLE"},
+		{`let scores = {("ann", 1): 10}; scores[("ann", 1)]`, "10"},
+		{`let scores = {}; scores[("ann", 1)] = 1; scores[("bob", 1)] = 2; len(array(#{("ann", 1), ("ann", 1)}))`, "1"},
+		{`{(1, fn() {}): 1}`, "TypeError: unusable as hash key: TUPLE"},
+		{`let a = [1]; let t = (a,); a[0] = 2; t`, "([2],)"},
+		{`let a = [1]; let h = {(a,): 1}; a[0] = 2; h[([1],)]`, "1"},
+		{`tuple([1, 2])`, "(1, 2)"},
+		{`array((1, 2))`, "[1, 2]"},
+		{`contains((1, [2]), [2])`, "true"},
+		{`let a = [0]; let t = (a,); a[0] = t; t == t`, "true"},
+	}
+	for _, tt := range tests {
+		if got := testEval(tt.input).Inspect(); got != tt.expected {
+			t.Errorf("%s: expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestRanges(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`0..3`, "0..3"},
+		{`1..=3`, "1..=3"},
+		{`array(0..3)`, "[0, 1, 2]"},
+		{`array(1..=3)`, "[1, 2, 3]"},
+		{`array(3..1)`, "[]"},
+		{`tuple(-2..0)`, "(-2, -1)"},
+		{`len(0..10)`, "10"},
+		{`len(5..=5)`, "1"},
+		{`len(5..0)`, "0"},
+		{`let n = 4; len(0..n + 1)`, "5"},
+		{`contains(0..10, 9)`, "true"},
+		{`contains(0..10, 10)`, "false"},
+		{`contains(0..=10, 10)`, "true"},
+		{`contains(0..10, "a")`, "false"},
+		{`0..3 == 0..=2`, "true"},
+		{`3..0 == 5..1`, "true"},
+		{`0..3 == [0, 1, 2]`, "false"},
+		{`len(0..9223372036854775807)`, "9223372036854775807"},
+		{`1.5..3`, "TypeError: unknown operator: FLOAT .. INTEGER"},
+		{`"a"..="c"`, "TypeError: unknown operator: STRING ..= STRING"},
+	}
+	for _, tt := range tests {
+		if got := testEval(tt.input).Inspect(); got != tt.expected {
+			t.Errorf("%s: expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestForExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let acc = {"sum": 0}; for (i in 1..=4) { acc.sum = acc.sum + i }; acc.sum`, "10"},
+		{`for (x in [1, 2]) { x }`, "null"},
+		{`let acc = {"s": ""}; for (c in "abc") { acc.s = c + acc.s }; acc.s`, "cba"},
+		{`let acc = {"s": ""}; for (c in "héllo") { acc.s = c + acc.s }; acc.s`, "olléh"},
+		{`let acc = {"keys": []}; for (k in {"b": 1, "a": 2}) { acc.keys = push(acc.keys, k) }; acc.keys`, "[b, a]"},
+		{`let acc = {"n": 0}; for ([a, b] in [[1, 2], [3, 4]]) { acc.n = acc.n + a * b }; acc.n`, "14"},
+		{`let acc = {"n": 0}; for ({x} in [{"x": 1}, {"x": 2}]) { acc.n = acc.n + x }; acc.n`, "3"},
+		{`let acc = {"all": []}; for (x in #{3, 1}) { acc.all = push(acc.all, x) }; acc.all`, "[3, 1]"},
+		{`let a = [1, 2, 3]; let acc = {"seen": []}; for (x in a) { a[2] = 9; acc.seen = push(acc.seen, x) }; acc.seen`, "[1, 2, 9]"},
+		{`let f = fn() { for (x in 0..10) { if (x == 3) { return x } }; 99 }; f()`, "3"},
+		{`for (x in 0..3) { let y = x }; y`, "NameError: identifier not found: y"},
+		{`for (x in 0..3) { x }; x`, "NameError: identifier not found: x"},
+		{`for (x in 5) { x }`, "TypeError: INTEGER is not iterable"},
+		{`for ([a] in [1]) { a }`, "MatchError: pattern mismatch: expected ARRAY, got INTEGER"},
+		{`for (x in [1, 0]) { 1 / x }`, "ZeroDivisionError: division by zero"},
+		{`let fs = {"all": []}; for (i in 0..3) { fs.all = push(fs.all, fn() { i }) }; [fs.all[0](), fs.all[2]()]`, "[0, 2]"},
+	}
+	for _, tt := range tests {
+		if got := testEval(tt.input).Inspect(); got != tt.expected {
+			t.Errorf("%s: expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestSpreadExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let xs = [2, 3]; [1, ...xs, 4]`, "[1, 2, 3, 4]"},
+		{`[...0..3, ...(1, 2)]`, "[0, 1, 2, 1, 2]"},
+		{`#{...[1, 2, 2], 3}`, "#{1, 2, 3}"},
+		{`[..."ab"]`, "[a, b]"},
+		{`[...[]]`, "[]"},
+		{`let f = fn(a, b, c) { a + b + c }; f(...[1, 2, 3])`, "6"},
+		{`let f = fn(a, b, c) { a + b + c }; f(1, ...[2, 3])`, "6"},
+		{`let f = fn(a, b) { a + b }; f(...[1, 2, 3])`, "ArgumentError: wrong number of arguments. got=3, want=2"},
+		{`len(..."a")`, "1"},
+		{`[...1]`, "TypeError: INTEGER is not iterable"},
+		{`[...(0..3).map(fn(x) { 1 / x })]`, "ZeroDivisionError: division by zero"},
+	}
+	for _, tt := range tests {
+		if got := testEval(tt.input).Inspect(); got != tt.expected {
+			t.Errorf("%s: expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestIteratorMethods(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`(0..5).map(fn(x) { x * x }).collect()`, "[0, 1, 4, 9, 16]"},
+		{`(0..10).filter(fn(x) { x / 2 * 2 == x }).collect()`, "[0, 2, 4, 6, 8]"},
+		{`(0..100).take(3).collect()`, "[0, 1, 2]"},
+		{`(0..5).skip(3).collect()`, "[3, 4]"},
+		{`(0..5).skip(10).collect()`, "[]"},
+		{`(0..10).take_while(fn(x) { x < 3 }).collect()`, "[0, 1, 2]"},
+		{`(0..6).skip_while(fn(x) { x < 3 }).collect()`, "[3, 4, 5]"},
+		{`(1..3).chain([7]).collect()`, "[1, 2, 7]"},
+		{`(0..3).zip("abcd").collect()`, "[[0, a], [1, b], [2, c]]"},
+		{`(5..7).enumerate().collect()`, "[[0, 5], [1, 6]]"},
+		{`(1..=4).reduce(0, fn(acc, x) { acc + x })`, "10"},
+		{`(0..1000000).filter(fn(x) { x > 10 }).count()`, "999989"},
+		{`let r = 0..3; r.collect(); r.count()`, "3"},
+		{`let it = (0..3).map(fn(x) { x }); it.collect(); it.count()`, "0"},
+		{`let calls = {"n": 0}; let squares = (0..1000000000).map(fn(x) { calls.n = calls.n + 1; x * x }); [squares.filter(fn(x) { x > 10 }).take(2).collect(), calls.n]`, "[[16, 25], 6]"},
+		{`(0..3).map(len).collect()`, "TypeError: argument to `len` not supported, got INTEGER"},
+		{`(0..3).map(1)`, "TypeError: argument to `map` must be FUNCTION, got INTEGER"},
+		{`(0..3).take(-1)`, "ArgumentError: argument to `take` must not be negative, got -1"},
+		{`(0..3).take()`, "ArgumentError: wrong number of arguments. got=0, want=1"},
+		{`(0..3).zip(1)`, "TypeError: INTEGER is not iterable"},
+		{`(0..3).push`, "NameError: RANGE has no method push"},
+		{`[1, 2].map(fn(x) { x * 2 }).collect()`, "[2, 4]"},
+		{`["a", "b"].enumerate().collect()`, "[[0, a], [1, b]]"},
+		{`(1, 2, 3).filter(fn(x) { x > 1 }).count()`, "2"},
+		{`"abc".map(fn(c) { c + c }).collect()`, "[aa, bb, cc]"},
+		{`let xs = [1, 2]; xs.map(fn(x) { x }).collect(); xs.count()`, "2"},
+		{`[1].push`, "NameError: ARRAY has no method push"},
+		{`[1].next`, "NameError: ARRAY has no method next"},
+		{`array((0..4).map(fn(x) { x * 2 }))`, "[0, 2, 4, 6]"},
+		{`set((0..6).map(fn(x) { x / 2 }))`, "#{0, 1, 2}"},
+		{`contains((0..3).map(fn(x) { x * 10 }), 20)`, "true"},
+		{`(0..3).map(fn(x) { x })`, "<iterator>"},
+	}
+	for _, tt := range tests {
+		if got := testEval(tt.input).Inspect(); got != tt.expected {
+			t.Errorf("%s: expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestGenerators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let g = fn() { yield 1; yield 2; }; let it = g(); [it.next(), it.next(), it.next(), it.next()]`, "[1, 2, null, null]"},
+		{`let g = fn(n) { for (i in 0..n) { yield i * i; } }; g(4).collect()`, "[0, 1, 4, 9]"},
+		{`let g = fn(n) { for (i in 0..n) { yield i; } }; [...g(3), ...g(2)]`, "[0, 1, 2, 0, 1]"},
+		{`let g = fn() { yield 1; return 5; yield 2; }; g().collect()`, "[1]"},
+		{`let g = fn() { yield 1; }; g()`, "<generator>"},
+		{`let naturals = fn() { let count = fn(n) { yield n; for (x in count(n + 1)) { yield x; } }; count(0) }; naturals().take(4).collect()`, "[0, 1, 2, 3]"},
+		{`let ones = fn() { for (_ in 0..1000000000) { yield 1; } }; ones().map(fn(x) { x * 2 }).take(3).collect()`, "[2, 2, 2]"},
+		{`let g = fn() { yield 1; }; let a = g(); let b = g(); [a.next(), a.next(), b.next()]`, "[1, null, 1]"},
+		{`let log = {"steps": []}; let g = fn() { log.steps = push(log.steps, "start"); yield 1; log.steps = push(log.steps, "end"); }; let it = g(); let before = log.steps; it.next(); let during = log.steps; it.next(); [before, during, log.steps]`, "[[], [start], [start, end]]"},
+		{`let counter = fn(start) { let step = 2; fn() { let n = start; yield n; yield n + step; } }; counter(10)().collect()`, "[10, 12]"},
+		{`let g = fn() { let x = 1; yield x; let y = x + 1; yield y; }; let fs = g().map(fn(v) { fn() { v } }).collect(); [fs[0](), fs[1]()]`, "[1, 2]"},
+		{`let g = fn() { for (i in 0..3) { yield fn() { i }; } }; let fs = g().collect(); [fs[0](), fs[2]()]`, "[0, 2]"},
+		{`let g = fn() { yield 1; yield 1 / 0; yield 3; }; let it = g(); [it.next(), len(it.collect())]`, "ZeroDivisionError: division by zero"},
+		{`let g = fn() { yield 1; yield 1 / 0; yield 3; }; let it = g(); [it.next()]`, "[1]"},
+		{`let g = fn() { yield 1; yield 1 / 0; yield 3; }; let it = g(); it.next(); it.next()`, "ZeroDivisionError: division by zero"},
+		{`let g = fn() { yield 1; throw "boom"; }; g().collect()`, "Error: boom"},
+		{`let g = fn() { yield 1; missing; }; for (x in g()) { x }`, "NameError: identifier not found: missing"},
+		{`let g = fn(a, b) { yield a; }; g(1)`, "ArgumentError: wrong number of arguments. got=1, want=2"},
+		{`let g = fn(x) { yield x; }; let f = fn(x) { g(x + 1) }; f(1).collect()`, "[2]"},
+		{`let g = fn() { yield g_it.next(); }; let g_it = g(); g_it.next()`, "Error: generator is already running"},
+		{`let g = fn() { yield 1; }; contains(g(), 1)`, "true"},
+		{`(0..3).next`, "NameError: RANGE has no method next"},
+		{`let it = (0..2).map(fn(x) { x + 1 }); [it.next(), it.next(), it.next()]`, "[1, 2, null]"},
+	}
+	for _, tt := range tests {
+		if got := testEval(tt.input).Inspect(); got != tt.expected {
+			t.Errorf("%s: expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestTasks(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let f = fn(x) { x * 2 }; await spawn f(21)`, "42"},
+		{`await spawn fn() { "done" }`, "done"},
+		{`let fs = array((0..5).map(fn(i) { spawn fn() { i * i } })); (0..5).map(fn(i) { await fs[i] }).collect()`, "[0, 1, 4, 9, 16]"},
+		{`let f = spawn len([1, 2]); [await f, await f]`, "[2, 2]"},
+		{`let f = spawn fn() { 1 }; await f; f`, "<future 1>"},
+		{`let point = {"x": 1, "get": fn(self) { self.x }}; await spawn point.get()`, "1"},
+		{`let ch = channel(); spawn fn() { for (i in 0..3) { ch.send(i) }; ch.close() }; ch.collect()`, "[0, 1, 2]"},
+		{`let ch = channel(2); ch.send(1); ch.send(2); ch.close(); [ch.recv(), ch.recv(), ch.recv()]`, "[1, 2, null]"},
+		{`let ch = channel(1); ch.send("x"); ch`, "<channel 1/1>"},
+		{`let ch = channel(); spawn fn() { ch.send(5) }; ch.map(fn(x) { x + 1 }).take(1).collect()`, "[6]"},
+		{`let results = channel(); let worker = fn(id) { results.send(id * 10) }; for (i in 0..4) { spawn worker(i) }; set(results.take(4)) == #{0, 10, 20, 30}`, "true"},
+		{`let a = channel(1); let b = channel(1); b.send("b"); select(a, b)`, "[1, b]"},
+		{`let a = channel(1); select((a, 7), channel()); a.recv()`, "7"},
+		{`let a = channel(); let b = channel(1); a.close(); b.send(2); select(a, b)`, "[1, 2]"},
+		{`let a = channel(); a.close(); select(a)`, "null"},
+		{`select()`, "null"},
+		{`let done = channel(); let work = fn() { let x = 1; done.send(x) }; for (_ in 0..10) { spawn work() }; done.take(10).reduce(0, fn(a, b) { a + b })`, "10"},
+		{`await spawn fn() { 1 / 0 }`, "ZeroDivisionError: division by zero"},
+		{`let f = spawn fn() { throw "boom" }; try { await f } catch (e) { e.message }`, "boom"},
+		{`let f = fn(a, b) { a }; await spawn f(1)`, "ArgumentError: wrong number of arguments. got=1, want=2"},
+		{`let f = spawn fn() { 1 / 0 }; 1`, "1"},
+		{`spawn 1`, "TypeError: cannot spawn INTEGER"},
+		{`spawn missing()`, "NameError: identifier not found: missing"},
+		{`await 1`, "TypeError: cannot await INTEGER"},
+		{`let ch = channel(); ch.close(); ch.send(1)`, "Error: send on closed channel"},
+		{`let ch = channel(); ch.close(); ch.close()`, "Error: close of closed channel"},
+		{`let ch = channel(); ch.close(); select((ch, 1))`, "Error: send on closed channel"},
+		{`select(1)`, "TypeError: argument 1 to `select` must be CHANNEL or TUPLE, got INTEGER"},
+		{`select((1, 2))`, "TypeError: argument 1 to `select` must be (CHANNEL, value), got (1, 2)"},
+		{`channel(-1)`, "ArgumentError: argument to `channel` must not be negative, got -1"},
+		{`channel().push`, "NameError: CHANNEL has no method push"},
+	}
+	for _, tt := range tests {
+		if got := testEval(tt.input).Inspect(); got != tt.expected {
+			t.Errorf("%s: expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+// TestTasksShareValues has tasks write to the same hash, array and captured
+// variables at once. Run it with -race.
+func TestTasksShareValues(t *testing.T) {
+	input := `
+let h = {};
+let sums = [0, 0, 0, 0, 0, 0];
+let stats = {"reads": 0};
+let worker = fn(i) {
+  let seen = {"last": null};
+  let tasks = (0..100).map(fn(j) {
+    h[i * 100 + j] = j;
+    h.shared = j;
+    sums[i] = sums[i] + j;
+    seen.last = len(str(h)) + len(str(sums));
+    stats.reads = stats.reads + 1;
+  });
+  tasks.count()
+};
+let fs = array((0..6).map(fn(i) { spawn worker(i) }));
+let reader = spawn fn() { (0..50).map(fn(_) { [len(array(h)), h == h, sums[0], set(h)] }).count() };
+[fs.map(fn(f) { await f }).reduce(0, fn(a, b) { a + b }), await reader, len(array(h)), sums]
+`
+	expected := "[600, 50, 601, [4950, 4950, 4950, 4950, 4950, 4950]]"
+	if got := testEval(input).Inspect(); got != expected {
+		t.Errorf("expected=%q, got=%q", expected, got)
+	}
+}
+
+func TestConversionBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`[type(1), type(1.5), type("a"), type(true), type(null)]`, "[INTEGER, FLOAT, STRING, BOOLEAN, NULL]"},
+		{`[type([]), type({}), type(#{}), type((1,)), type(0..1)]`, "[ARRAY, HASH, SET, TUPLE, RANGE]"},
+		{`[type(fn() {}), type(len), type(channel()), type(spawn len([]))]`, "[FUNCTION, BUILTIN, CHANNEL, FUTURE]"},
+		{`type(1) == "INTEGER"`, "true"},
+		{`[str(1), str(2.5), str(true), str(null), str("s"), str([1, "a"])]`, "[1, 2.5, true, null, s, [1, a]]"},
+		{`len(str(123))`, "3"},
+		{`[int("42"), int(" -7 "), int("0xff"), int("1_000"), int(3.9), int(-3.9), int(true), int(5)]`, "[42, -7, 255, 1000, 3, -3, 1, 5]"},
+		{`int("4.2")`, `ValueError: could not parse "4.2" as integer`},
+		{`int("")`, `ValueError: could not parse "" as integer`},
+		{`[int("0"), int("-0o17"), int("+0b101"), int("-9223372036854775808")]`, "[0, -15, 5, -9223372036854775808]"},
+		{`int("010")`, `ValueError: could not parse "010" as integer`},
+		{`int("08")`, `ValueError: could not parse "08" as integer`},
+		{`int("1__0")`, `ValueError: could not parse "1__0" as integer`},
+		{`int("--1")`, `ValueError: could not parse "--1" as integer`},
+		{`int("99999999999999999999")`, `ValueError: could not parse "99999999999999999999" as integer`},
+		{`int(1e300)`, "ValueError: cannot convert 1e+300 to INTEGER"},
+		{`int([1])`, "TypeError: argument to `int` not supported, got ARRAY"},
+		{`try { int("x") } catch (e) { e.kind }`, "ValueError"},
+		{`[float("2.5"), float(" 1e3 "), float(2), float(false)]`, "[2.5, 1000.0, 2.0, 0.0]"},
+		{`float("two")`, `ValueError: could not parse "two" as float`},
+		{`[float("-1_000.5"), float("+2"), float("010.5"), float("-0")]`, "[-1000.5, 2.0, 10.5, -0.0]"},
+		{`float("inf")`, `ValueError: could not parse "inf" as float`},
+		{`float("NaN")`, `ValueError: could not parse "NaN" as float`},
+		{`float("0x1p4")`, `ValueError: could not parse "0x1p4" as float`},
+		{`float("0xff")`, `ValueError: could not parse "0xff" as float`},
+		{`float("1.5 // x")`, `ValueError: could not parse "1.5 // x" as float`},
+		{`float(null)`, "TypeError: argument to `float` not supported, got NULL"},
+		{`[bool(0), bool(""), bool(null), bool(false), bool([])]`, "[true, true, false, false, true]"},
+		{`[is_null(null), is_null(0), is_null({}.missing)]`, "[true, false, true]"},
+		{`[is_fn(fn() {}), is_fn(len), is_fn((0..1).map), is_fn("len")]`, "[true, true, true, false]"},
+		{`[is_iterable([]), is_iterable("ab"), is_iterable(0..1), is_iterable(1)]`, "[true, true, true, false]"},
+		{`type()`, "ArgumentError: wrong number of arguments. got=0, want=1"},
+		{`len`, "<builtin len>"},
+		{`(0..3).map`, "<builtin map>"},
+		{`channel().send`, "<builtin send>"},
+	}
+	for _, tt := range tests {
+		if got := testEval(tt.input).Inspect(); got != tt.expected {
+			t.Errorf("%s: expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
 //==============================================
 //=============Helper functions=================
 //==============================================
@@ -393,6 +1323,9 @@ func testEval(input string) object.Object {
 	p := parser.New(l)
 	program := p.ParseProgram()
 	env := object.NewEnvironment()
+	if errs := Resolve(program, env); len(errs) != 0 {
+		return errs[0]
+	}
 
 	return Eval(program, env)
 }
@@ -0,0 +1,276 @@
+package evaluator
+
+import (
+	"errors"
+	"io/fs"
+	"monkey/object"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// FS is the file system behind the file builtins: an fs.FS that can also
+// be written to. Names are those of fs.FS, slash-separated and relative to
+// the root of the file system.
+type FS interface {
+	fs.FS
+	WriteFile(name string, data []byte) error
+	AppendFile(name string, data []byte) error
+	Remove(name string) error
+}
+
+// FilePolicy decides what scripts may do with files.
+//
+// Scripts name files by slash-separated paths. A leading slash is ignored,
+// so "/data/in.json" and "data/in.json" are the same file, and a path may
+// not climb above the root of FS with "..". Only files in or under one of
+// Roots, which are paths in FS like "data" or "." for all of it, can be
+// used. A ReadOnly policy refuses write_file, append_file and remove.
+type FilePolicy struct {
+	FS       FS
+	Roots    []string
+	ReadOnly bool
+}
+
+// Files is the policy of the file builtins. Scripts have no access to files
+// while it is nil, as it is by default.
+var Files *FilePolicy
+
+// DirFS returns the file system of the files under dir. Unlike os.DirFS, it
+// refuses to follow symbolic links that lead out of dir.
+func DirFS(dir string) FS {
+	return dirFS{FS: os.DirFS(dir), dir: dir}
+}
+
+// errEscape is the error of a name whose symbolic links lead out of a DirFS.
+var errEscape = errors.New("symbolic link leads out of the directory")
+
+type dirFS struct {
+	fs.FS
+	dir string
+}
+
+func (d dirFS) Open(name string) (fs.File, error) {
+	if _, err := d.path("open", name); err != nil {
+		return nil, err
+	}
+	return d.FS.Open(name)
+}
+
+// path returns the host path of name, checking that it stays in d.dir once
+// symbolic links are followed. A file that does not exist yet is checked by
+// the directories it would be created in.
+func (d dirFS) path(op, name string) (string, error) {
+	if !fs.ValidPath(name) {
+		return "", &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	file := filepath.Join(d.dir, filepath.FromSlash(name))
+
+	root, err := filepath.EvalSymlinks(d.dir)
+	if err != nil {
+		return "", &fs.PathError{Op: op, Path: name, Err: err}
+	}
+	resolved, err := evalExisting(file)
+	if err != nil {
+		return "", &fs.PathError{Op: op, Path: name, Err: err}
+	}
+	if resolved != root && !strings.HasPrefix(resolved, root+string(filepath.Separator)) {
+		return "", &fs.PathError{Op: op, Path: name, Err: errEscape}
+	}
+	return file, nil
+}
+
+// evalExisting follows the symbolic links of the part of file that exists.
+// A link that leads nowhere is refused, since writing through it could
+// create a file anywhere.
+func evalExisting(file string) (string, error) {
+	resolved, err := filepath.EvalSymlinks(file)
+	if err == nil {
+		return resolved, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return "", err
+	}
+	if _, err := os.Lstat(file); err == nil {
+		return "", errEscape
+	}
+	parent := filepath.Dir(file)
+	if parent == file {
+		return "", err
+	}
+	resolved, err = evalExisting(parent)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(resolved, filepath.Base(file)), nil
+}
+
+func (d dirFS) WriteFile(name string, data []byte) error {
+	file, err := d.path("write", name)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, data, 0o666)
+}
+
+func (d dirFS) AppendFile(name string, data []byte) error {
+	file, err := d.path("append", name)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(file, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o666)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func (d dirFS) Remove(name string) error {
+	file, err := d.path("remove", name)
+	if err != nil {
+		return err
+	}
+	return os.Remove(file)
+}
+
+// filePath checks that the policy lets the builtin fn use the file a script
+// named by arg, and returns its name in the file system.
+func filePath(fn string, arg object.Object, write bool) (string, *object.Error) {
+	str, ok := arg.(*object.String)
+	if !ok {
+		return "", newKindError(object.TYPE_ERROR, "argument to `%s` must be STRING, got %s", fn, arg.Type())
+	}
+	if Files == nil || Files.FS == nil {
+		return "", newKindError(object.PERMISSION_ERROR, "`%s`: file access is disabled", fn)
+	}
+	if write && Files.ReadOnly {
+		return "", newKindError(object.PERMISSION_ERROR, "`%s`: file access is read-only", fn)
+	}
+
+	name := path.Clean(strings.TrimLeft(str.Value, "/"))
+	if !fs.ValidPath(name) {
+		return "", newKindError(object.PERMISSION_ERROR, "`%s`: %s is outside the allowed directories", fn, str.Value)
+	}
+	for _, root := range Files.Roots {
+		root = path.Clean(strings.TrimLeft(root, "/"))
+		if root == "." || name == root || strings.HasPrefix(name, root+"/") {
+			return name, nil
+		}
+	}
+	return "", newKindError(object.PERMISSION_ERROR, "`%s`: %s is outside the allowed directories", fn, str.Value)
+}
+
+// fileError reports the failure of the builtin fn on the file name. It
+// leaves out the path the file system gave, which may be a host path the
+// script should not see.
+func fileError(fn, name string, err error) *object.Error {
+	if errors.Is(err, errEscape) {
+		return newKindError(object.PERMISSION_ERROR, "`%s`: %s is outside the allowed directories", fn, name)
+	}
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		err = pathErr.Err
+	}
+	return newKindError(object.IO_ERROR, "`%s`: %s: %s", fn, name, err)
+}
+
+func readFileFn(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newKindError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=1", len(args))
+	}
+	name, err := filePath("read_file", args[0], false)
+	if err != nil {
+		return err
+	}
+	data, readErr := fs.ReadFile(Files.FS, name)
+	if readErr != nil {
+		return fileError("read_file", name, readErr)
+	}
+	return &object.String{Value: string(data)}
+}
+
+// writeFileFn replaces the contents of a file, creating it if need be.
+func writeFileFn(args ...object.Object) object.Object {
+	return writeFile("write_file", FS.WriteFile, args)
+}
+
+// appendFileFn adds to the end of a file, creating it if need be.
+func appendFileFn(args ...object.Object) object.Object {
+	return writeFile("append_file", FS.AppendFile, args)
+}
+
+func writeFile(fn string, write func(FS, string, []byte) error, args []object.Object) object.Object {
+	if len(args) != 2 {
+		return newKindError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=2", len(args))
+	}
+	name, err := filePath(fn, args[0], true)
+	if err != nil {
+		return err
+	}
+	content, ok := args[1].(*object.String)
+	if !ok {
+		return newKindError(object.TYPE_ERROR, "second argument to `%s` must be STRING, got=%s", fn, args[1].Type())
+	}
+	if writeErr := write(Files.FS, name, []byte(content.Value)); writeErr != nil {
+		return fileError(fn, name, writeErr)
+	}
+	return NULL
+}
+
+// listDirFn returns the names of the entries of a directory, sorted.
+func listDirFn(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newKindError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=1", len(args))
+	}
+	name, err := filePath("list_dir", args[0], false)
+	if err != nil {
+		return err
+	}
+	entries, readErr := fs.ReadDir(Files.FS, name)
+	if readErr != nil {
+		return fileError("list_dir", name, readErr)
+	}
+	names := make([]object.Object, len(entries))
+	for i, entry := range entries {
+		names[i] = &object.String{Value: entry.Name()}
+	}
+	return &object.Array{Elements: names}
+}
+
+func existsFn(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newKindError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=1", len(args))
+	}
+	name, err := filePath("exists", args[0], false)
+	if err != nil {
+		return err
+	}
+	_, statErr := fs.Stat(Files.FS, name)
+	if errors.Is(statErr, fs.ErrNotExist) {
+		return FALSE
+	}
+	if statErr != nil {
+		return fileError("exists", name, statErr)
+	}
+	return TRUE
+}
+
+// removeFn deletes a file or an empty directory.
+func removeFn(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newKindError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=1", len(args))
+	}
+	name, err := filePath("remove", args[0], true)
+	if err != nil {
+		return err
+	}
+	if removeErr := Files.FS.Remove(name); removeErr != nil {
+		return fileError("remove", name, removeErr)
+	}
+	return NULL
+}
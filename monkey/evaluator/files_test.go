@@ -0,0 +1,211 @@
+package evaluator
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+)
+
+// memFS is an FS kept in memory. Directories exist as long as there are
+// files in them.
+type memFS struct {
+	mu    sync.Mutex
+	files fstest.MapFS
+}
+
+func newMemFS(files map[string]string) *memFS {
+	m := &memFS{files: fstest.MapFS{}}
+	for name, data := range files {
+		m.files[name] = &fstest.MapFile{Data: []byte(data)}
+	}
+	return m
+}
+
+func (m *memFS) Open(name string) (fs.File, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.files.Open(name)
+}
+
+func (m *memFS) WriteFile(name string, data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.files[name] = &fstest.MapFile{Data: append([]byte(nil), data...)}
+	return nil
+}
+
+func (m *memFS) AppendFile(name string, data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var old []byte
+	if file, ok := m.files[name]; ok {
+		old = file.Data
+	}
+	m.files[name] = &fstest.MapFile{Data: append(append([]byte(nil), old...), data...)}
+	return nil
+}
+
+func (m *memFS) Remove(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.files[name]; ok {
+		delete(m.files, name)
+		return nil
+	}
+	for file := range m.files {
+		if strings.HasPrefix(file, name+"/") {
+			return &fs.PathError{Op: "remove", Path: name, Err: errors.New("directory not empty")}
+		}
+	}
+	return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrNotExist}
+}
+
+func TestFileBuiltins(t *testing.T) {
+	defer func() { Files = nil }()
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`read_file("data/in.txt")`, "hello"},
+		{`read_file("/data/in.txt")`, "hello"},
+		{`read_file("data/../data/in.txt")`, "hello"},
+		{`write_file("data/out.txt", "a"); append_file("data/out.txt", "b"); read_file("data/out.txt")`, "ab"},
+		{`append_file("data/new.txt", "x"); read_file("data/new.txt")`, "x"},
+		{`list_dir("data")`, "[in.txt, new.txt, out.txt, sub]"},
+		{`[exists("data/in.txt"), exists("data/sub"), exists("data/missing")]`, "[true, true, false]"},
+		{`remove("data/new.txt"); exists("data/new.txt")`, "false"},
+		{`json_parse(read_file("data/sub/config.json")).debug`, "true"},
+		{`read_file("data/missing")`, "IOError: `read_file`: data/missing: file does not exist"},
+		{`remove("data/sub")`, "IOError: `remove`: data/sub: directory not empty"},
+		{`remove("data/in.txt", "x")`, "ArgumentError: wrong number of arguments. got=2, want=1"},
+		{`list_dir("data/none")`, "IOError: `list_dir`: data/none: file does not exist"},
+		{`read_file("secret.txt")`, "PermissionError: `read_file`: secret.txt is outside the allowed directories"},
+		{`read_file("database/x")`, "PermissionError: `read_file`: database/x is outside the allowed directories"},
+		{`read_file("data/../secret.txt")`, "PermissionError: `read_file`: data/../secret.txt is outside the allowed directories"},
+		{`read_file("../etc/passwd")`, "PermissionError: `read_file`: ../etc/passwd is outside the allowed directories"},
+		{`exists("secret.txt")`, "PermissionError: `exists`: secret.txt is outside the allowed directories"},
+		{`read_file(1)`, "TypeError: argument to `read_file` must be STRING, got INTEGER"},
+		{`write_file("data/x", 1)`, "TypeError: second argument to `write_file` must be STRING, got=INTEGER"},
+		{`write_file("data/x")`, "ArgumentError: wrong number of arguments. got=1, want=2"},
+	}
+
+	fsys := newMemFS(map[string]string{
+		"data/in.txt":          "hello",
+		"data/sub/config.json": `{"debug": true}`,
+		"secret.txt":           "s3cret",
+	})
+	Files = &FilePolicy{FS: fsys, Roots: []string{"data"}}
+	for _, tt := range tests {
+		if got := testEval(tt.input).Inspect(); got != tt.expected {
+			t.Errorf("%s: expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestFilePolicy(t *testing.T) {
+	defer func() { Files = nil }()
+
+	tests := []struct {
+		policy   *FilePolicy
+		input    string
+		expected string
+	}{
+		{nil, `read_file("a.txt")`, "PermissionError: `read_file`: file access is disabled"},
+		{&FilePolicy{}, `exists("a.txt")`, "PermissionError: `exists`: file access is disabled"},
+		{&FilePolicy{FS: newMemFS(nil)}, `exists("a.txt")`, "PermissionError: `exists`: a.txt is outside the allowed directories"},
+		{&FilePolicy{FS: newMemFS(map[string]string{"a.txt": "a"}), Roots: []string{"."}}, `[read_file("a.txt"), list_dir("/")]`, "[a, [a.txt]]"},
+		{&FilePolicy{FS: newMemFS(map[string]string{"a.txt": "a"}), Roots: []string{"/"}, ReadOnly: true}, `read_file("a.txt")`, "a"},
+		{&FilePolicy{FS: newMemFS(nil), Roots: []string{"."}, ReadOnly: true}, `write_file("a.txt", "a")`, "PermissionError: `write_file`: file access is read-only"},
+		{&FilePolicy{FS: newMemFS(nil), Roots: []string{"."}, ReadOnly: true}, `append_file("a.txt", "a")`, "PermissionError: `append_file`: file access is read-only"},
+		{&FilePolicy{FS: newMemFS(nil), Roots: []string{"."}, ReadOnly: true}, `remove("a.txt")`, "PermissionError: `remove`: file access is read-only"},
+		{&FilePolicy{FS: newMemFS(nil), Roots: []string{"in", "out"}}, `write_file("out/r.txt", "r"); read_file("out/r.txt")`, "r"},
+	}
+	for _, tt := range tests {
+		Files = tt.policy
+		if got := testEval(tt.input).Inspect(); got != tt.expected {
+			t.Errorf("%s: expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestDirFS(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0o777); err != nil {
+		t.Fatal(err)
+	}
+	fsys := DirFS(dir)
+
+	if err := fsys.WriteFile("sub/a.txt", []byte("a")); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := fsys.AppendFile("sub/a.txt", []byte("b")); err != nil {
+		t.Fatalf("AppendFile: %v", err)
+	}
+	data, err := fs.ReadFile(fsys, "sub/a.txt")
+	if err != nil || string(data) != "ab" {
+		t.Errorf("wrong contents. expected=%q, got=%q (%v)", "ab", data, err)
+	}
+	if err := fsys.Remove("sub/a.txt"); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "sub", "a.txt")); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("file still exists after Remove: %v", err)
+	}
+	if err := fsys.WriteFile("../escape.txt", nil); !errors.Is(err, fs.ErrInvalid) {
+		t.Errorf("writing outside the directory: expected ErrInvalid, got %v", err)
+	}
+}
+
+func TestDirFSSymlinks(t *testing.T) {
+	outside := t.TempDir()
+	if err := os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("s3cret"), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "in.txt"), []byte("in"), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	links := map[string]string{
+		"etc":      outside,
+		"secret":   filepath.Join(outside, "secret.txt"),
+		"dangling": filepath.Join(outside, "new.txt"),
+		"alias":    filepath.Join(dir, "in.txt"),
+	}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(dir, name)); err != nil {
+			t.Skipf("cannot make symbolic links: %v", err)
+		}
+	}
+	defer func() { Files = nil }()
+	Files = &FilePolicy{FS: DirFS(dir), Roots: []string{"."}}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`read_file("alias")`, "in"},
+		{`read_file("etc/secret.txt")`, "PermissionError: `read_file`: etc/secret.txt is outside the allowed directories"},
+		{`read_file("secret")`, "PermissionError: `read_file`: secret is outside the allowed directories"},
+		{`list_dir("etc")`, "PermissionError: `list_dir`: etc is outside the allowed directories"},
+		{`exists("etc/secret.txt")`, "PermissionError: `exists`: etc/secret.txt is outside the allowed directories"},
+		{`write_file("etc/new.txt", "x")`, "PermissionError: `write_file`: etc/new.txt is outside the allowed directories"},
+		{`append_file("dangling", "x")`, "PermissionError: `append_file`: dangling is outside the allowed directories"},
+		{`remove("etc/secret.txt")`, "PermissionError: `remove`: etc/secret.txt is outside the allowed directories"},
+	}
+	for _, tt := range tests {
+		if got := testEval(tt.input).Inspect(); got != tt.expected {
+			t.Errorf("%s: expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+
+	entries, err := os.ReadDir(outside)
+	if err != nil || len(entries) != 1 {
+		t.Errorf("files outside the directory changed: %v (%v)", entries, err)
+	}
+}
@@ -0,0 +1,119 @@
+package evaluator
+
+import (
+	"monkey/ast"
+	"monkey/object"
+	"runtime"
+	"sync"
+)
+
+// generator is what calling a generator function returns: an iterator that
+// runs the function body, stopping at each yield until the next element is
+// asked for.
+//
+// The body runs on a goroutine of its own, which takes turns with whoever
+// calls Next, so only one of them runs at a time. A generator dropped before
+// it finished stops its goroutine once it is garbage collected; the
+// goroutine therefore only refers to the generatorState, never to the
+// generator itself.
+type generator struct {
+	state *generatorState
+}
+
+type generatorState struct {
+	mu      sync.Mutex
+	fn      *object.Function
+	env     *object.Environment
+	resume  chan struct{}
+	values  chan object.Object
+	started bool
+	running bool // the body is running, so may not be resumed
+	done    bool
+}
+
+func newGenerator(fn *object.Function, env *object.Environment) *generator {
+	g := &generator{state: &generatorState{
+		fn:     fn,
+		env:    env,
+		resume: make(chan struct{}),
+		values: make(chan object.Object),
+	}}
+	runtime.SetFinalizer(g, func(g *generator) { g.state.stop() })
+	return g
+}
+
+func (g *generator) Type() object.ObjectType     { return object.GENERATOR_OBJ }
+func (g *generator) Inspect() string             { return "<generator>" }
+func (g *generator) Iter() object.Iterator       { return g }
+func (g *generator) Next() (object.Object, bool) { return g.state.next() }
+
+// next runs the body up to its next yield and returns the value yielded. A
+// body that returns, or runs off its end, ends the generator; what it
+// returns is discarded. An error ends it too, and is its last element.
+func (s *generatorState) next() (object.Object, bool) {
+	s.mu.Lock()
+	switch {
+	case s.done:
+		s.mu.Unlock()
+		return nil, false
+	case s.running:
+		s.mu.Unlock()
+		return newError("generator is already running"), true
+	}
+	s.running = true
+	s.mu.Unlock()
+
+	if s.started {
+		s.resume <- struct{}{}
+	} else {
+		s.started = true
+		go s.run()
+	}
+	value, ok := <-s.values
+
+	s.mu.Lock()
+	s.running = false
+	if !ok || isError(value) {
+		s.done = true
+	}
+	s.mu.Unlock()
+	return value, ok
+}
+
+func (s *generatorState) run() {
+	defer close(s.values)
+
+	s.env.SetYield(func(value object.Object) {
+		s.values <- value
+		if _, ok := <-s.resume; !ok {
+			runtime.Goexit()
+		}
+	})
+	if result := unwrapReturnValue(Eval(s.fn.Body, s.env)); isError(result) {
+		s.values <- result
+	}
+}
+
+// stop ends the goroutine of a generator that is suspended at a yield.
+func (s *generatorState) stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.started && !s.done {
+		s.done = true
+		close(s.resume)
+	}
+}
+
+func evalYieldStatement(ys *ast.YieldStatement, env *object.Environment) object.Object {
+	val := Eval(ys.Value, env)
+	if isError(val) {
+		return val
+	}
+	yield, ok := env.Yield()
+	if !ok {
+		return newError("yield outside of a generator")
+	}
+	yield(val)
+	return nil
+}
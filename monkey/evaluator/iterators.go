@@ -0,0 +1,339 @@
+package evaluator
+
+import (
+	"monkey/ast"
+	"monkey/object"
+)
+
+// iterate returns an iterator over obj, or a TypeError if obj is not
+// iterable.
+func iterate(obj object.Object) (object.Iterator, *object.Error) {
+	iterable, ok := obj.(object.Iterable)
+	if !ok {
+		return nil, newKindError(object.TYPE_ERROR, "%s is not iterable", obj.Type())
+	}
+	return iterable.Iter(), nil
+}
+
+// drain runs it to the end and returns its elements, or the first error it
+// produced.
+func drain(it object.Iterator) ([]object.Object, *object.Error) {
+	elements := []object.Object{}
+	for {
+		element, ok := it.Next()
+		if !ok {
+			return elements, nil
+		}
+		if err, ok := element.(*object.Error); ok {
+			return nil, err
+		}
+		elements = append(elements, element)
+	}
+}
+
+// elementsOf returns a fresh slice of the elements of an iterable passed to
+// the builtin fn.
+func elementsOf(fn string, obj object.Object) ([]object.Object, *object.Error) {
+	iterable, ok := obj.(object.Iterable)
+	if !ok {
+		return nil, newKindError(object.TYPE_ERROR, "argument to `%s` not supported, got %s", fn, obj.Type())
+	}
+	return drain(iterable.Iter())
+}
+
+func spreadElements(spread *ast.SpreadExpression, env *object.Environment) ([]object.Object, object.Object) {
+	value := Eval(spread.Value, env)
+	if isError(value) {
+		return nil, value
+	}
+	it, err := iterate(value)
+	if err != nil {
+		return nil, withPos(err, spread)
+	}
+	elements, err := drain(it)
+	if err != nil {
+		return nil, err
+	}
+	return elements, nil
+}
+
+// iteratorMethod returns the method name of an iterable, such as an array,
+// string, range or iterator, bound to it. Any other iterable starts a new
+// iterator each time one of its methods is called, so only iterators have
+// next.
+//
+// All but the last few methods are lazy: they return an iterator that does
+// its work as it is run, so `(1..1000000).map(f).filter(g).take(3)` calls f
+// only until three results passed g.
+func iteratorMethod(iterable object.Iterable, name string) object.Object {
+	var arity int
+	var method func(it object.Iterator, args []object.Object) object.Object
+	switch name {
+	case "map":
+		arity, method = 1, iterMap
+	case "filter":
+		arity, method = 1, iterFilter
+	case "take":
+		arity, method = 1, iterTake
+	case "skip":
+		arity, method = 1, iterSkip
+	case "take_while":
+		arity, method = 1, iterTakeWhile
+	case "skip_while":
+		arity, method = 1, iterSkipWhile
+	case "enumerate":
+		arity, method = 0, iterEnumerate
+	case "zip":
+		arity, method = 1, iterZip
+	case "chain":
+		arity, method = 1, iterChain
+	case "collect":
+		arity, method = 0, iterCollect
+	case "count":
+		arity, method = 0, iterCount
+	case "reduce":
+		arity, method = 2, iterReduce
+	case "next":
+		if _, ok := iterable.(object.Iterator); !ok {
+			return newKindError(object.NAME_ERROR, "%s has no method %s", iterable.Type(), name)
+		}
+		arity, method = 0, iterNext
+	default:
+		return newKindError(object.NAME_ERROR, "%s has no method %s", iterable.Type(), name)
+	}
+
+	return &object.Builtin{Name: name, Fn: func(args ...object.Object) object.Object {
+		if len(args) != arity {
+			return newKindError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=%d", len(args), arity)
+		}
+		return method(iterable.Iter(), args)
+	}}
+}
+
+func iterMap(it object.Iterator, args []object.Object) object.Object {
+	fn, err := functionArgument("map", args[0])
+	if err != nil {
+		return err
+	}
+	return object.NewIterator(func() (object.Object, bool) {
+		element, ok := it.Next()
+		if !ok || isError(element) {
+			return element, ok
+		}
+		return applyFunction(fn, []object.Object{element}), true
+	})
+}
+
+func iterFilter(it object.Iterator, args []object.Object) object.Object {
+	fn, err := functionArgument("filter", args[0])
+	if err != nil {
+		return err
+	}
+	return object.NewIterator(func() (object.Object, bool) {
+		for {
+			element, ok := it.Next()
+			if !ok || isError(element) {
+				return element, ok
+			}
+			keep := applyFunction(fn, []object.Object{element})
+			if isError(keep) {
+				return keep, true
+			}
+			if isTruthy(keep) {
+				return element, true
+			}
+		}
+	})
+}
+
+func iterTake(it object.Iterator, args []object.Object) object.Object {
+	n, err := countArgument("take", args[0])
+	if err != nil {
+		return err
+	}
+	return object.NewIterator(func() (object.Object, bool) {
+		if n <= 0 {
+			return nil, false
+		}
+		n--
+		return it.Next()
+	})
+}
+
+func iterSkip(it object.Iterator, args []object.Object) object.Object {
+	n, err := countArgument("skip", args[0])
+	if err != nil {
+		return err
+	}
+	return object.NewIterator(func() (object.Object, bool) {
+		for ; n > 0; n-- {
+			if element, ok := it.Next(); !ok || isError(element) {
+				n = 0
+				return element, ok
+			}
+		}
+		return it.Next()
+	})
+}
+
+func iterTakeWhile(it object.Iterator, args []object.Object) object.Object {
+	fn, err := functionArgument("take_while", args[0])
+	if err != nil {
+		return err
+	}
+	return object.NewIterator(func() (object.Object, bool) {
+		element, ok := it.Next()
+		if !ok || isError(element) {
+			return element, ok
+		}
+		keep := applyFunction(fn, []object.Object{element})
+		if isError(keep) {
+			return keep, true
+		}
+		return element, isTruthy(keep)
+	})
+}
+
+func iterSkipWhile(it object.Iterator, args []object.Object) object.Object {
+	fn, err := functionArgument("skip_while", args[0])
+	if err != nil {
+		return err
+	}
+	skipping := true
+	return object.NewIterator(func() (object.Object, bool) {
+		for {
+			element, ok := it.Next()
+			if !ok || isError(element) || !skipping {
+				return element, ok
+			}
+			skip := applyFunction(fn, []object.Object{element})
+			if isError(skip) {
+				return skip, true
+			}
+			if !isTruthy(skip) {
+				skipping = false
+				return element, true
+			}
+		}
+	})
+}
+
+// iterEnumerate pairs each element with its index, as a two element array
+// that a for loop can destructure: `for ([i, x] in xs.enumerate())`.
+func iterEnumerate(it object.Iterator, args []object.Object) object.Object {
+	var i int64
+	return object.NewIterator(func() (object.Object, bool) {
+		element, ok := it.Next()
+		if !ok || isError(element) {
+			return element, ok
+		}
+		i++
+		return &object.Array{Elements: []object.Object{&object.Integer{Value: i - 1}, element}}, true
+	})
+}
+
+// iterZip pairs the elements of two iterables up, stopping at the end of
+// the shorter one.
+func iterZip(it object.Iterator, args []object.Object) object.Object {
+	other, err := iterate(args[0])
+	if err != nil {
+		return err
+	}
+	return object.NewIterator(func() (object.Object, bool) {
+		a, ok := it.Next()
+		if !ok || isError(a) {
+			return a, ok
+		}
+		b, ok := other.Next()
+		if !ok || isError(b) {
+			return b, ok
+		}
+		return &object.Array{Elements: []object.Object{a, b}}, true
+	})
+}
+
+func iterChain(it object.Iterator, args []object.Object) object.Object {
+	other, err := iterate(args[0])
+	if err != nil {
+		return err
+	}
+	return object.NewIterator(func() (object.Object, bool) {
+		if element, ok := it.Next(); ok {
+			return element, true
+		}
+		return other.Next()
+	})
+}
+
+func iterCollect(it object.Iterator, args []object.Object) object.Object {
+	elements, err := drain(it)
+	if err != nil {
+		return err
+	}
+	return &object.Array{Elements: elements}
+}
+
+// iterNext returns the next element of an iterator, or null once it has
+// run out.
+func iterNext(it object.Iterator, args []object.Object) object.Object {
+	element, ok := it.Next()
+	if !ok {
+		return NULL
+	}
+	return element
+}
+
+func iterCount(it object.Iterator, args []object.Object) object.Object {
+	var n int64
+	for {
+		element, ok := it.Next()
+		if !ok {
+			return &object.Integer{Value: n}
+		}
+		if isError(element) {
+			return element
+		}
+		n++
+	}
+}
+
+func iterReduce(it object.Iterator, args []object.Object) object.Object {
+	fn, err := functionArgument("reduce", args[1])
+	if err != nil {
+		return err
+	}
+	acc := args[0]
+	for {
+		element, ok := it.Next()
+		if !ok {
+			return acc
+		}
+		if isError(element) {
+			return element
+		}
+		acc = applyFunction(fn, []object.Object{acc, element})
+		if isError(acc) {
+			return acc
+		}
+	}
+}
+
+func functionArgument(method string, arg object.Object) (object.Object, *object.Error) {
+	switch arg.(type) {
+	case *object.Function, *object.Builtin:
+		return arg, nil
+	default:
+		return nil, newKindError(object.TYPE_ERROR, "argument to `%s` must be FUNCTION, got %s", method, arg.Type())
+	}
+}
+
+func countArgument(method string, arg object.Object) (int64, *object.Error) {
+	n, ok := arg.(*object.Integer)
+	if !ok {
+		return 0, newKindError(object.TYPE_ERROR, "argument to `%s` must be INTEGER, got %s", method, arg.Type())
+	}
+	if n.Value < 0 {
+		return 0, newKindError(object.ARGUMENT_ERROR, "argument to `%s` must not be negative, got %d", method, n.Value)
+	}
+	return n.Value, nil
+}
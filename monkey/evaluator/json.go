@@ -0,0 +1,212 @@
+package evaluator
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"monkey/object"
+	"strconv"
+	"strings"
+)
+
+// jsonParseFn turns JSON text into objects: objects become hashes, keeping
+// the order of their keys, and arrays become arrays. Numbers written with a
+// fraction or exponent become floats and all others integers, unless they
+// are too big for one.
+func jsonParseFn(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newKindError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=1", len(args))
+	}
+	text, ok := args[0].(*object.String)
+	if !ok {
+		return newKindError(object.TYPE_ERROR, "argument to `json_parse` must be STRING, got %s", args[0].Type())
+	}
+
+	// Unmarshal checks the whole text, with better messages than the
+	// decoder gives for the same mistakes.
+	var raw json.RawMessage
+	if err := json.Unmarshal([]byte(text.Value), &raw); err != nil {
+		return newKindError(object.VALUE_ERROR, "invalid JSON: %s", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	value, err := decodeJSON(dec)
+	if err != nil {
+		return newKindError(object.VALUE_ERROR, "invalid JSON: %s", err)
+	}
+	return value
+}
+
+func decodeJSON(dec *json.Decoder) (object.Object, error) {
+	token, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch token := token.(type) {
+	case json.Delim:
+		if token == '[' {
+			elements := []object.Object{}
+			for dec.More() {
+				element, err := decodeJSON(dec)
+				if err != nil {
+					return nil, err
+				}
+				elements = append(elements, element)
+			}
+			_, err := dec.Token() // ]
+			return &object.Array{Elements: elements}, err
+		}
+		hash := &object.Hash{}
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeJSON(dec)
+			if err != nil {
+				return nil, err
+			}
+			hash.Set(&object.String{Value: key.(string)}, value)
+		}
+		_, err := dec.Token() // }
+		return hash, err
+	case json.Number:
+		if !strings.ContainsAny(token.String(), ".eE") {
+			if n, err := token.Int64(); err == nil {
+				return &object.Integer{Value: n}, nil
+			}
+		}
+		f, err := token.Float64()
+		if err != nil {
+			return nil, err
+		}
+		return &object.Float{Value: f}, nil
+	case string:
+		return &object.String{Value: token}, nil
+	case bool:
+		return nativeBoolToObj(token), nil
+	default:
+		return NULL, nil
+	}
+}
+
+// jsonStringifyFn turns an object into JSON text, on one line or, given an
+// indent of a number of spaces or a string, one element per line. Tuples
+// and sets become arrays; hashes must have string keys. Floats keep a
+// fraction, so that 2.0 is written 2.0 and parses back as a float.
+func jsonStringifyFn(args ...object.Object) object.Object {
+	if len(args) != 1 && len(args) != 2 {
+		return newKindError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=1 or 2", len(args))
+	}
+
+	var out bytes.Buffer
+	if err := encodeJSON(&out, args[0], map[object.Object]bool{}); err != nil {
+		return err
+	}
+	if len(args) == 1 {
+		return &object.String{Value: out.String()}
+	}
+
+	var indent string
+	switch arg := args[1].(type) {
+	case *object.Integer:
+		n, err := countArgument("json_stringify", arg)
+		if err != nil {
+			return err
+		}
+		indent = strings.Repeat(" ", int(n))
+	case *object.String:
+		indent = arg.Value
+	default:
+		return newKindError(object.TYPE_ERROR, "indent for `json_stringify` must be INTEGER or STRING, got %s", arg.Type())
+	}
+	var indented bytes.Buffer
+	json.Indent(&indented, out.Bytes(), "", indent)
+	return &object.String{Value: indented.String()}
+}
+
+// encodeJSON writes obj to out. visiting holds the arrays and hashes being
+// written, to catch one that contains itself.
+func encodeJSON(out *bytes.Buffer, obj object.Object, visiting map[object.Object]bool) *object.Error {
+	switch obj := obj.(type) {
+	case *object.Null:
+		out.WriteString("null")
+	case *object.Boolean:
+		out.WriteString(strconv.FormatBool(obj.Value))
+	case *object.Integer:
+		out.WriteString(strconv.FormatInt(obj.Value, 10))
+	case *object.Float:
+		if math.IsNaN(obj.Value) || math.IsInf(obj.Value, 0) {
+			return newKindError(object.VALUE_ERROR, "cannot convert %s to JSON", obj.Inspect())
+		}
+		f := strconv.FormatFloat(obj.Value, 'g', -1, 64)
+		if !strings.ContainsAny(f, ".e") {
+			f += ".0"
+		}
+		out.WriteString(f)
+	case *object.String:
+		encodeJSONString(out, obj.Value)
+	case *object.Array:
+		return encodeJSONArray(out, obj, obj.Snapshot(), visiting)
+	case *object.Tuple:
+		return encodeJSONArray(out, obj, obj.Elements, visiting)
+	case *object.Set:
+		return encodeJSONArray(out, obj, obj.Elements(), visiting)
+	case *object.Hash:
+		if visiting[obj] {
+			return newKindError(object.VALUE_ERROR, "cannot convert %s that contains itself to JSON", obj.Type())
+		}
+		visiting[obj] = true
+		defer delete(visiting, obj)
+
+		out.WriteByte('{')
+		for i, pair := range obj.Pairs() {
+			key, ok := pair.Key.(*object.String)
+			if !ok {
+				return newKindError(object.TYPE_ERROR, "cannot convert hash key of type %s to JSON", pair.Key.Type())
+			}
+			if i > 0 {
+				out.WriteByte(',')
+			}
+			encodeJSONString(out, key.Value)
+			out.WriteByte(':')
+			if err := encodeJSON(out, pair.Value, visiting); err != nil {
+				return err
+			}
+		}
+		out.WriteByte('}')
+	default:
+		return newKindError(object.TYPE_ERROR, "cannot convert %s to JSON", obj.Type())
+	}
+	return nil
+}
+
+func encodeJSONArray(out *bytes.Buffer, obj object.Object, elements []object.Object, visiting map[object.Object]bool) *object.Error {
+	if visiting[obj] {
+		return newKindError(object.VALUE_ERROR, "cannot convert %s that contains itself to JSON", obj.Type())
+	}
+	visiting[obj] = true
+	defer delete(visiting, obj)
+
+	out.WriteByte('[')
+	for i, element := range elements {
+		if i > 0 {
+			out.WriteByte(',')
+		}
+		if err := encodeJSON(out, element, visiting); err != nil {
+			return err
+		}
+	}
+	out.WriteByte(']')
+	return nil
+}
+
+// encodeJSONString writes s as a JSON string. Unlike json.Marshal it leaves
+// <, > and & alone, since the text is not meant for HTML.
+func encodeJSONString(out *bytes.Buffer, s string) {
+	enc := json.NewEncoder(out)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	out.Truncate(out.Len() - 1) // Encode ends with a newline
+}
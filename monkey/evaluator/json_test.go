@@ -0,0 +1,65 @@
+package evaluator
+
+import (
+	"monkey/object"
+	"testing"
+)
+
+func TestJSONParse(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{"b": 1, "a": [true, false, null], "c": {"d": "e"}}`, "{b: 1, a: [true, false, null], c: {d: e}}"},
+		{`[1, 1.0, 1.5, -2, 1e3, 2E-1, 12345678901234567890]`, "[1, 1.0, 1.5, -2, 1000.0, 0.2, 1.2345678901234567e+19]"},
+		{` "text" `, "text"},
+		{`{"a": 1, "a": 2}`, "{a: 2}"},
+		{`[]`, "[]"},
+		{`{}`, "{}"},
+		{`null`, "null"},
+		{`{"a": 1`, "ValueError: invalid JSON: unexpected end of JSON input"},
+		{`[1, 2,]`, "ValueError: invalid JSON: invalid character ']' looking for beginning of value"},
+		{`{"a": 1} x`, "ValueError: invalid JSON: invalid character 'x' after top-level value"},
+		{`[1] [2]`, "ValueError: invalid JSON: invalid character '[' after top-level value"},
+		{``, "ValueError: invalid JSON: unexpected end of JSON input"},
+	}
+	for _, tt := range tests {
+		if got := jsonParseFn(&object.String{Value: tt.input}).Inspect(); got != tt.expected {
+			t.Errorf("%s: expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+
+	if jsonParseFn(&object.String{Value: "null"}) != NULL || jsonParseFn(&object.String{Value: "true"}) != TRUE {
+		t.Errorf("null and booleans are not parsed to the shared objects")
+	}
+}
+
+func TestJSONStringify(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`json_stringify({"b": 1, "a": [true, null, 2.5, 2.0]})`, `{"b":1,"a":[true,null,2.5,2.0]}`},
+		{`json_stringify((1, #{2}))`, `[1,[2]]`},
+		{`json_stringify("<a & b>")`, `"<a & b>"`},
+		{`json_stringify(1e21)`, `1e+21`},
+		{`json_stringify({"a": [1, {}], "b": {"c": null}}, 2)`, "{\n  \"a\": [\n    1,\n    {}\n  ],\n  \"b\": {\n    \"c\": null\n  }\n}"},
+		{`json_stringify([1], "..")`, "[\n..1\n]"},
+		{`let v = {"n": 1, "f": 1.0, "s": [1, "x"]}; json_parse(json_stringify(v)) == v`, "true"},
+		{`let v = {"n": 1, "f": 1.0}; [type(json_parse(json_stringify(v)).f), type(json_parse(json_stringify(v)).n)]`, "[FLOAT, INTEGER]"},
+		{`json_stringify(fn(x) { x })`, "TypeError: cannot convert FUNCTION to JSON"},
+		{`json_stringify([len])`, "TypeError: cannot convert BUILTIN to JSON"},
+		{`json_stringify({1: 2})`, "TypeError: cannot convert hash key of type INTEGER to JSON"},
+		{`let a = [1]; a[0] = a; json_stringify(a)`, "ValueError: cannot convert ARRAY that contains itself to JSON"},
+		{`let x = [1]; json_stringify([x, x])`, "[[1],[1]]"},
+		{`json_stringify(1, true)`, "TypeError: indent for `json_stringify` must be INTEGER or STRING, got BOOLEAN"},
+		{`json_stringify(1, -1)`, "ArgumentError: argument to `json_stringify` must not be negative, got -1"},
+		{`json_stringify()`, "ArgumentError: wrong number of arguments. got=0, want=1 or 2"},
+		{`json_parse(1)`, "TypeError: argument to `json_parse` must be STRING, got INTEGER"},
+	}
+	for _, tt := range tests {
+		if got := testEval(tt.input).Inspect(); got != tt.expected {
+			t.Errorf("%s: expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
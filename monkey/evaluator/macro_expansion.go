@@ -0,0 +1,105 @@
+package evaluator
+
+import (
+	"monkey/ast"
+	"monkey/object"
+)
+
+// DefineMacros removes the top-level `let name = macro(...) { ... }`
+// statements from program and binds each macro in env.
+func DefineMacros(program *ast.Program, env *object.Environment) {
+	statements := program.Statements[:0]
+	for _, statement := range program.Statements {
+		if !isMacroDefinition(statement) {
+			statements = append(statements, statement)
+			continue
+		}
+		let := statement.(*ast.LetStatement)
+		macro := let.Value.(*ast.MacroLiteral)
+		env.Set(let.Name.Value, &object.Macro{
+			Parameters: macro.Parameters,
+			Body:       macro.Body,
+			Env:        env,
+		})
+	}
+	program.Statements = statements
+}
+
+func isMacroDefinition(statement ast.Statement) bool {
+	let, ok := statement.(*ast.LetStatement)
+	if !ok || let.Name == nil {
+		return false
+	}
+	_, ok = let.Value.(*ast.MacroLiteral)
+	return ok
+}
+
+// ExpandMacros replaces every call to a macro defined in env with the code
+// the macro returns. Macro arguments are passed unevaluated, as quotes, and
+// the macro body must evaluate to a quote. Code produced by a macro is not
+// expanded again.
+func ExpandMacros(program ast.Node, env *object.Environment) (ast.Node, *object.Error) {
+	var err *object.Error
+
+	expanded := ast.Modify(program, func(node ast.Node) ast.Node {
+		call, ok := node.(*ast.CallExpression)
+		if !ok || err != nil {
+			return node
+		}
+		macro, ok := macroCall(call, env)
+		if !ok {
+			return node
+		}
+
+		var result ast.Node
+		result, err = expandMacro(macro, call)
+		if err != nil {
+			if err.Pos.Line == 0 {
+				err.Pos = call.Pos()
+			}
+			return node
+		}
+		return result
+	})
+
+	return expanded, err
+}
+
+func macroCall(call *ast.CallExpression, env *object.Environment) (*object.Macro, bool) {
+	ident, ok := call.Function.(*ast.Identifier)
+	if !ok {
+		return nil, false
+	}
+	obj, ok := env.Get(ident.Value)
+	if !ok {
+		return nil, false
+	}
+	macro, ok := obj.(*object.Macro)
+	return macro, ok
+}
+
+func expandMacro(macro *object.Macro, call *ast.CallExpression) (ast.Node, *object.Error) {
+	if len(call.Arguments) != len(macro.Parameters) {
+		return nil, newKindError(object.ARGUMENT_ERROR, "wrong number of arguments to macro %s. got=%d, want=%d",
+			call.Function.String(), len(call.Arguments), len(macro.Parameters))
+	}
+
+	env := object.NewEnclosedEnvironment(macro.Env)
+	for i, param := range macro.Parameters {
+		env.Set(param.Value, &object.Quote{Node: call.Arguments[i]})
+	}
+
+	evaluated := unwrapReturnValue(Eval(macro.Body, env))
+	if evaluated == nil {
+		evaluated = NULL
+	}
+	if err, ok := evaluated.(*object.Error); ok {
+		return nil, err
+	}
+	quote, ok := evaluated.(*object.Quote)
+	if !ok {
+		return nil, newKindError(object.MACRO_ERROR, "macro %s must return quoted code, got %s",
+			call.Function.String(), evaluated.Type())
+	}
+	return quote.Node, nil
+}
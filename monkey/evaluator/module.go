@@ -0,0 +1,334 @@
+package evaluator
+
+import (
+	"monkey/ast"
+	"monkey/lexer"
+	"monkey/object"
+	"monkey/parser"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// ModuleExtension is appended to import paths that do not name a file.
+const ModuleExtension = ".mk"
+
+// ModuleLoader resolves the paths given to import statements, evaluates each
+// module once and caches the result.
+//
+// A path starting with ./ or ../ is relative to the directory of the
+// importing file. Any other relative path is looked up in the importing
+// file's directory first and then in each of SearchPaths in order.
+//
+// Tasks may import at the same time. A module that is already being
+// evaluated is waited for rather than evaluated again, unless waiting would
+// close an import cycle.
+type ModuleLoader struct {
+	SearchPaths []string
+
+	// NewEnvironment creates the environment a module is evaluated in.
+	NewEnvironment func() *object.Environment
+
+	mu      sync.Mutex
+	cache   map[string]*object.Module
+	loading map[string]*loading // files being evaluated
+}
+
+// loading is a file being evaluated. done is closed once it is finished,
+// with module or err set. waits counts the imports made from the file that
+// are waiting for other files to be evaluated.
+type loading struct {
+	done   chan struct{}
+	module *object.Module
+	err    *object.Error
+	waits  map[string]int
+	script bool // the file run by RunFile, which is not a module
+}
+
+// Loader is the ModuleLoader used by import statements.
+var Loader = NewModuleLoader()
+
+func NewModuleLoader(searchPaths ...string) *ModuleLoader {
+	return &ModuleLoader{
+		SearchPaths:    searchPaths,
+		NewEnvironment: object.NewEnvironment,
+		cache:          make(map[string]*object.Module),
+		loading:        make(map[string]*loading),
+	}
+}
+
+// RunFile evaluates the script at path in env. Imports inside it are
+// resolved relative to the script's directory. A script that cannot be
+// parsed gives a SyntaxError at the first mistake found in it.
+func (ml *ModuleLoader) RunFile(path string, env *object.Environment) object.Object {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return newKindError(object.IO_ERROR, "%s", err)
+	}
+	source, err := os.ReadFile(abs)
+	if err != nil {
+		return newKindError(object.IO_ERROR, "%s", err)
+	}
+
+	p := parser.New(lexer.NewFile(abs, string(source)))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return syntaxError(p)
+	}
+	program, loadErr := expandMacros(program)
+	if loadErr != nil {
+		return loadErr
+	}
+	if errs := Resolve(program, env); len(errs) != 0 {
+		return errs[0]
+	}
+
+	ml.mu.Lock()
+	ml.loading[abs] = &loading{done: make(chan struct{}), waits: make(map[string]int), script: true}
+	ml.mu.Unlock()
+	defer ml.finish(abs, nil, nil)
+
+	return Eval(program, env)
+}
+
+// Load returns the module that path refers to, evaluating it on first use.
+// importer is the file the import statement is in, or empty if it is in
+// none.
+func (ml *ModuleLoader) Load(path, importer string) (*object.Module, *object.Error) {
+	file, err := ml.resolve(path, importer)
+	if err != nil {
+		return nil, err
+	}
+
+	ml.mu.Lock()
+	if module, ok := ml.cache[file]; ok {
+		ml.mu.Unlock()
+		return module, nil
+	}
+	if l, ok := ml.loading[file]; ok {
+		cycle := ml.waitPath(file, importer, map[string]bool{})
+		if cycle == nil && l.script {
+			cycle = []string{file, importer}
+		}
+		if cycle != nil {
+			ml.mu.Unlock()
+			return nil, newKindError(object.IMPORT_ERROR, "import cycle: %s", displayPaths(append(cycle, file)))
+		}
+		ml.wait(importer, file, 1)
+		ml.mu.Unlock()
+
+		<-l.done
+		ml.mu.Lock()
+		ml.wait(importer, file, -1)
+		ml.mu.Unlock()
+		return l.module, l.err
+	}
+	ml.loading[file] = &loading{done: make(chan struct{}), waits: make(map[string]int)}
+	ml.wait(importer, file, 1)
+	ml.mu.Unlock()
+
+	module, err := ml.evaluate(file)
+	ml.mu.Lock()
+	ml.wait(importer, file, -1)
+	ml.mu.Unlock()
+	ml.finish(file, module, err)
+	return module, err
+}
+
+// evaluate evaluates the module file and collects its exports.
+func (ml *ModuleLoader) evaluate(file string) (*object.Module, *object.Error) {
+	program, err := parseFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	env := ml.NewEnvironment()
+	if errs := Resolve(program, env); len(errs) != 0 {
+		return nil, errs[0]
+	}
+	result := Eval(program, env)
+	if err, ok := result.(*object.Error); ok {
+		return nil, err
+	}
+
+	module := &object.Module{
+		Name:    strings.TrimSuffix(filepath.Base(file), ModuleExtension),
+		Path:    file,
+		Exports: make(map[string]object.Object),
+	}
+	for _, statement := range program.Statements {
+		export, ok := statement.(*ast.ExportStatement)
+		if !ok {
+			continue
+		}
+		for _, name := range letNames(export.Statement) {
+			if val, ok := env.Get(name); ok {
+				module.Exports[name] = val
+			}
+		}
+	}
+
+	return module, nil
+}
+
+// finish records the result of evaluating file, caching it if it is a
+// module, and wakes the imports waiting for it.
+func (ml *ModuleLoader) finish(file string, module *object.Module, err *object.Error) {
+	ml.mu.Lock()
+	l := ml.loading[file]
+	delete(ml.loading, file)
+	if module != nil {
+		ml.cache[file] = module
+	}
+	ml.mu.Unlock()
+
+	l.module, l.err = module, err
+	close(l.done)
+}
+
+// wait adds n to the number of imports from importer that wait for file.
+// ml.mu must be held.
+func (ml *ModuleLoader) wait(importer, file string, n int) {
+	l, ok := ml.loading[importer]
+	if !ok {
+		return
+	}
+	l.waits[file] += n
+	if l.waits[file] == 0 {
+		delete(l.waits, file)
+	}
+}
+
+// waitPath returns the files leading from one file to another, each waiting
+// for the next to be evaluated, or nil if from does not wait for to. ml.mu
+// must be held.
+func (ml *ModuleLoader) waitPath(from, to string, seen map[string]bool) []string {
+	if from == to {
+		return []string{from}
+	}
+	l, ok := ml.loading[from]
+	if !ok || seen[from] {
+		return nil
+	}
+	seen[from] = true
+	for next := range l.waits {
+		if path := ml.waitPath(next, to, seen); path != nil {
+			return append([]string{from}, path...)
+		}
+	}
+	return nil
+}
+
+// resolve turns an import path into the absolute path of an existing file,
+// relative to the importing file or, if there is none, the working
+// directory.
+func (ml *ModuleLoader) resolve(path, importer string) (string, *object.Error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", newKindError(object.IMPORT_ERROR, "%s", err)
+	}
+	if importer != "" {
+		dir = filepath.Dir(importer)
+	}
+
+	var candidates []string
+	switch {
+	case filepath.IsAbs(path):
+		candidates = []string{path}
+	case strings.HasPrefix(path, "./") || strings.HasPrefix(path, "../"):
+		candidates = []string{filepath.Join(dir, path)}
+	default:
+		candidates = []string{filepath.Join(dir, path)}
+		for _, searchPath := range ml.SearchPaths {
+			candidates = append(candidates, filepath.Join(searchPath, path))
+		}
+	}
+
+	for _, candidate := range candidates {
+		for _, file := range []string{candidate, candidate + ModuleExtension} {
+			if info, err := os.Stat(file); err == nil && !info.IsDir() {
+				abs, err := filepath.Abs(file)
+				if err != nil {
+					return "", newKindError(object.IMPORT_ERROR, "%s", err)
+				}
+				return abs, nil
+			}
+		}
+	}
+	return "", newKindError(object.IMPORT_ERROR, "module not found: %s", path)
+}
+
+// parseFile parses the module file and expands the macros it defines.
+func parseFile(file string) (*ast.Program, *object.Error) {
+	source, err := os.ReadFile(file)
+	if err != nil {
+		return nil, newKindError(object.IMPORT_ERROR, "%s", err)
+	}
+
+	p := parser.New(lexer.NewFile(file, string(source)))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, newKindError(object.IMPORT_ERROR, "could not parse %s:\n\t%s",
+			displayPaths([]string{file}), strings.Join(p.Errors(), "\n\t"))
+	}
+	return expandMacros(program)
+}
+
+// syntaxError reports the first of the errors p found. Errors found by the
+// lexer already start with their position, which the error carries instead.
+func syntaxError(p *parser.Parser) *object.Error {
+	pos := p.ErrorPositions()[0]
+	msg := strings.TrimPrefix(p.Errors()[0], pos.String()+": ")
+	return &object.Error{Messgae: msg, Kind: object.SYNTAX_ERROR, Pos: pos}
+}
+
+// expandMacros expands the macros program defines.
+func expandMacros(program *ast.Program) (*ast.Program, *object.Error) {
+	macros := object.NewEnvironment()
+	DefineMacros(program, macros)
+	expanded, loadErr := ExpandMacros(program, macros)
+	if loadErr != nil {
+		return nil, loadErr
+	}
+	return expanded.(*ast.Program), nil
+}
+
+// displayPaths joins files with arrows, showing each relative to the working
+// directory when possible.
+func displayPaths(files []string) string {
+	wd, _ := os.Getwd()
+
+	shown := make([]string, len(files))
+	for i, file := range files {
+		shown[i] = file
+		if rel, err := filepath.Rel(wd, file); err == nil && !strings.HasPrefix(rel, "..") {
+			shown[i] = rel
+		}
+	}
+	return strings.Join(shown, " -> ")
+}
+
+func evalImportStatement(node *ast.ImportStatement, env *object.Environment) object.Object {
+	module, err := Loader.Load(node.Path.Value, node.Pos().File)
+	if err != nil {
+		return err
+	}
+
+	name := module.Name
+	if node.Alias != nil {
+		name = node.Alias.Value
+	}
+	env.Set(name, module)
+	return nil
+}
+
+// importName returns the name an import statement binds the module to,
+// without loading it.
+func importName(is *ast.ImportStatement) string {
+	if is.Alias != nil {
+		return is.Alias.Value
+	}
+	return strings.TrimSuffix(path.Base(is.Path.Value), ModuleExtension)
+}
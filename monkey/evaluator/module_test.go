@@ -0,0 +1,227 @@
+package evaluator
+
+import (
+	"monkey/object"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestImportExport(t *testing.T) {
+	dir := writeModules(t, map[string]string{
+		"main.mk": `import "lib/math.mk" as math;
+import "./lib/greet";
+math.square(math.two) + greet.size`,
+		"lib/math.mk": `export let two = 2;
+export let square = fn(x) { x * x };
+let hidden = 1;`,
+		"lib/greet.mk": `import "./math.mk" as m;
+export let [size, _] = [m.square(3), 0];`,
+	})
+
+	result := Loader.RunFile(filepath.Join(dir, "main.mk"), object.NewEnvironment())
+	testIntegerObject(t, result, 13)
+}
+
+func TestMacrosAreExpandedOnLoad(t *testing.T) {
+	dir := writeModules(t, map[string]string{
+		"main.mk": `import "./lib.mk" as lib;
+let unless = macro(cond, then, otherwise) {
+  quote(if (!(unquote(cond))) { unquote(then) } else { unquote(otherwise) })
+};
+unless(lib.big > 5, 1, 2) + lib.twice`,
+		"lib.mk": `let double = macro(x) { quote(unquote(x) * 2) };
+export let big = double(1);
+export let twice = double(big);`,
+	})
+
+	result := Loader.RunFile(filepath.Join(dir, "main.mk"), object.NewEnvironment())
+	testIntegerObject(t, result, 5)
+}
+
+func TestImportErrors(t *testing.T) {
+	tests := []struct {
+		modules       map[string]string
+		expectedError string
+	}{
+		{
+			map[string]string{"main.mk": `import "./lib.mk" as lib; lib.hidden`, "lib.mk": "let hidden = 1;"},
+			"module lib has no exported member hidden",
+		},
+		{
+			map[string]string{"main.mk": `import "./missing.mk" as lib;`},
+			"module not found: ./missing.mk",
+		},
+		{
+			map[string]string{"main.mk": `import "./lib.mk" as lib;`, "lib.mk": "let x = ;"},
+			"could not parse lib.mk:\n\tno prefix parse function for ; found",
+		},
+		{
+			map[string]string{"main.mk": `import "./lib.mk" as lib;`, "lib.mk": "1 + true"},
+			"type mismatch: INTEGER + BOOLEAN",
+		},
+		{
+			map[string]string{
+				"main.mk": `import "./a.mk" as a;`,
+				"a.mk":    `import "./b.mk" as b;`,
+				"b.mk":    `import "./a.mk" as a;`,
+			},
+			"import cycle: a.mk -> b.mk -> a.mk",
+		},
+		{
+			map[string]string{"main.mk": `let x = 1; x.y`},
+			"member access not supported: INTEGER",
+		},
+	}
+
+	for _, tt := range tests {
+		dir := writeModules(t, tt.modules)
+		chdir(t, dir)
+
+		result := Loader.RunFile(filepath.Join(dir, "main.mk"), object.NewEnvironment())
+		errObj, ok := result.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned. got=%T (%+v)", result, result)
+			continue
+		}
+		if errObj.Messgae != tt.expectedError {
+			t.Errorf("wrong error message. expected=%q, got=%q", tt.expectedError, errObj.Messgae)
+		}
+	}
+}
+
+func TestErrorPositions(t *testing.T) {
+	tests := []struct {
+		modules      map[string]string
+		expectedKind string
+		expectedFile string
+		expectedPos  string
+	}{
+		{map[string]string{"main.mk": "let x = 1;\nlet y = ;"}, "SyntaxError", "main.mk", "2:9"},
+		{map[string]string{"main.mk": "let x = 1;\nlet y = \"\x80\";"}, "SyntaxError", "main.mk", "2:10"},
+		{map[string]string{"main.mk": "1;\n2 + true"}, "TypeError", "main.mk", "2:3"},
+		{
+			map[string]string{
+				"main.mk": "import \"./lib.mk\" as lib;\nlib.f()",
+				"lib.mk":  "export let f = fn() {\n  1 + true\n};",
+			},
+			"TypeError", "lib.mk", "2:5",
+		},
+	}
+
+	for _, tt := range tests {
+		dir := writeModules(t, tt.modules)
+
+		result := Loader.RunFile(filepath.Join(dir, "main.mk"), object.NewEnvironment())
+		errObj, ok := result.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned. got=%T (%+v)", result, result)
+			continue
+		}
+		if errObj.Kind != tt.expectedKind {
+			t.Errorf("wrong error kind. expected=%q, got=%q", tt.expectedKind, errObj.Kind)
+		}
+		if file := filepath.Join(dir, tt.expectedFile); errObj.Pos.File != file {
+			t.Errorf("wrong file. expected=%q, got=%q", file, errObj.Pos.File)
+		}
+		if errObj.Pos.String() != tt.expectedPos {
+			t.Errorf("wrong position. expected=%s, got=%s", tt.expectedPos, errObj.Pos)
+		}
+	}
+}
+
+func TestImportSearchPaths(t *testing.T) {
+	libDir := writeModules(t, map[string]string{"util.mk": "export let answer = 42;"})
+	dir := writeModules(t, map[string]string{"main.mk": `import "util" as u; u.answer`})
+
+	Loader.SearchPaths = []string{libDir}
+	result := Loader.RunFile(filepath.Join(dir, "main.mk"), object.NewEnvironment())
+	testIntegerObject(t, result, 42)
+}
+
+func TestModulesAreCached(t *testing.T) {
+	dir := writeModules(t, map[string]string{
+		"main.mk":   `import "./a.mk" as a; import "./b.mk" as b; a.counter == b.counter`,
+		"a.mk":      `import "./shared.mk" as s; export let counter = s.value;`,
+		"b.mk":      `import "./shared.mk" as s; export let counter = s.value;`,
+		"shared.mk": `export let value = fn() { 1 };`,
+	})
+
+	result := Loader.RunFile(filepath.Join(dir, "main.mk"), object.NewEnvironment())
+	testBooleanObject(t, result, true)
+	if len(Loader.cache) != 3 {
+		t.Errorf("expected 3 cached modules. got=%d", len(Loader.cache))
+	}
+}
+
+// writeModules writes files into a fresh directory and gives the test its own
+// module loader.
+func writeModules(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, source := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(source), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	previous := Loader
+	Loader = NewModuleLoader()
+	t.Cleanup(func() { Loader = previous })
+
+	dir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.TrimSuffix(dir, string(filepath.Separator))
+}
+
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+func TestConcurrentImports(t *testing.T) {
+	dir := writeModules(t, map[string]string{
+		"main.mk": `import "./sync" as s;
+let slow = fn() { import "./lib/slow" as m; m.value };
+let near = fn() { import "./b" as m; m.value };
+let slows = array((0..4).map(fn(_) { spawn slow() }));
+s.started.recv();
+let nears = array((0..4).map(fn(_) { spawn near() })).map(fn(f) { await f }).collect();
+s.release.send(true);
+[slows.map(fn(f) { await f }).collect(), nears]`,
+		"sync.mk": `export let started = channel();
+export let release = channel();`,
+		"lib/slow.mk": `import "../sync" as s;
+s.started.send(true);
+s.release.recv();
+import "./b" as b;
+export let value = b.value;`,
+		"lib/b.mk": `export let value = "lib/b";`,
+		"b.mk":     `export let value = "b";`,
+	})
+
+	result := Loader.RunFile(filepath.Join(dir, "main.mk"), object.NewEnvironment())
+	expected := "[[lib/b, lib/b, lib/b, lib/b], [b, b, b, b]]"
+	if result.Inspect() != expected {
+		t.Errorf("expected=%q, got=%q", expected, result.Inspect())
+	}
+	if len(Loader.cache) != 4 {
+		t.Errorf("expected 4 cached modules. got=%d", len(Loader.cache))
+	}
+}
@@ -0,0 +1,148 @@
+package evaluator
+
+import (
+	"monkey/ast"
+	"monkey/object"
+)
+
+// bindPattern checks that value has the shape described by pattern and binds
+// the identifiers inside the pattern in env. It returns an error describing
+// the first mismatch, or nil when the value matched. Bindings made before a
+// mismatch is found are left in env.
+func bindPattern(pattern ast.Expression, value object.Object, env *object.Environment) *object.Error {
+	switch pattern := pattern.(type) {
+	case *ast.Identifier:
+		if pattern.Value != "_" {
+			bind(pattern, value, env)
+		}
+		return nil
+	case *ast.ArrayPattern:
+		return bindArrayPattern(pattern, value, env)
+	case *ast.HashPattern:
+		return bindHashPattern(pattern, value, env)
+	default:
+		expected := Eval(pattern, env)
+		if isError(expected) {
+			return expected.(*object.Error)
+		}
+		if evalInfixExpression("==", expected, value) != TRUE {
+			return newKindError(object.MATCH_ERROR, "pattern mismatch: expected %s, got %s", expected.Inspect(), value.Inspect())
+		}
+		return nil
+	}
+}
+
+func bindArrayPattern(pattern *ast.ArrayPattern, value object.Object, env *object.Environment) *object.Error {
+	array, ok := value.(*object.Array)
+	if !ok {
+		return newKindError(object.MATCH_ERROR, "pattern mismatch: expected ARRAY, got %s", value.Type())
+	}
+
+	elements := array.Snapshot()
+	want, got := len(pattern.Elements), len(elements)
+	if pattern.Rest == nil && got != want {
+		return newKindError(object.MATCH_ERROR, "pattern mismatch: expected array of length %d, got %d", want, got)
+	}
+	if got < want {
+		return newKindError(object.MATCH_ERROR, "pattern mismatch: expected array of at least length %d, got %d", want, got)
+	}
+
+	for i, element := range pattern.Elements {
+		if err := bindPattern(element, elements[i], env); err != nil {
+			return err
+		}
+	}
+
+	if pattern.Rest != nil {
+		return bindPattern(pattern.Rest, &object.Array{Elements: elements[want:]}, env)
+	}
+	return nil
+}
+
+func bindHashPattern(pattern *ast.HashPattern, value object.Object, env *object.Environment) *object.Error {
+	hash, ok := value.(*object.Hash)
+	if !ok {
+		return newKindError(object.MATCH_ERROR, "pattern mismatch: expected HASH, got %s", value.Type())
+	}
+
+	used := &object.Hash{}
+	for _, pair := range pattern.Pairs {
+		var key object.Object
+		if ident, ok := pair.Key.(*ast.Identifier); ok {
+			key = &object.String{Value: ident.Value}
+		} else {
+			key = Eval(pair.Key, env)
+		}
+
+		if isError(key) {
+			return key.(*object.Error)
+		}
+		hashable, err := hashKey(key)
+		if err != nil {
+			return err
+		}
+		found, ok := hash.Get(hashable)
+		if !ok {
+			return newKindError(object.MATCH_ERROR, "pattern mismatch: missing key %s", key.Inspect())
+		}
+		used.Set(hashable, TRUE)
+
+		if err := bindPattern(pair.Value, found, env); err != nil {
+			return err
+		}
+	}
+
+	if pattern.Rest != nil {
+		rest := &object.Hash{}
+		for _, pair := range hash.Pairs() {
+			if _, ok := used.Get(pair.Key.(object.Hashable)); !ok {
+				rest.Set(pair.Key.(object.Hashable), pair.Value)
+			}
+		}
+		return bindPattern(pattern.Rest, rest, env)
+	}
+	return nil
+}
+
+// letNames returns the names bound by a let statement in source order.
+func letNames(ls *ast.LetStatement) []string {
+	names := []string{}
+	for _, ident := range letIdentifiers(ls, nil) {
+		names = append(names, ident.Value)
+	}
+	return names
+}
+
+// letIdentifiers appends the identifiers a let statement binds to idents.
+func letIdentifiers(ls *ast.LetStatement, idents []*ast.Identifier) []*ast.Identifier {
+	if ls.Pattern == nil {
+		return append(idents, ls.Name)
+	}
+	return patternIdentifiers(ls.Pattern, idents)
+}
+
+// patternIdentifiers appends the identifiers pattern binds to idents, in
+// source order.
+func patternIdentifiers(pattern ast.Expression, idents []*ast.Identifier) []*ast.Identifier {
+	switch pattern := pattern.(type) {
+	case *ast.Identifier:
+		if pattern.Value != "_" {
+			idents = append(idents, pattern)
+		}
+	case *ast.ArrayPattern:
+		for _, element := range pattern.Elements {
+			idents = patternIdentifiers(element, idents)
+		}
+		if pattern.Rest != nil {
+			idents = patternIdentifiers(pattern.Rest, idents)
+		}
+	case *ast.HashPattern:
+		for _, pair := range pattern.Pairs {
+			idents = patternIdentifiers(pair.Value, idents)
+		}
+		if pattern.Rest != nil {
+			idents = patternIdentifiers(pattern.Rest, idents)
+		}
+	}
+	return idents
+}
@@ -0,0 +1,134 @@
+package evaluator
+
+import (
+	"monkey/ast"
+	"monkey/object"
+	"monkey/token"
+	"strconv"
+)
+
+// quote is the special form behind quote(expr): it returns expr unevaluated,
+// except for unquote(...) calls inside it, which are evaluated in env and
+// replaced by the resulting value.
+func quote(call *ast.CallExpression, env *object.Environment) object.Object {
+	if len(call.Arguments) != 1 {
+		return newKindError(object.ARGUMENT_ERROR, "wrong number of arguments to `quote`. got=%d, want=1", len(call.Arguments))
+	}
+
+	node, err := evalUnquoteCalls(ast.Copy(call.Arguments[0]), env)
+	if err != nil {
+		return err
+	}
+	return &object.Quote{Node: node}
+}
+
+func evalUnquoteCalls(quoted ast.Node, env *object.Environment) (ast.Node, *object.Error) {
+	var err *object.Error
+
+	node := ast.Modify(quoted, func(node ast.Node) ast.Node {
+		call, ok := node.(*ast.CallExpression)
+		if !ok || err != nil || !isUnquoteCall(call) {
+			return node
+		}
+
+		if len(call.Arguments) != 1 {
+			err = newKindError(object.ARGUMENT_ERROR, "wrong number of arguments to `unquote`. got=%d, want=1", len(call.Arguments))
+			err.Pos = call.Pos()
+			return node
+		}
+
+		unquoted := Eval(call.Arguments[0], env)
+		if errObj, ok := unquoted.(*object.Error); ok {
+			err = errObj
+			return node
+		}
+
+		converted, ok := convertObjectToASTNode(unquoted, call.Token.Pos)
+		if !ok {
+			err = newKindError(object.TYPE_ERROR, "cannot unquote %s", unquoted.Type())
+			err.Pos = call.Pos()
+			return node
+		}
+		return converted
+	})
+
+	return node, err
+}
+
+func isUnquoteCall(call *ast.CallExpression) bool {
+	ident, ok := call.Function.(*ast.Identifier)
+	return ok && ident.Value == "unquote"
+}
+
+// convertObjectToASTNode turns a value back into the literal that evaluates
+// to it. Quoted code is spliced in as is.
+func convertObjectToASTNode(obj object.Object, pos token.Position) (ast.Expression, bool) {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		t := token.Token{Type: token.INT, Literal: strconv.FormatInt(obj.Value, 10), Pos: pos}
+		return &ast.IntegerLiteral{Token: t, Value: obj.Value}, true
+	case *object.Float:
+		t := token.Token{Type: token.FLOAT, Literal: obj.Inspect(), Pos: pos}
+		return &ast.FloatLiteral{Token: t, Value: obj.Value}, true
+	case *object.String:
+		t := token.Token{Type: token.STRING, Literal: obj.Value, Pos: pos}
+		return &ast.StringLiteral{Token: t, Value: obj.Value}, true
+	case *object.Boolean:
+		t := token.Token{Type: token.FALSE, Literal: "false", Pos: pos}
+		if obj.Value {
+			t = token.Token{Type: token.TRUE, Literal: "true", Pos: pos}
+		}
+		return &ast.Boolean{Token: t, Value: obj.Value}, true
+	case *object.Null:
+		t := token.Token{Type: token.NULL, Literal: "null", Pos: pos}
+		return &ast.NullExpression{Token: t}, true
+	case *object.Array:
+		t := token.Token{Type: token.LBRACKET, Literal: "[", Pos: pos}
+		array := &ast.ArrayLiteral{Token: t, Elements: make([]ast.Expression, len(obj.Elements))}
+		for i, el := range obj.Snapshot() {
+			converted, ok := convertObjectToASTNode(el, pos)
+			if !ok {
+				return nil, false
+			}
+			array.Elements[i] = converted
+		}
+		return array, true
+	case *object.Tuple:
+		t := token.Token{Type: token.LPAREN, Literal: "(", Pos: pos}
+		tuple := &ast.TupleLiteral{Token: t, Elements: make([]ast.Expression, len(obj.Elements))}
+		for i, el := range obj.Elements {
+			converted, ok := convertObjectToASTNode(el, pos)
+			if !ok {
+				return nil, false
+			}
+			tuple.Elements[i] = converted
+		}
+		return tuple, true
+	case *object.Set:
+		t := token.Token{Type: token.SET_LBRACE, Literal: "#{", Pos: pos}
+		set := &ast.SetLiteral{Token: t, Elements: []ast.Expression{}}
+		for _, el := range obj.Elements() {
+			converted, ok := convertObjectToASTNode(el, pos)
+			if !ok {
+				return nil, false
+			}
+			set.Elements = append(set.Elements, converted)
+		}
+		return set, true
+	case *object.Range:
+		start, _ := convertObjectToASTNode(&object.Integer{Value: obj.Start}, pos)
+		end, _ := convertObjectToASTNode(&object.Integer{Value: obj.End}, pos)
+		t := token.Token{Type: token.DOTDOT, Literal: "..", Pos: pos}
+		if obj.Inclusive {
+			t = token.Token{Type: token.DOTDOT_EQ, Literal: "..=", Pos: pos}
+		}
+		return &ast.InfixExpression{Token: t, Left: start, Operator: t.Literal, Right: end}, true
+	case *object.Quote:
+		// Copied so that a quote spliced in twice does not share nodes,
+		// which the resolver annotates per occurrence.
+		expression, ok := ast.Copy(obj.Node).(ast.Expression)
+		return expression, ok
+	default:
+		return nil, false
+	}
+}
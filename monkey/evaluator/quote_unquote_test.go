@@ -0,0 +1,94 @@
+package evaluator
+
+import (
+	"monkey/object"
+	"testing"
+)
+
+func TestQuote(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`quote(5)`, `5`},
+		{`quote(5 + 8)`, `(5 + 8)`},
+		{`quote(foobar)`, `foobar`},
+		{`quote(foobar + barfoo)`, `(foobar + barfoo)`},
+		{`quote(a.b = c)`, `((a.b) = c)`},
+	}
+
+	for _, tt := range tests {
+		testQuoteObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestQuoteUnquote(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`quote(unquote(4))`, `4`},
+		{`quote(unquote(4 + 4))`, `8`},
+		{`quote(8 + unquote(4 + 4))`, `(8 + 8)`},
+		{`quote(unquote(4 + 4) + 8)`, `(8 + 8)`},
+		{`let foobar = 8; quote(foobar)`, `foobar`},
+		{`let foobar = 8; quote(unquote(foobar))`, `8`},
+		{`quote(unquote(true))`, `true`},
+		{`quote(unquote(true == false))`, `false`},
+		{`quote(unquote(quote(4 + 4)))`, `(4 + 4)`},
+		{`let quotedInfixExpression = quote(4 + 4); quote(unquote(4 + 4) + unquote(quotedInfixExpression))`, `(8 + (4 + 4))`},
+		{`quote(unquote("hi"))`, `hi`},
+		{`quote(unquote(1.5))`, `1.5`},
+		{`quote(unquote(null))`, `null`},
+		{`quote(unquote([1, 2]))`, `[1, 2]`},
+		{`quote(unquote((1, [2])))`, `(1, [2])`},
+		{`quote(unquote((1,)))`, `(1,)`},
+		{`quote(unquote(#{1, 2}))`, `#{1, 2}`},
+		{`quote(unquote(1..=3))`, `(1 ..= 3)`},
+		{`let f = fn(x) { quote(unquote(x) * 2) }; f(1); f(3)`, `(3 * 2)`},
+	}
+
+	for _, tt := range tests {
+		testQuoteObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestQuoteErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`quote(1, 2)`, "ArgumentError: wrong number of arguments to `quote`. got=2, want=1"},
+		{`quote(unquote())`, "ArgumentError: wrong number of arguments to `unquote`. got=0, want=1"},
+		{`quote(unquote(fn() {}))`, "TypeError: cannot unquote FUNCTION"},
+		{`quote(unquote(missing))`, "NameError: identifier not found: missing"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("%s: expected error. got=%T (%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if errObj.Inspect() != tt.expected {
+			t.Errorf("%s: wrong error. expected=%q, got=%q", tt.input, tt.expected, errObj.Inspect())
+		}
+	}
+}
+
+func testQuoteObject(t *testing.T, evaluated object.Object, expected string) {
+	t.Helper()
+	quote, ok := evaluated.(*object.Quote)
+	if !ok {
+		t.Errorf("expected *object.Quote. got=%T (%+v)", evaluated, evaluated)
+		return
+	}
+	if quote.Node == nil {
+		t.Errorf("quote.Node is nil")
+		return
+	}
+	if quote.Node.String() != expected {
+		t.Errorf("not equal. got=%q, want=%q", quote.Node.String(), expected)
+	}
+}
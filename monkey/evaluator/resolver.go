@@ -0,0 +1,539 @@
+package evaluator
+
+import (
+	"monkey/ast"
+	"monkey/object"
+)
+
+// Resolve works out where every variable of program lives before it runs in
+// env, and records it in the Scope and Slot of each identifier and in the
+// Frame of the program and of each function literal. It returns an error for
+// each name that is bound nowhere. Functions may also use the globals of
+// the later programs, which run in env after this one.
+//
+// Names bound by the top-level code of the program, by imports and in env
+// are globals, looked up by name. Every other variable lives in a slot of
+// the frame of the function it is bound in, or of the program for those of
+// top-level match arms, catch blocks and for loops. A variable that closures
+// capture lives in a cell instead, and each function value keeps just the
+// cells of the variables its code uses. The cells of a for loop's variables
+// are listed in its Fresh, to be replaced on every iteration.
+//
+// Resolve also marks the calls in tail position in each function, whose
+// result is the result of the function: those in the final expression of its
+// body, through if and match expressions, and those in return statements
+// outside try expressions. The evaluator runs them without growing the stack.
+//
+// Scopes follow the dynamic evaluator: function bodies, match arms, catch
+// blocks and for loops have their own, while other blocks share the scope
+// around them. A name refers to a variable of the same frame only once that
+// variable has been bound; code in a nested function may use any variable
+// around it.
+func Resolve(program *ast.Program, env *object.Environment, later ...*ast.Program) []*object.Error {
+	r := &resolver{env: env, globals: make(map[string]bool)}
+	return r.resolve(program, later)
+}
+
+// ResolveInteractive is Resolve for code entered a line at a time, as in
+// the REPL, where a function may use a global that a later line defines.
+// Names bound nowhere inside function bodies are left Dynamic, to be looked
+// up when the function runs, instead of being reported.
+func ResolveInteractive(program *ast.Program, env *object.Environment) []*object.Error {
+	r := &resolver{env: env, globals: make(map[string]bool), interactive: true}
+	return r.resolve(program, nil)
+}
+
+func (r *resolver) resolve(program *ast.Program, later []*ast.Program) []*object.Error {
+	for _, p := range append([]*ast.Program{program}, later...) {
+		ast.Inspect(p, func(node ast.Node) bool {
+			if is, ok := node.(*ast.ImportStatement); ok {
+				r.globals[importName(is)] = true
+			}
+			return true
+		})
+		declarations(p, func(ident *ast.Identifier) { r.globals[ident.Value] = true })
+	}
+
+	program.Frame = &ast.Frame{}
+	r.frame = r.openFrame(program.Frame)
+	r.statements(program.Statements)
+
+	for _, f := range r.frames {
+		f.layOut()
+	}
+	for _, l := range r.loops {
+		l.node.Fresh = nil
+		for _, v := range l.variables {
+			if v.captured {
+				l.node.Fresh = append(l.node.Fresh, v.slot)
+			}
+		}
+	}
+	return r.errors
+}
+
+type resolver struct {
+	env         *object.Environment
+	globals     map[string]bool
+	interactive bool // leave unknown names in functions Dynamic
+
+	scope  *scope // nil in top-level code
+	frame  *frame
+	frames []*frame
+	loops  []loop
+	errors []*object.Error
+}
+
+type frame struct {
+	frame     *ast.Frame
+	outer     *frame
+	variables []*variable
+	captured  map[*variable]int // index in frame.Captures
+}
+
+type scope struct {
+	outer *scope
+	frame *frame
+	names map[string]*variable
+}
+
+type variable struct {
+	frame    *frame
+	bound    bool // whether code of the same frame may refer to it yet
+	captured bool
+	slot     int // set by layOut
+
+	idents   []*ast.Identifier // the identifiers naming it in its own frame
+	captures []captureRef      // the captures that take its cell
+}
+
+// loop is a for loop and the variables of its scope.
+type loop struct {
+	node      *ast.ForExpression
+	variables []*variable
+}
+
+type captureRef struct {
+	frame *ast.Frame
+	index int
+}
+
+func (r *resolver) openFrame(f *ast.Frame) *frame {
+	fr := &frame{frame: f, outer: r.frame, captured: make(map[*variable]int)}
+	r.frames = append(r.frames, fr)
+	return fr
+}
+
+// layOut numbers the slots and cells of a frame once every use of its
+// variables is known.
+func (f *frame) layOut() {
+	for _, v := range f.variables {
+		scope, slot := ast.Local, f.frame.Slots
+		if v.captured {
+			scope, slot = ast.Cell, f.frame.Cells
+			f.frame.Cells++
+		} else {
+			f.frame.Slots++
+		}
+		v.slot = slot
+		for _, ident := range v.idents {
+			ident.Scope, ident.Slot = scope, slot
+		}
+		for _, ref := range v.captures {
+			ref.frame.Captures[ref.index].Slot = slot
+		}
+	}
+}
+
+// openScope starts a scope with the names that code in nodes binds.
+func (r *resolver) openScope(nodes ...ast.Node) {
+	s := &scope{outer: r.scope, frame: r.frame, names: make(map[string]*variable)}
+	for _, node := range nodes {
+		declarations(node, func(ident *ast.Identifier) {
+			if _, ok := s.names[ident.Value]; !ok {
+				v := &variable{frame: r.frame}
+				s.names[ident.Value] = v
+				r.frame.variables = append(r.frame.variables, v)
+			}
+		})
+	}
+	r.scope = s
+}
+
+func (r *resolver) closeScope() {
+	r.scope = r.scope.outer
+}
+
+// declarations calls f for each identifier bound by node itself, not
+// counting those bound in scopes nested in it.
+func declarations(node ast.Node, f func(*ast.Identifier)) {
+	ast.Inspect(node, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.LetStatement:
+			for _, ident := range letIdentifiers(node, nil) {
+				f(ident)
+			}
+			if node.Value != nil {
+				declarations(node.Value, f)
+			}
+			return false
+		case *ast.MatchExpression:
+			declarations(node.Subject, f)
+			return false
+		case *ast.ForExpression:
+			declarations(node.Iterable, f)
+			return false
+		case *ast.TryExpression:
+			declarations(node.Block, f)
+			if node.Finally != nil {
+				declarations(node.Finally, f)
+			}
+			return false
+		case *ast.FunctionLiteral, *ast.MacroLiteral:
+			return false
+		case *ast.CallExpression:
+			if isQuoteCall(node) {
+				unquotedArguments(node, func(arg ast.Expression) { declarations(arg, f) })
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// bind resolves an identifier that binds a variable of the current scope.
+func (r *resolver) bind(ident *ast.Identifier) {
+	if r.scope == nil {
+		ident.Scope = ast.Global
+		return
+	}
+	v := r.scope.names[ident.Value]
+	v.bound = true
+	v.idents = append(v.idents, ident)
+}
+
+func (r *resolver) use(ident *ast.Identifier) {
+	for s := r.scope; s != nil; s = s.outer {
+		v, ok := s.names[ident.Value]
+		if !ok || s.frame == r.frame && !v.bound {
+			continue
+		}
+		if v.frame == r.frame {
+			v.idents = append(v.idents, ident)
+		} else {
+			ident.Scope, ident.Slot = ast.Free, r.capture(r.frame, v)
+		}
+		return
+	}
+
+	if _, ok := r.env.Get(ident.Value); ok || r.globals[ident.Value] {
+		ident.Scope = ast.Global
+	} else if IsBuiltin(ident.Value) {
+		ident.Scope = ast.Builtin
+	} else if r.interactive && r.frame.outer != nil {
+		ident.Scope = ast.Dynamic
+	} else {
+		err := newKindError(object.NAME_ERROR, "identifier not found: %s", ident.Value)
+		err.Pos = ident.Pos()
+		r.errors = append(r.errors, err)
+	}
+}
+
+// capture makes v, a variable of a frame around f, a free variable of f
+// and returns its index.
+func (r *resolver) capture(f *frame, v *variable) int {
+	if i, ok := f.captured[v]; ok {
+		return i
+	}
+
+	c := ast.Capture{Scope: ast.Cell}
+	if f.outer == v.frame {
+		v.captured = true
+		v.captures = append(v.captures, captureRef{frame: f.frame, index: len(f.frame.Captures)})
+	} else {
+		c = ast.Capture{Scope: ast.Free, Slot: r.capture(f.outer, v)}
+	}
+
+	i := len(f.frame.Captures)
+	f.frame.Captures = append(f.frame.Captures, c)
+	f.captured[v] = i
+	return i
+}
+
+// ================================================
+// ===================STATEMENTS===================
+// ================================================
+
+func (r *resolver) statements(statements []ast.Statement) {
+	for _, statement := range statements {
+		r.statement(statement)
+	}
+}
+
+func (r *resolver) statement(statement ast.Statement) {
+	switch statement := statement.(type) {
+	case *ast.LetStatement:
+		r.let(statement)
+	case *ast.ExportStatement:
+		r.let(statement.Statement)
+	case *ast.ReturnStatement:
+		if statement.ReturnValue != nil {
+			r.expr(statement.ReturnValue)
+		}
+	case *ast.ThrowStatement:
+		r.expr(statement.Value)
+	case *ast.YieldStatement:
+		r.expr(statement.Value)
+	case *ast.ExpressionStatement:
+		r.expr(statement.Expression)
+	case *ast.BlockStatement:
+		r.block(statement)
+	}
+}
+
+func (r *resolver) let(ls *ast.LetStatement) {
+	r.expr(ls.Value)
+	if ls.Pattern != nil {
+		r.pattern(ls.Pattern)
+	} else {
+		r.bind(ls.Name)
+	}
+}
+
+func (r *resolver) block(block *ast.BlockStatement) {
+	if block != nil {
+		r.statements(block.Statements)
+	}
+}
+
+// pattern resolves a let, parameter, match or catch pattern.
+func (r *resolver) pattern(pattern ast.Expression) {
+	switch pattern := pattern.(type) {
+	case *ast.Identifier:
+		if pattern.Value != "_" {
+			r.bind(pattern)
+		}
+	case *ast.ArrayPattern:
+		for _, element := range pattern.Elements {
+			r.pattern(element)
+		}
+		if pattern.Rest != nil {
+			r.pattern(pattern.Rest)
+		}
+	case *ast.HashPattern:
+		for _, pair := range pattern.Pairs {
+			if _, ok := pair.Key.(*ast.Identifier); !ok {
+				r.expr(pair.Key)
+			}
+			r.pattern(pair.Value)
+		}
+		if pattern.Rest != nil {
+			r.pattern(pattern.Rest)
+		}
+	default:
+		r.expr(pattern)
+	}
+}
+
+// ================================================
+// ==================EXPRESSIONS===================
+// ================================================
+
+func (r *resolver) expr(expr ast.Expression) {
+	switch expr := expr.(type) {
+	case *ast.Identifier:
+		r.use(expr)
+	case *ast.PrefixExpression:
+		r.expr(expr.Right)
+	case *ast.InfixExpression:
+		r.expr(expr.Left)
+		r.expr(expr.Right)
+	case *ast.IfExpression:
+		r.expr(expr.Condition)
+		r.block(expr.Consequence)
+		r.block(expr.Alternative)
+	case *ast.TryExpression:
+		r.block(expr.Block)
+		if expr.Catch != nil {
+			r.catch(expr)
+		}
+		r.block(expr.Finally)
+	case *ast.MatchExpression:
+		r.expr(expr.Subject)
+		for _, arm := range expr.Arms {
+			r.arm(arm)
+		}
+	case *ast.ForExpression:
+		r.expr(expr.Iterable)
+		r.loop(expr)
+	case *ast.FunctionLiteral:
+		r.function(expr)
+	case *ast.CallExpression:
+		if isQuoteCall(expr) {
+			unquotedArguments(expr, r.expr)
+			return
+		}
+		r.expr(expr.Function)
+		for _, arg := range expr.Arguments {
+			r.expr(arg)
+		}
+	case *ast.ArrayLiteral:
+		for _, element := range expr.Elements {
+			r.expr(element)
+		}
+	case *ast.SetLiteral:
+		for _, element := range expr.Elements {
+			r.expr(element)
+		}
+	case *ast.SpreadExpression:
+		r.expr(expr.Value)
+	case *ast.SpawnExpression:
+		r.expr(expr.Call)
+	case *ast.AwaitExpression:
+		r.expr(expr.Value)
+	case *ast.TupleLiteral:
+		for _, element := range expr.Elements {
+			r.expr(element)
+		}
+	case *ast.HashLiteral:
+		for _, key := range expr.Keys() {
+			r.expr(key)
+			r.expr(expr.Pairs[key])
+		}
+	case *ast.IndexExpression:
+		r.expr(expr.Left)
+		r.expr(expr.Index)
+	case *ast.SliceExpression:
+		r.expr(expr.Left)
+		r.expr(expr.Start)
+		r.expr(expr.End)
+		r.expr(expr.Step)
+	case *ast.MemberExpression:
+		r.expr(expr.Object)
+	case *ast.AssignExpression:
+		r.expr(expr.Target)
+		r.expr(expr.Value)
+	}
+}
+
+func (r *resolver) function(fl *ast.FunctionLiteral) {
+	outerScope, outerFrame := r.scope, r.frame
+	fl.Frame = &ast.Frame{}
+	r.frame = r.openFrame(fl.Frame)
+
+	nodes := []ast.Node{}
+	for _, param := range fl.Parameters {
+		nodes = append(nodes, &ast.LetStatement{Pattern: param})
+	}
+	nodes = append(nodes, fl.Body)
+	r.openScope(nodes...)
+	for _, param := range fl.Parameters {
+		r.pattern(param)
+	}
+	r.block(fl.Body)
+	// A generator runs its body itself rather than in applyFunction, and
+	// discards what it returns, so its calls are never tail calls.
+	if !fl.Generator {
+		markTailCalls(fl.Body)
+	}
+
+	r.scope, r.frame = outerScope, outerFrame
+}
+
+// markTailCalls marks the calls in tail position in a function body.
+func markTailCalls(body *ast.BlockStatement) {
+	markTailBlock(body)
+	ast.Inspect(body, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.ReturnStatement:
+			markTailExpression(node.ReturnValue)
+		case *ast.FunctionLiteral, *ast.TryExpression:
+			// A return inside a try runs the finally block afterwards.
+			return false
+		}
+		return true
+	})
+}
+
+func markTailBlock(block *ast.BlockStatement) {
+	if block == nil || len(block.Statements) == 0 {
+		return
+	}
+	if es, ok := block.Statements[len(block.Statements)-1].(*ast.ExpressionStatement); ok {
+		markTailExpression(es.Expression)
+	}
+}
+
+func markTailExpression(expr ast.Expression) {
+	switch expr := expr.(type) {
+	case *ast.CallExpression:
+		expr.Tail = !isQuoteCall(expr)
+	case *ast.IfExpression:
+		markTailBlock(expr.Consequence)
+		markTailBlock(expr.Alternative)
+	case *ast.MatchExpression:
+		for _, arm := range expr.Arms {
+			markTailExpression(arm.Body)
+		}
+	}
+}
+
+func (r *resolver) arm(arm *ast.MatchArm) {
+	nodes := []ast.Node{&ast.LetStatement{Pattern: arm.Pattern}, arm.Body}
+	if arm.Guard != nil {
+		nodes = append(nodes, arm.Guard)
+	}
+	r.openScope(nodes...)
+	r.pattern(arm.Pattern)
+	if arm.Guard != nil {
+		r.expr(arm.Guard)
+	}
+	r.expr(arm.Body)
+	r.closeScope()
+}
+
+func (r *resolver) loop(fe *ast.ForExpression) {
+	first := len(r.frame.variables)
+	r.openScope(&ast.LetStatement{Pattern: fe.Pattern}, fe.Body)
+	variables := append([]*variable{}, r.frame.variables[first:]...)
+	r.loops = append(r.loops, loop{node: fe, variables: variables})
+
+	r.pattern(fe.Pattern)
+	r.block(fe.Body)
+	r.closeScope()
+}
+
+func (r *resolver) catch(te *ast.TryExpression) {
+	nodes := []ast.Node{te.Catch}
+	if te.CatchParam != nil {
+		nodes = append(nodes, &ast.LetStatement{Pattern: te.CatchParam})
+	}
+	r.openScope(nodes...)
+	if te.CatchParam != nil {
+		r.pattern(te.CatchParam)
+	}
+	r.block(te.Catch)
+	r.closeScope()
+}
+
+// unquotedArguments calls f with the arguments of the unquote calls inside
+// quote(...), the only code in it that runs.
+func unquotedArguments(call *ast.CallExpression, f func(ast.Expression)) {
+	for _, arg := range call.Arguments {
+		ast.Inspect(arg, func(node ast.Node) bool {
+			unquote, ok := node.(*ast.CallExpression)
+			if !ok || !isUnquoteCall(unquote) {
+				return true
+			}
+			for _, arg := range unquote.Arguments {
+				f(arg)
+			}
+			return false
+		})
+	}
+}
+
+func isQuoteCall(call *ast.CallExpression) bool {
+	ident, ok := call.Function.(*ast.Identifier)
+	return ok && ident.Value == "quote"
+}
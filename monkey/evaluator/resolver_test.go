@@ -0,0 +1,257 @@
+package evaluator
+
+import (
+	"fmt"
+	"monkey/ast"
+	"monkey/lexer"
+	"monkey/object"
+	"monkey/parser"
+	"strings"
+	"testing"
+)
+
+func TestResolveAnnotations(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string // each identifier in source order as name:scope[:slot]
+	}{
+		{
+			"let g = 1; let f = fn(a, b) { let c = a; fn() { b + c + g } }; len(f)",
+			[]string{"g:global", "f:global", "a:local:0", "b:cell:0", "c:cell:1", "a:local:0",
+				"b:free:0", "c:free:1", "g:global", "len:builtin", "f:global"},
+		},
+		{
+			// nested closures pass captured variables along
+			"fn(x) { fn() { fn() { x } } }",
+			[]string{"x:cell:0", "x:free:0"},
+		},
+		{
+			// a later binding in the same frame is not visible yet
+			"let x = 1; fn() { let y = x; let x = 2; x + y }",
+			[]string{"x:global", "y:local:0", "x:global", "x:local:1", "x:local:1", "y:local:0"},
+		},
+		{
+			// a nested function may use a binding made after it
+			"fn() { let f = fn() { g() }; let g = fn() { 1 }; f }",
+			[]string{"f:local:0", "g:free:0", "g:cell:0", "f:local:0"},
+		},
+		{
+			"match [1, 2] { [a, _] => a, b if b => 0 }",
+			[]string{"a:local:0", "_:dynamic", "a:local:0", "b:local:1", "b:local:1"},
+		},
+		{
+			"fn(n) { try { n } catch (e) { e } }",
+			[]string{"n:local:0", "n:local:0", "e:local:1", "e:local:1"},
+		},
+		{
+			"fn() { if (true) { let x = 1 }; x }",
+			[]string{"x:local:0", "x:local:0"},
+		},
+		{
+			"fn({name, age: years}) { [name, years] }",
+			[]string{"name:local:0", "age:dynamic", "years:local:1", "name:local:0", "years:local:1"},
+		},
+		{
+			"fn(xs) { for ([i, x] in xs) { let y = x; fn() { i + y } } }",
+			[]string{"xs:local:0", "i:cell:0", "x:local:1", "xs:local:0", "y:cell:1", "x:local:1", "i:free:0", "y:free:1"},
+		},
+		{
+			"let m = quote(x + unquote(y)); let y = 1;",
+			[]string{"m:global", "quote:dynamic", "x:dynamic", "unquote:dynamic", "y:global", "y:global"},
+		},
+	}
+
+	for _, tt := range tests {
+		program := parse(t, tt.input)
+		if errs := Resolve(program, object.NewEnvironment()); len(errs) != 0 {
+			t.Errorf("%q: unexpected errors: %v", tt.input, errs)
+			continue
+		}
+
+		got := []string{}
+		ast.Inspect(program, func(node ast.Node) bool {
+			if ident, ok := node.(*ast.Identifier); ok {
+				got = append(got, describe(ident))
+			}
+			return true
+		})
+		if strings.Join(got, " ") != strings.Join(tt.expected, " ") {
+			t.Errorf("%q: wrong annotations.\nexpected=%v\ngot=     %v", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func describe(ident *ast.Identifier) string {
+	switch ident.Scope {
+	case ast.Local, ast.Cell, ast.Free:
+		return fmt.Sprintf("%s:%s:%d", ident.Value, ident.Scope, ident.Slot)
+	default:
+		return fmt.Sprintf("%s:%s", ident.Value, ident.Scope)
+	}
+}
+
+func TestResolveFreshLoopCells(t *testing.T) {
+	program := parse(t, "fn(xs) { let total = 0; for (x in xs) { let sq = x * x; let cube = sq * x; fn() { cube + x + total } } }")
+	if errs := Resolve(program, object.NewEnvironment()); len(errs) != 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+
+	var loop *ast.ForExpression
+	ast.Inspect(program, func(node ast.Node) bool {
+		if fe, ok := node.(*ast.ForExpression); ok {
+			loop = fe
+		}
+		return true
+	})
+	// total is captured but lives outside the loop; sq is not captured.
+	if fmt.Sprint(loop.Fresh) != "[1 2]" {
+		t.Errorf("wrong fresh cells. expected=[1 2], got=%v", loop.Fresh)
+	}
+}
+
+func TestResolveFrames(t *testing.T) {
+	program := parse(t, "match 1 { x => fn(a, b) { let c = b; fn() { a } } }")
+	if errs := Resolve(program, object.NewEnvironment()); len(errs) != 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+
+	var functions []*ast.FunctionLiteral
+	ast.Inspect(program, func(node ast.Node) bool {
+		if fl, ok := node.(*ast.FunctionLiteral); ok {
+			functions = append(functions, fl)
+		}
+		return true
+	})
+
+	tests := []struct {
+		frame    *ast.Frame
+		expected string
+	}{
+		{program.Frame, "&{Slots:1 Cells:0 Captures:[]}"},
+		{functions[0].Frame, "&{Slots:2 Cells:1 Captures:[]}"},
+		{functions[1].Frame, "&{Slots:0 Cells:0 Captures:[{Scope:cell Slot:0}]}"},
+	}
+	for i, tt := range tests {
+		if got := fmt.Sprintf("%+v", tt.frame); got != tt.expected {
+			t.Errorf("frames[%d] wrong. expected=%s, got=%s", i, tt.expected, got)
+		}
+	}
+}
+
+func TestResolveErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"foo", []string{"1:1: identifier not found: foo"}},
+		{"let f = fn(a) { a + b }; c", []string{"1:21: identifier not found: b", "1:26: identifier not found: c"}},
+		{"fn() { x; let x = 1 }", []string{"1:8: identifier not found: x"}},
+		{"match 1 { x => x }; x", []string{"1:21: identifier not found: x"}},
+		{"quote(undefined + unquote(missing))", []string{"1:27: identifier not found: missing"}},
+		{"later; let later = 1;", nil},
+		{"import \"lib/strings\" as s; s", nil},
+		{"defined", nil},
+		{"fn() { fromNextFile }", nil},
+	}
+
+	for _, tt := range tests {
+		env := object.NewEnvironment()
+		env.Set("defined", NULL)
+		next := parse(t, "let fromNextFile = 1;")
+
+		got := []string{}
+		for _, err := range Resolve(parse(t, tt.input), env, next) {
+			got = append(got, err.Pos.String()+": "+err.Messgae)
+		}
+		if strings.Join(got, "\n") != strings.Join(tt.expected, "\n") {
+			t.Errorf("%q: wrong errors.\nexpected=%v\ngot=     %v", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestResolveInteractive(t *testing.T) {
+	env := object.NewEnvironment()
+	lines := []struct {
+		input    string
+		expected string
+	}{
+		{"let f = fn() { g() + 1 };", ""},
+		{"later", "NameError: identifier not found: later"},
+		{"let g = fn() { 2 };", ""},
+		{"f()", "3"},
+		{"fn() { missing }()", "NameError: identifier not found: missing"},
+	}
+
+	for _, tt := range lines {
+		program := parse(t, tt.input)
+		var got string
+		if errs := ResolveInteractive(program, env); len(errs) != 0 {
+			got = errs[0].Inspect()
+		} else if result := Eval(program, env); result != nil {
+			got = result.Inspect()
+		}
+		if got != tt.expected {
+			t.Errorf("%q: expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestClosuresCaptureOnlyWhatTheyUse(t *testing.T) {
+	evaluated := testEval("let f = fn(a, b, c) { let d = 4; fn() { b + d } }; f(1, 2, 3)")
+
+	fn, ok := evaluated.(*object.Function)
+	if !ok {
+		t.Fatalf("object is not Function. got=%T (%+v)", evaluated, evaluated)
+	}
+	if len(fn.Free) != 2 {
+		t.Fatalf("closure captured %d variables, want 2", len(fn.Free))
+	}
+	testIntegerObject(t, fn.Free[0].Get(), 2)
+	testIntegerObject(t, fn.Free[1].Get(), 4)
+	if fn.Env.IsFrame() {
+		t.Errorf("closure holds on to the frame it was created in")
+	}
+}
+
+// TestResolvedMatchesDynamic checks that resolving a program does not change
+// what it evaluates to.
+func TestResolvedMatchesDynamic(t *testing.T) {
+	inputs := []string{
+		"let counter = fn() { let c = {\"n\": 0}; fn() { c.n = c.n + 1; c.n } }; let next = counter(); next(); next()",
+		"let fib = fn(n) { if (n < 2) { n } else { fib(n - 1) + fib(n - 2) } }; fib(15)",
+		"let f = fn(x) { let even = fn(n) { if (n == 0) { true } else { odd(n - 1) } }; let odd = fn(n) { if (n == 0) { false } else { even(n - 1) } }; [even(x), odd(x)] }; f(9)",
+		"let x = 10; let f = fn() { let x = x + 1; x }; [f(), x]",
+		"let add = fn(a) { fn(b) { fn(c) { a + b + c } } }; add(1)(2)(3)",
+		"let g = match [1, [2, 3]] { [a, [b, ...rest]] if a < b => fn() { [a, b, rest] }, _ => null }; g()",
+		"let f = fn(n) { if (n > 0) { let y = n; }; y }; [f(2), try { f(0) } catch (e) { e.kind }]",
+		"let f = fn() { try { throw {\"kind\": \"K\", \"message\": \"m\"} } catch ({kind}) { fn() { kind } } }; f()()",
+		"let point = {\"x\": 1, \"move\": fn(self, dx) { self.x = self.x + dx; self }}; point.move(2).x",
+		"let {a, b: [c, d]} = {\"a\": 1, \"b\": [2, 3]}; let f = fn({a}, [b]) { a + b + c + d }; f({\"a\": 10}, [20])",
+		"let s = 0; let f = fn() { s }; let s = 5; f()",
+		"let acc = {\"fs\": []}; for (i in 0..3) { let j = i * 10; acc.fs = push(acc.fs, fn() { i + j }) }; let out = {\"v\": []}; for (f in acc.fs) { out.v = push(out.v, f()) }; out.v",
+		"let f = fn(xs) { let fs = {\"all\": []}; for ([i, x] in xs.enumerate()) { fs.all = push(fs.all, fn() { [i, x] }) }; fs.all }; let gs = f(#{\"a\", \"b\"}); [gs[0](), gs[1]()]",
+		"let f = fn(n) { for (i in 0..n) { if (i * i > n) { return i } }; -1 }; [f(10), f(0)]",
+		"let g = fn(n) { let fs = {\"all\": []}; for (i in 0..n) { fs.all = push(fs.all, fn() { i }); yield fs.all } }; let last = g(3).collect()[2]; [last[0](), last[2]()]",
+		"let g = fn(n) { yield n; if (n > 0) { return g(n - 1) } }; g(3).collect()",
+		"let g = fn(x) { let y = x * 2; yield fn() { x + y }; yield y }; let it = g(1); [it.next()(), it.next()]",
+		"let ch = channel(); let n = 3; let fs = array((0..n).map(fn(i) { spawn fn() { ch.send(i); i * n } })); [set(ch.take(n)) == set(0..n), (0..n).map(fn(i) { await fs[i] }).collect()]",
+	}
+
+	for _, input := range inputs {
+		dynamic := Eval(parse(t, input), object.NewEnvironment())
+		resolved := testEval(input)
+		if dynamic.Inspect() != resolved.Inspect() {
+			t.Errorf("%q: resolved program evaluated to %s, dynamic to %s", input, resolved.Inspect(), dynamic.Inspect())
+		}
+	}
+}
+
+func parse(t *testing.T, input string) *ast.Program {
+	t.Helper()
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("%q: parser errors: %v", input, p.Errors())
+	}
+	return program
+}
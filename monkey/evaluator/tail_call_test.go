@@ -0,0 +1,121 @@
+package evaluator
+
+import (
+	"monkey/object"
+	"runtime"
+	"testing"
+)
+
+// withStackDepth makes the builtin stack_depth() available while f runs. It
+// returns the number of Go stack frames below it.
+func withStackDepth(f func()) {
+	builtins["stack_depth"] = &object.Builtin{Name: "stack_depth", Fn: func(args ...object.Object) object.Object {
+		pcs := make([]uintptr, 1<<20)
+		return &object.Integer{Value: int64(runtime.Callers(0, pcs))}
+	}}
+	defer delete(builtins, "stack_depth")
+	f()
+}
+
+func TestTailCallsUseConstantStack(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"final expression", `
+			let count = fn(n) { if (n == 0) { stack_depth() } else { count(n - 1) } };
+			count(N)`},
+		{"return", `
+			let count = fn(n) { if (n == 0) { return stack_depth(); } return count(n - 1); };
+			count(N)`},
+		{"match arm", `
+			let count = fn(n) { match n { 0 => stack_depth(), _ => count(n - 1) } };
+			count(N)`},
+		{"mutual recursion", `
+			let even = fn(n) { if (n == 0) { stack_depth() } else { odd(n - 1) } };
+			let odd = fn(n) { if (n == 0) { stack_depth() } else { even(n - 1) } };
+			even(N)`},
+		{"method call", `
+			let counter = {"count": fn(self, n) { if (n == 0) { stack_depth() } else { self.count(n - 1) } }};
+			counter.count(N)`},
+		{"closure", `
+			let make = fn() { let loop = fn(n, acc) { if (n == 0) { stack_depth() } else { loop(n - 1, acc + n) } }; loop };
+			make()(N, 0)`},
+	}
+
+	withStackDepth(func() {
+		for _, tt := range tests {
+			shallow := testEval("let N = 10; " + tt.input)
+			deep := testEval("let N = 100000; " + tt.input)
+
+			shallowDepth, ok := shallow.(*object.Integer)
+			if !ok {
+				t.Errorf("%s: expected an integer. got=%s", tt.name, shallow.Inspect())
+				continue
+			}
+			if !testIntegerObject(t, deep, shallowDepth.Value) {
+				t.Errorf("%s: stack grows with recursion depth", tt.name)
+			}
+		}
+	})
+}
+
+func TestNonTailCallsStillGrowStack(t *testing.T) {
+	input := `
+		let f = fn(n) { if (n == 0) { stack_depth() } else { let depth = f(n - 1); depth } };
+		[f(1), f(10)]`
+
+	withStackDepth(func() {
+		evaluated := testEval(input)
+		depths, ok := evaluated.(*object.Array)
+		if !ok || len(depths.Elements) != 2 {
+			t.Fatalf("expected an array of two depths. got=%s", evaluated.Inspect())
+		}
+		if depths.Elements[0].(*object.Integer).Value >= depths.Elements[1].(*object.Integer).Value {
+			t.Errorf("expected a call that is not in tail position to use stack. got=%s", evaluated.Inspect())
+		}
+	})
+}
+
+func TestTailCalls(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{"let sum = fn(n, acc) { if (n == 0) { acc } else { sum(n - 1, acc + n) } }; sum(100000, 0)", 5000050000},
+		{"let f = fn(n) { if (n == 0) { return 1; } 2 + f(n - 1) }; f(3)", 7},
+		{"let f = fn(n) { if (n > 0) { f(0) } else { 5 } }; f(1) + 1", 6},
+		{"let g = fn(a, b) { a + b }; let f = fn() { g(1) }; f()", "wrong number of arguments. got=1, want=2"},
+		{"let f = fn(n) { if (n == 0) { throw \"done\" } else { f(n - 1) } }; try { f(100000) } catch (e) { e.message }", "done"},
+		{"let f = fn(n) { try { return g(n); } finally { 1 } }; let g = fn(n) { n * 2 }; f(4)", 8},
+		{"let f = fn() { len([1, 2]) }; f()", 2},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			if errObj, ok := evaluated.(*object.Error); ok {
+				if errObj.Messgae != expected {
+					t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Messgae)
+				}
+				continue
+			}
+			testStringObject(t, evaluated, expected)
+		}
+	}
+}
+
+func TestTailCallErrorPosition(t *testing.T) {
+	input := "let g = fn(a, b) { a + b };\nlet f = fn() { g(1) };\nf()"
+
+	err, ok := testEval(input).(*object.Error)
+	if !ok {
+		t.Fatalf("expected an error")
+	}
+	if err.Pos.String() != "2:17" {
+		t.Errorf("wrong error position. expected=2:17, got=%s", err.Pos)
+	}
+}
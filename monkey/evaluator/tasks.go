@@ -0,0 +1,167 @@
+package evaluator
+
+import (
+	"monkey/ast"
+	"monkey/object"
+	"reflect"
+)
+
+// evalSpawnExpression starts a task: the function and arguments of the call
+// are evaluated right away, and the call itself is made on a goroutine of
+// its own. The task shares the globals and captured variables of the code
+// that spawned it.
+//
+// An error a task fails with is not reported anywhere by itself. It
+// resolves the task's future, so that awaiting the future raises it; the
+// error of a task nobody awaits is lost.
+func evalSpawnExpression(se *ast.SpawnExpression, env *object.Environment) object.Object {
+	var function object.Object
+	var args []object.Object
+	if call, ok := se.Call.(*ast.CallExpression); ok {
+		function, args = evalCall(call, env)
+	} else {
+		function = Eval(se.Call, env)
+	}
+	if isError(function) {
+		return function
+	}
+	switch function.(type) {
+	case *object.Function, *object.Builtin:
+	default:
+		return newKindError(object.TYPE_ERROR, "cannot spawn %s", function.Type())
+	}
+
+	future := object.NewFuture()
+	go func() {
+		future.Resolve(withPos(applyFunction(function, args), se.Call))
+	}()
+	return future
+}
+
+// evalAwaitExpression waits for a future and evaluates to the result of its
+// task, which is an error if the task failed.
+func evalAwaitExpression(ae *ast.AwaitExpression, env *object.Environment) object.Object {
+	val := Eval(ae.Value, env)
+	if isError(val) {
+		return val
+	}
+	future, ok := val.(*object.Future)
+	if !ok {
+		return newKindError(object.TYPE_ERROR, "cannot await %s", val.Type())
+	}
+	return future.Wait()
+}
+
+// channelFn makes a channel, unbuffered or with the given capacity.
+func channelFn(args ...object.Object) object.Object {
+	if len(args) > 1 {
+		return newKindError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=0 or 1", len(args))
+	}
+	if len(args) == 0 {
+		return object.NewChannel(0)
+	}
+	capacity, err := countArgument("channel", args[0])
+	if err != nil {
+		return err
+	}
+	return object.NewChannel(int(capacity))
+}
+
+// channelMethod returns the method name of a channel, bound to it. Besides
+// send, recv and close, a channel has the methods of an iterator, which
+// receive from it until it is closed.
+func channelMethod(ch *object.Channel, name string) object.Object {
+	switch name {
+	case "send":
+		return &object.Builtin{Name: name, Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newKindError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=1", len(args))
+			}
+			if !ch.Send(args[0]) {
+				return newError("send on closed channel")
+			}
+			return NULL
+		}}
+	case "recv":
+		return &object.Builtin{Name: name, Fn: func(args ...object.Object) object.Object {
+			if len(args) != 0 {
+				return newKindError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=0", len(args))
+			}
+			if value, ok := ch.Recv(); ok {
+				return value
+			}
+			return NULL
+		}}
+	case "close":
+		return &object.Builtin{Name: name, Fn: func(args ...object.Object) object.Object {
+			if len(args) != 0 {
+				return newKindError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=0", len(args))
+			}
+			if !ch.Close() {
+				return newError("close of closed channel")
+			}
+			return NULL
+		}}
+	default:
+		return iteratorMethod(ch, name)
+	}
+}
+
+// selectFn waits until one of several channel operations can go ahead and
+// makes it. A channel argument receives from it; a tuple (channel, value)
+// sends value on it. The result is [i, value] for the i-th argument, where
+// value is what was received, or null for a send.
+//
+// Channels that are closed are left out, so select returns null once every
+// channel it receives from is closed and it has nothing to send.
+func selectFn(args ...object.Object) object.Object {
+	cases := make([]reflect.SelectCase, len(args))
+	for i, arg := range args {
+		switch arg := arg.(type) {
+		case *object.Channel:
+			cases[i] = reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(arg.Chan())}
+		case *object.Tuple:
+			var ch *object.Channel
+			if len(arg.Elements) == 2 {
+				ch, _ = arg.Elements[0].(*object.Channel)
+			}
+			if ch == nil {
+				return newKindError(object.TYPE_ERROR, "argument %d to `select` must be (CHANNEL, value), got %s", i+1, arg.Inspect())
+			}
+			cases[i] = reflect.SelectCase{Dir: reflect.SelectSend, Chan: reflect.ValueOf(ch.Chan()), Send: reflect.ValueOf(&arg.Elements[1]).Elem()}
+		default:
+			return newKindError(object.TYPE_ERROR, "argument %d to `select` must be CHANNEL or TUPLE, got %s", i+1, arg.Type())
+		}
+	}
+
+	for open := len(cases); open > 0; open-- {
+		chosen, value, ok, err := trySelect(cases)
+		if err != nil {
+			return err
+		}
+		if cases[chosen].Dir == reflect.SelectSend {
+			return &object.Array{Elements: []object.Object{&object.Integer{Value: int64(chosen)}, NULL}}
+		}
+		if ok {
+			return &object.Array{Elements: []object.Object{&object.Integer{Value: int64(chosen)}, value}}
+		}
+		// A zero Chan makes reflect.Select ignore the case.
+		cases[chosen].Chan = reflect.Value{}
+	}
+	return NULL
+}
+
+// trySelect runs reflect.Select, turning a send on a closed channel into an
+// error rather than a panic.
+func trySelect(cases []reflect.SelectCase) (chosen int, value object.Object, ok bool, err *object.Error) {
+	defer func() {
+		if recover() != nil {
+			err = newError("send on closed channel")
+		}
+	}()
+	chosen, recv, ok := reflect.Select(cases)
+	if ok {
+		value = recv.Interface().(object.Object)
+	}
+	return chosen, value, ok, nil
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"monkey/format"
+	"os"
+)
+
+// formatFiles prints each file in canonical form, or with -w rewrites the
+// files that are not already formatted.
+func formatFiles(args []string) int {
+	flags := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	write := flags.Bool("w", false, "write the result to the file instead of printing it")
+	flags.SetOutput(os.Stderr)
+	flags.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	if err := flags.Parse(args); err != nil || flags.NArg() == 0 {
+		if err == nil {
+			fmt.Fprint(os.Stderr, usage)
+		}
+		return 2
+	}
+
+	status := 0
+	for _, file := range flags.Args() {
+		if err := formatFile(file, *write); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", file, err)
+			status = 1
+		}
+	}
+	return status
+}
+
+func formatFile(file string, write bool) error {
+	source, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	formatted, err := format.Source(source)
+	if err != nil {
+		return err
+	}
+
+	if !write {
+		_, err := os.Stdout.Write(formatted)
+		return err
+	}
+	if bytes.Equal(source, formatted) {
+		return nil
+	}
+	info, err := os.Stat(file)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, formatted, info.Mode().Perm())
+}
@@ -0,0 +1,698 @@
+// Package format prints Monkey programs in a canonical style: two-space
+// indentation, one statement per line, only the parentheses the parser
+// needs, and arrays, hashes, calls and parameter lists split one element per
+// line when they do not fit in Width columns.
+//
+// Comments are kept. A comment is printed before the first statement that
+// follows it, or after the statement it shares a line with; comments inside
+// a multi-line expression move to just after the statement holding it.
+package format
+
+import (
+	"fmt"
+	"monkey/ast"
+	"monkey/lexer"
+	"monkey/parser"
+	"monkey/token"
+	"strings"
+	"unicode/utf8"
+)
+
+// Width is the line length the printer tries to stay within.
+const Width = 80
+
+const indent = "  "
+
+// Source formats a Monkey program. It fails if src does not parse.
+func Source(src []byte) ([]byte, error) {
+	l := lexer.New(string(src))
+	p := parser.New(l)
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, fmt.Errorf("%s", strings.Join(p.Errors(), "\n"))
+	}
+
+	pr := &printer{comments: l.Comments(), blank: blankLines(src)}
+	pr.statementList(program.Statements, token.Position{}, true)
+	if pr.out.Len() == 0 {
+		return nil, nil
+	}
+	pr.write("\n")
+	return []byte(pr.out.String()), nil
+}
+
+// Node formats a single node, without comments.
+func Node(node ast.Node) string {
+	p := &printer{}
+	switch node := node.(type) {
+	case *ast.Program:
+		p.statementList(node.Statements, token.Position{}, true)
+	case ast.Statement:
+		p.statement(node, needsSemicolon(node, nil))
+	case ast.Expression:
+		p.expr(node, parser.LOWEST)
+	}
+	return p.out.String()
+}
+
+type printer struct {
+	out   strings.Builder
+	depth int // indentation level
+	col   int // column the next write starts at, counted in runes
+
+	// flat is set while trying whether a list fits on one line; lists are
+	// then never split.
+	flat bool
+
+	comments []lexer.Comment
+	next     int // index of the first comment not yet printed
+	lastLine int // last source line printed so far
+
+	// blank reports for each source line, counted from 1, whether it is
+	// empty. It is nil when there is no source.
+	blank []bool
+}
+
+func blankLines(src []byte) []bool {
+	lines := strings.Split(string(src), "\n")
+	blank := make([]bool, len(lines)+1)
+	for i, line := range lines {
+		blank[i+1] = strings.TrimSpace(line) == ""
+	}
+	return blank
+}
+
+func (p *printer) write(s string) {
+	p.out.WriteString(s)
+	if i := strings.LastIndexByte(s, '\n'); i >= 0 {
+		p.col = utf8.RuneCountInString(s[i+1:])
+	} else {
+		p.col += utf8.RuneCountInString(s)
+	}
+}
+
+func (p *printer) newline() {
+	p.write("\n" + strings.Repeat(indent, p.depth))
+}
+
+// fork returns a printer that continues from the current position and
+// writes into its own buffer.
+func (p *printer) fork(flat bool) *printer {
+	return &printer{
+		depth:    p.depth,
+		col:      p.col,
+		flat:     flat,
+		comments: p.comments,
+		next:     p.next,
+		lastLine: p.lastLine,
+		blank:    p.blank,
+	}
+}
+
+// adopt appends the output of a fork and takes over its state.
+func (p *printer) adopt(f *printer) {
+	p.write(f.out.String())
+	p.next = f.next
+	p.lastLine = f.lastLine
+}
+
+// fits reports whether the first and last line of a fork's output stay
+// within Width. Lines in between belong to blocks, which are laid out on
+// their own.
+func (p *printer) fits(f *printer) bool {
+	out := f.out.String()
+	first, last := out, out
+	if i := strings.IndexByte(out, '\n'); i >= 0 {
+		first, last = out[:i], out[strings.LastIndexByte(out, '\n')+1:]
+	}
+	return p.col+utf8.RuneCountInString(first) <= Width && utf8.RuneCountInString(last) <= Width
+}
+
+// ================================================
+// ===================STATEMENTS===================
+// ================================================
+
+// statementList prints statements with the comments that come before end,
+// or all remaining comments if end is the zero Position. At the top level
+// the first item is not preceded by a line break.
+func (p *printer) statementList(statements []ast.Statement, end token.Position, top bool) {
+	count := 0
+	for i, statement := range statements {
+		var next ast.Statement
+		if i+1 < len(statements) {
+			next = statements[i+1]
+		}
+		p.commentsBefore(statement.Pos(), &count, top)
+		p.beginItem(statement.Pos().Line, &count, top)
+		p.statement(statement, needsSemicolon(statement, next))
+		p.lastLine = max(p.lastLine, lastLine(statement))
+		p.trailingComment()
+	}
+	p.commentsBefore(end, &count, top)
+}
+
+// beginItem starts the line of a statement or comment found at source line
+// line, keeping a single blank line where the source had one or more.
+func (p *printer) beginItem(line int, count *int, top bool) {
+	if *count > 0 || !top {
+		if *count > 0 && p.blankBefore(line) {
+			p.out.WriteString("\n")
+		}
+		p.newline()
+	}
+	*count++
+}
+
+// blankBefore reports whether the source has a blank line right before
+// line. Without source, a gap after the last line printed counts as one.
+func (p *printer) blankBefore(line int) bool {
+	if p.blank == nil {
+		return p.lastLine > 0 && line > p.lastLine+1
+	}
+	return line > 1 && line-1 < len(p.blank) && p.blank[line-1]
+}
+
+func (p *printer) commentsBefore(pos token.Position, count *int, top bool) {
+	for p.next < len(p.comments) {
+		comment := p.comments[p.next]
+		if pos.Line != 0 && !before(comment.Pos, pos) {
+			return
+		}
+		p.beginItem(comment.Pos.Line, count, top)
+		p.write(comment.Text)
+		p.lastLine = comment.Pos.Line
+		p.next++
+	}
+}
+
+func (p *printer) trailingComment() {
+	if p.next < len(p.comments) && p.comments[p.next].Pos.Line == p.lastLine {
+		p.write(" " + p.comments[p.next].Text)
+		p.next++
+	}
+}
+
+// hasCommentBefore reports whether a comment not yet printed comes before
+// pos.
+func (p *printer) hasCommentBefore(pos token.Position) bool {
+	return p.next < len(p.comments) && before(p.comments[p.next].Pos, pos)
+}
+
+func before(a, b token.Position) bool {
+	return a.Line < b.Line || a.Line == b.Line && a.Column < b.Column
+}
+
+// lastLine returns the last source line a node is known to occupy.
+func lastLine(node ast.Node) int {
+	line := 0
+	ast.Inspect(node, func(n ast.Node) bool {
+		if n == nil {
+			return false
+		}
+		line = max(line, n.Pos().Line)
+		if block, ok := n.(*ast.BlockStatement); ok {
+			line = max(line, block.Rbrace.Line)
+		}
+		return true
+	})
+	return line
+}
+
+// needsSemicolon reports whether statement must end in a semicolon when it
+// is followed by next, which is nil at the end of a block or program.
+//
+// Statements other than expression statements always end in one. So does an
+// expression statement followed by another statement, since the next line
+// could otherwise continue the expression, except after an if, match, try
+// or for expression where only a next line starting with (, [ or - could.
+func needsSemicolon(statement, next ast.Statement) bool {
+	es, ok := statement.(*ast.ExpressionStatement)
+	if !ok {
+		return true
+	}
+	if next == nil {
+		return false
+	}
+	switch es.Expression.(type) {
+	case *ast.IfExpression, *ast.MatchExpression, *ast.TryExpression, *ast.ForExpression:
+		nextES, ok := next.(*ast.ExpressionStatement)
+		if !ok {
+			return false
+		}
+		switch nextES.Token.Type {
+		case token.LPAREN, token.LBRACKET, token.MINUS:
+			return true
+		}
+		return false
+	}
+	return true
+}
+
+func (p *printer) statement(statement ast.Statement, semicolon bool) {
+	switch s := statement.(type) {
+	case *ast.LetStatement:
+		p.write("let ")
+		if s.Pattern != nil {
+			p.pattern(s.Pattern)
+		} else {
+			p.write(s.Name.Value)
+		}
+		p.write(" = ")
+		p.expr(s.Value, parser.LOWEST)
+		p.write(";")
+
+	case *ast.ReturnStatement:
+		p.write("return")
+		if s.ReturnValue != nil {
+			p.write(" ")
+			p.expr(s.ReturnValue, parser.LOWEST)
+		}
+		p.write(";")
+
+	case *ast.ThrowStatement:
+		p.write("throw ")
+		p.expr(s.Value, parser.LOWEST)
+		p.write(";")
+
+	case *ast.YieldStatement:
+		p.write("yield ")
+		p.expr(s.Value, parser.LOWEST)
+		p.write(";")
+
+	case *ast.ImportStatement:
+		p.write("import ")
+		p.expr(s.Path, parser.LOWEST)
+		if s.Alias != nil {
+			p.write(" as " + s.Alias.Value)
+		}
+		p.write(";")
+
+	case *ast.ExportStatement:
+		p.write("export ")
+		p.statement(s.Statement, true)
+
+	case *ast.ExpressionStatement:
+		p.expr(s.Expression, parser.LOWEST)
+		if semicolon {
+			p.write(";")
+		}
+
+	case *ast.BlockStatement:
+		p.block(s)
+	}
+}
+
+// block prints a block. A block written on one line in the source that
+// holds a single short expression stays on one line.
+func (p *printer) block(block *ast.BlockStatement) {
+	if p.hasCommentBefore(block.Rbrace) {
+		p.blockLines(block)
+		return
+	}
+	if len(block.Statements) == 0 {
+		p.write("{}")
+		return
+	}
+
+	statement, ok := block.Statements[0].(*ast.ExpressionStatement)
+	if ok && len(block.Statements) == 1 && block.Token.Pos.Line == block.Rbrace.Line {
+		inline := p.fork(true)
+		inline.write("{ ")
+		inline.expr(statement.Expression, parser.LOWEST)
+		inline.write(" }")
+		if !strings.Contains(inline.out.String(), "\n") && p.fits(inline) {
+			p.adopt(inline)
+			return
+		}
+	}
+
+	p.blockLines(block)
+}
+
+func (p *printer) blockLines(block *ast.BlockStatement) {
+	flat := p.flat
+	p.flat = false
+
+	p.write("{")
+	p.depth++
+	p.statementList(block.Statements, block.Rbrace, false)
+	p.depth--
+	p.newline()
+	p.write("}")
+	p.lastLine = max(p.lastLine, block.Rbrace.Line)
+
+	p.flat = flat
+}
+
+// ================================================
+// ==================EXPRESSIONS===================
+// ================================================
+
+// precedence returns how tightly an expression binds. Postfix operators and
+// primary expressions such as literals never need parentheses around them.
+func precedence(expression ast.Expression) int {
+	switch e := expression.(type) {
+	case *ast.InfixExpression:
+		return parser.Precedence(e.Token.Type)
+	case *ast.AssignExpression:
+		return parser.ASSIGN
+	case *ast.PrefixExpression, *ast.SpawnExpression, *ast.AwaitExpression:
+		return parser.PREFIX
+	default:
+		return parser.CALL
+	}
+}
+
+// expr prints an expression that appears where the parser requires at
+// least the given precedence, adding parentheses if it binds more loosely.
+func (p *printer) expr(expression ast.Expression, prec int) {
+	if precedence(expression) < prec {
+		p.write("(")
+		p.expr(expression, parser.LOWEST)
+		p.write(")")
+		return
+	}
+
+	switch e := expression.(type) {
+	case *ast.Identifier:
+		p.write(e.Value)
+	case *ast.NullExpression:
+		p.write("null")
+	case *ast.IntegerLiteral:
+		p.write(e.Token.Literal)
+	case *ast.FloatLiteral:
+		p.write(e.Token.Literal)
+	case *ast.StringLiteral:
+		p.write(`"` + e.Value + `"`)
+	case *ast.Boolean:
+		p.write(fmt.Sprint(e.Value))
+
+	case *ast.PrefixExpression:
+		p.write(e.Operator)
+		p.expr(e.Right, parser.PREFIX)
+
+	case *ast.InfixExpression:
+		prec := parser.Precedence(e.Token.Type)
+		p.expr(e.Left, prec)
+		if prec == parser.RANGE {
+			p.write(e.Operator)
+		} else {
+			p.write(" " + e.Operator + " ")
+		}
+		p.expr(e.Right, prec+1)
+
+	case *ast.AssignExpression:
+		p.expr(e.Target, parser.ASSIGN+1)
+		p.write(" = ")
+		p.expr(e.Value, parser.ASSIGN)
+
+	case *ast.CallExpression:
+		p.expr(e.Function, parser.CALL)
+		p.expressionList("(", e.Arguments, ")")
+
+	case *ast.IndexExpression:
+		p.expr(e.Left, parser.CALL)
+		p.write("[")
+		p.expr(e.Index, parser.LOWEST)
+		p.write("]")
+
+	case *ast.SliceExpression:
+		p.expr(e.Left, parser.CALL)
+		p.write("[")
+		for i, bound := range []ast.Expression{e.Start, e.End, e.Step} {
+			if i == 2 && bound == nil {
+				break
+			}
+			if i > 0 {
+				p.write(":")
+			}
+			if bound != nil {
+				p.expr(bound, parser.LOWEST)
+			}
+		}
+		p.write("]")
+
+	case *ast.MemberExpression:
+		p.expr(e.Object, parser.CALL)
+		p.write("." + e.Property.Value)
+
+	case *ast.SpreadExpression:
+		p.write("...")
+		p.expr(e.Value, parser.LOWEST)
+
+	case *ast.SpawnExpression:
+		p.write("spawn ")
+		p.expr(e.Call, parser.PREFIX)
+
+	case *ast.AwaitExpression:
+		p.write("await ")
+		p.expr(e.Value, parser.PREFIX)
+
+	case *ast.ArrayLiteral:
+		p.expressionList("[", e.Elements, "]")
+
+	case *ast.SetLiteral:
+		p.expressionList("#{", e.Elements, "}")
+
+	case *ast.TupleLiteral:
+		if len(e.Elements) == 1 {
+			// The trailing comma is what makes it a tuple.
+			p.list("(", 1, ")", func(p *printer, i int) {
+				p.expr(e.Elements[0], parser.LOWEST)
+				p.write(",")
+			})
+			break
+		}
+		p.expressionList("(", e.Elements, ")")
+
+	case *ast.HashLiteral:
+		keys := e.Keys()
+		p.list("{", len(keys), "}", func(p *printer, i int) {
+			p.expr(keys[i], parser.LOWEST)
+			p.write(": ")
+			p.expr(e.Pairs[keys[i]], parser.LOWEST)
+		})
+
+	case *ast.FunctionLiteral:
+		p.write("fn")
+		p.list("(", len(e.Parameters), ")", func(p *printer, i int) {
+			p.pattern(e.Parameters[i])
+		})
+		p.write(" ")
+		p.block(e.Body)
+
+	case *ast.MacroLiteral:
+		p.write("macro")
+		p.list("(", len(e.Parameters), ")", func(p *printer, i int) {
+			p.write(e.Parameters[i].Value)
+		})
+		p.write(" ")
+		p.block(e.Body)
+
+	case *ast.IfExpression:
+		p.ifExpression(e)
+
+	case *ast.MatchExpression:
+		p.matchExpression(e)
+
+	case *ast.ForExpression:
+		p.write("for (")
+		p.pattern(e.Pattern)
+		p.write(" in ")
+		p.expr(e.Iterable, parser.LOWEST)
+		p.write(") ")
+		p.block(e.Body)
+
+	case *ast.TryExpression:
+		inline := p.fork(p.flat)
+		inline.tryExpression(e, false)
+		if !strings.Contains(inline.out.String(), "\n") && p.fits(inline) {
+			p.adopt(inline)
+		} else {
+			p.tryExpression(e, true)
+		}
+
+	default:
+		p.write(expression.String())
+	}
+}
+
+func (p *printer) expressionList(open string, expressions []ast.Expression, close string) {
+	p.list(open, len(expressions), close, func(p *printer, i int) {
+		p.expr(expressions[i], parser.LOWEST)
+	})
+}
+
+// list prints n comma-separated items between open and close, on one line
+// if that fits and one item per line otherwise.
+func (p *printer) list(open string, n int, close string, item func(p *printer, i int)) {
+	flat := p.fork(true)
+	flat.write(open)
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			flat.write(", ")
+		}
+		item(flat, i)
+	}
+	flat.write(close)
+
+	if p.flat || n == 0 || p.fits(flat) {
+		p.adopt(flat)
+		return
+	}
+
+	p.write(open)
+	p.depth++
+	for i := 0; i < n; i++ {
+		p.newline()
+		item(p, i)
+		if i < n-1 {
+			p.write(",")
+		}
+	}
+	p.depth--
+	p.newline()
+	p.write(close)
+}
+
+// ifExpression prints an if expression, with an else-if chain, on one line
+// only if every branch can stay inline.
+func (p *printer) ifExpression(e *ast.IfExpression) {
+	inline := p.fork(p.flat)
+	inline.ifChain(e, false)
+	if !strings.Contains(inline.out.String(), "\n") && p.fits(inline) {
+		p.adopt(inline)
+		return
+	}
+	p.ifChain(e, true)
+}
+
+func (p *printer) ifChain(e *ast.IfExpression, expand bool) {
+	p.write("if (")
+	p.expr(e.Condition, parser.LOWEST)
+	p.write(") ")
+	p.blockOrLines(e.Consequence, expand)
+
+	if e.Alternative == nil {
+		return
+	}
+	p.write(" else ")
+	if nested, ok := elseIf(e.Alternative); ok {
+		p.ifChain(nested, expand)
+		return
+	}
+	p.blockOrLines(e.Alternative, expand)
+}
+
+func (p *printer) tryExpression(e *ast.TryExpression, expand bool) {
+	p.write("try ")
+	p.blockOrLines(e.Block, expand)
+	if e.Catch != nil {
+		p.write(" catch ")
+		if e.CatchParam != nil {
+			p.write("(")
+			p.pattern(e.CatchParam)
+			p.write(") ")
+		}
+		p.blockOrLines(e.Catch, expand)
+	}
+	if e.Finally != nil {
+		p.write(" finally ")
+		p.blockOrLines(e.Finally, expand)
+	}
+}
+
+// blockOrLines prints a block like block does, or always on several lines if
+// expand is set and the block is not empty.
+func (p *printer) blockOrLines(block *ast.BlockStatement, expand bool) {
+	if expand && len(block.Statements) > 0 {
+		p.blockLines(block)
+		return
+	}
+	p.block(block)
+}
+
+// elseIf returns the if expression of an `else if`, which the parser stores
+// as an else block holding just that expression.
+func elseIf(block *ast.BlockStatement) (*ast.IfExpression, bool) {
+	if block.Token.Type != token.IF || len(block.Statements) != 1 {
+		return nil, false
+	}
+	statement, ok := block.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		return nil, false
+	}
+	nested, ok := statement.Expression.(*ast.IfExpression)
+	return nested, ok
+}
+
+// matchExpression prints one arm per line, each followed by a comma.
+func (p *printer) matchExpression(e *ast.MatchExpression) {
+	flat := p.flat
+	p.flat = false
+
+	p.write("match ")
+	p.expr(e.Subject, parser.LOWEST)
+	p.write(" {")
+	p.depth++
+	count := 0
+	for _, arm := range e.Arms {
+		p.commentsBefore(arm.Pos(), &count, false)
+		p.beginItem(arm.Pos().Line, &count, false)
+		p.pattern(arm.Pattern)
+		if arm.Guard != nil {
+			p.write(" if ")
+			p.expr(arm.Guard, parser.LOWEST)
+		}
+		p.write(" => ")
+		p.expr(arm.Body, parser.LOWEST)
+		p.write(",")
+		p.lastLine = max(p.lastLine, lastLine(arm))
+		p.trailingComment()
+	}
+	p.depth--
+	p.newline()
+	p.write("}")
+
+	p.flat = flat
+}
+
+// pattern prints a binding pattern: an identifier, a literal, or an array
+// or hash pattern.
+func (p *printer) pattern(pattern ast.Expression) {
+	switch pat := pattern.(type) {
+	case *ast.ArrayPattern:
+		n := len(pat.Elements)
+		if pat.Rest != nil {
+			n++
+		}
+		p.list("[", n, "]", func(p *printer, i int) {
+			if i == len(pat.Elements) {
+				p.write("..." + pat.Rest.Value)
+				return
+			}
+			p.pattern(pat.Elements[i])
+		})
+
+	case *ast.HashPattern:
+		n := len(pat.Pairs)
+		if pat.Rest != nil {
+			n++
+		}
+		p.list("{", n, "}", func(p *printer, i int) {
+			if i == len(pat.Pairs) {
+				p.write("..." + pat.Rest.Value)
+				return
+			}
+			pair := pat.Pairs[i]
+			p.expr(pair.Key, parser.LOWEST)
+			if pair.Value != pair.Key {
+				p.write(": ")
+				p.pattern(pair.Value)
+			}
+		})
+
+	default:
+		p.expr(pattern, parser.LOWEST)
+	}
+}
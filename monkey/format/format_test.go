@@ -0,0 +1,202 @@
+package format
+
+import (
+	"monkey/lexer"
+	"monkey/parser"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSource(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		// parentheses
+		{"(1 + 2) * 3", "(1 + 2) * 3"},
+		{"1 + (2 * 3)", "1 + 2 * 3"},
+		{"((a + b) + c)", "a + b + c"},
+		{"a + (b + c)", "a + (b + c)"},
+		{"a - (b - c)", "a - (b - c)"},
+		{"-(a + b)", "-(a + b)"},
+		{"-(a.b)", "-a.b"},
+		{"!(a == b)", "!(a == b)"},
+		{"(a < b) == (c > d)", "a < b == c > d"},
+		{"(f(x))[0]", "f(x)[0]"},
+		{"(a + b).c", "(a + b).c"},
+		{"(-f)(x)", "(-f)(x)"},
+		{"(a.b = 1)", "a.b = 1"},
+		{"a.b = (c.d = 1)", "a.b = c.d = 1"},
+		{"x == (a.b = 1)", "x == (a.b = 1)"},
+		{"(fn(x) { x })(1)", "fn(x) { x }(1)"},
+
+		// statements and spacing
+		{"let   x=1", "let x = 1;"},
+		{"let [a,b,...rest]=arr", "let [a, b, ...rest] = arr;"},
+		{"let {name,age:years,...rest}=p", "let {name, age: years, ...rest} = p;"},
+		{"return x", "return x;"},
+		{"throw   \"boom\"", `throw "boom";`},
+		{`import "lib"as l`, `import "lib" as l;`},
+		{"export let a=0xFF", "export let a = 0xFF;"},
+		{"a\nb", "a;\nb"},
+		{"a;\n\n\n\nb", "a;\n\nb"},
+		{"1_000 + 1.5e3", "1_000 + 1.5e3"},
+		{`{"a":1,b:[1,2]}`, `{"a": 1, b: [1, 2]}`},
+		{"#{1,2}|#{}", "#{1, 2} | #{}"},
+		{"(a|b)&c", "(a | b) & c"},
+		{"( 1 , )", "(1,)"},
+		{"(1,2,)", "(1, 2)"},
+		{"((1, 2))", "(1, 2)"},
+		{"()", "()"},
+		{"a[ 1 : n-1 ]", "a[1:n - 1]"},
+		{"a[::-1]", "a[::-1]"},
+		{"a[:]", "a[:]"},
+		{"a[1::2]", "a[1::2]"},
+		{"(a+b)[1:]", "(a + b)[1:]"},
+		{"0 .. n+1", "0..n + 1"},
+		{"(0..=n).map(f)", "(0..=n).map(f)"},
+		{"(a..b)..c", "a..b..c"},
+		{"a..(b..c)", "a..(b..c)"},
+		{"[ ...xs, ...0..3 ]", "[...xs, ...0..3]"},
+		{"f( ...args )", "f(...args)"},
+
+		// blocks
+		{"fn(x){x}", "fn(x) { x }"},
+		{"fn(){}", "fn() {}"},
+		{"fn(x){\nx}", "fn(x) {\n  x\n}"},
+		{"fn(x){ let y = x; y }", "fn(x) {\n  let y = x;\n  y\n}"},
+		{"macro(a){quote(unquote(a))}", "macro(a) { quote(unquote(a)) }"},
+		{"if(a){b}else if(c){d}else{e}", "if (a) { b } else if (c) { d } else { e }"},
+		{"if (a) {\nb } else { c }", "if (a) {\n  b\n} else {\n  c\n}"},
+		{"if (a) { return 1; }\nlet x = 2", "if (a) {\n  return 1;\n}\nlet x = 2;"},
+		{"if (a) { b };\n-1", "if (a) { b };\n-1"},
+		{"if (a) { b }\n-1", "if (a) { b } - 1"},
+		{"if (a) { b }\nc", "if (a) { b }\nc"},
+		{"try{a}catch(e){b}finally{c}", "try { a } catch (e) { b } finally { c }"},
+		{"try{a}catch{b}", "try { a } catch { b }"},
+		{"for(x in xs){print(x)}", "for (x in xs) { print(x) }"},
+		{"for ([i,x] in xs.enumerate()) {\nprint(i, x) };\n[1]", "for ([i, x] in xs.enumerate()) {\n  print(i, x)\n};\n[1]"},
+		{"for (_ in 0..3) {}", "for (_ in 0..3) {}"},
+		{"fn(n){yield n;yield n+1}", "fn(n) {\n  yield n;\n  yield n + 1;\n}"},
+		{"await   spawn f(x)+1", "await spawn f(x) + 1"},
+		{"spawn fn(){ch.send(1)}", "spawn fn() { ch.send(1) }"},
+		{"(await f).x", "(await f).x"},
+		{"match x { 1 => a, [h, ...t] if h > 0 => h, _ => null }",
+			"match x {\n  1 => a,\n  [h, ...t] if h > 0 => h,\n  _ => null,\n}"},
+
+		// wrapping
+		{
+			"let numbers = [1111111111, 2222222222, 3333333333, 4444444444, 5555555555, 6666666666];",
+			"let numbers = [\n  1111111111,\n  2222222222,\n  3333333333,\n  4444444444,\n  5555555555,\n  6666666666\n];",
+		},
+		{
+			`print(format("{} is {} years old and lives in {}", [person.name, person.age, person.city]));`,
+			"print(\n  format(\n    \"{} is {} years old and lives in {}\",\n    [person.name, person.age, person.city]\n  )\n)",
+		},
+		{
+			`let person = {"name": "Ada Lovelace", "born": 1815, "known for": "the first computer program"};`,
+			"let person = {\n  \"name\": \"Ada Lovelace\",\n  \"born\": 1815,\n  \"known for\": \"the first computer program\"\n};",
+		},
+		{
+			"let t = (\"a tuple of one long string that does not fit on a line of eighty columns\",);",
+			"let t = (\n  \"a tuple of one long string that does not fit on a line of eighty columns\",\n);",
+		},
+		{
+			"map(items, fn(item) { let doubled = item * 2; doubled + 1 })",
+			"map(items, fn(item) {\n  let doubled = item * 2;\n  doubled + 1\n})",
+		},
+
+		// comments
+		{"// leading\nlet x = 1; // trailing\n// final", "// leading\nlet x = 1; // trailing\n// final"},
+		{"let f = fn() {\n  // inside\n  1\n  // last\n};", "let f = fn() {\n  // inside\n  1\n  // last\n};"},
+		{"let f = fn() { // after brace\n  1\n};", "let f = fn() {\n  // after brace\n  1\n};"},
+		{"a;\n\n// about b\nb", "a;\n\n// about b\nb"},
+		{"let f = fn() {\n  // only a comment\n};", "let f = fn() {\n  // only a comment\n};"},
+		{"match x {\n  // first\n  1 => a,\n  _ => b, // other\n}", "match x {\n  // first\n  1 => a,\n  _ => b, // other\n}"},
+		{"let a = [\n  1, // one\n  2\n];\nb", "let a = [1, 2];\n// one\nb"},
+	}
+
+	for _, tt := range tests {
+		formatted, err := Source([]byte(tt.input))
+		if err != nil {
+			t.Errorf("%q: unexpected error: %s", tt.input, err)
+			continue
+		}
+		if string(formatted) != tt.expected+"\n" {
+			t.Errorf("%q: wrong output.\nexpected:\n%s\ngot:\n%s", tt.input, tt.expected, formatted)
+		}
+	}
+}
+
+func TestSourceErrors(t *testing.T) {
+	_, err := Source([]byte("let = 1;"))
+	if err == nil || !strings.HasPrefix(err.Error(), "expected next token to be IDENT, got = instead") {
+		t.Errorf("expected a parse error. got=%v", err)
+	}
+
+	formatted, err := Source([]byte("  \n// \n"))
+	if err != nil || string(formatted) != "//\n" {
+		t.Errorf("wrong output for a comment-only file. got=%q, %v", formatted, err)
+	}
+}
+
+// TestFormatPreservesProgram checks on the standard library and the inputs of
+// TestSource that formatting does not change what a program means and that
+// formatted code is left as is.
+func TestFormatPreservesProgram(t *testing.T) {
+	sources := map[string]string{}
+	files, err := filepath.Glob("../stdlib/*.mk")
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no standard library sources found: %v", err)
+	}
+	for _, file := range files {
+		source, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sources[file] = string(source)
+	}
+	sources["deeply nested"] = "let f = fn(a) { fn(b) { [a, {k: [b, fn(c) { if (c) { [1, 2, 3] } }]}] } };"
+
+	for name, source := range sources {
+		formatted, err := Source([]byte(source))
+		if err != nil {
+			t.Errorf("%s: %s", name, err)
+			continue
+		}
+
+		if parse(t, string(formatted)) != parse(t, source) {
+			t.Errorf("%s: formatting changed the program.\nbefore: %s\nafter:  %s", name, parse(t, source), parse(t, string(formatted)))
+		}
+		again, err := Source(formatted)
+		if err != nil || string(again) != string(formatted) {
+			t.Errorf("%s: formatting is not idempotent.\nfirst:\n%s\nsecond:\n%s", name, formatted, again)
+		}
+		for _, line := range strings.Split(string(formatted), "\n") {
+			if strings.HasSuffix(line, " ") {
+				t.Errorf("%s: trailing whitespace in %q", name, line)
+			}
+		}
+	}
+}
+
+func parse(t *testing.T, source string) string {
+	t.Helper()
+	p := parser.New(lexer.New(source))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+	return program.String()
+}
+
+func TestNode(t *testing.T) {
+	p := parser.New(lexer.New("let add = fn(a, b) { a + (b * 2) };"))
+	program := p.ParseProgram()
+
+	if got := Node(program.Statements[0]); got != "let add = fn(a, b) { a + b * 2 };" {
+		t.Errorf("wrong output. got=%q", got)
+	}
+}
@@ -1,48 +1,88 @@
 package lexer
 
 import (
+	"fmt"
 	"monkey/token"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
 type Lexer struct {
 	input        string
 	position     int  // current position in input (points to current char)
 	readPosition int  // curent reading position in input (after curent char)
-	ch           byte // current char under examination
+	ch           rune // current char under examination
+	width        int  // width in bytes of the current char
+
+	file   string // file the input comes from, recorded in positions
+	line   int    // line of the current char
+	column int    // column of the current char, counted in runes
+
+	errors   []string
+	errorPos []token.Position // where each of errors was found
+	comments []Comment
+}
+
+// Comment is a `//` line comment. Comments are skipped like whitespace when
+// tokenizing; they are only kept for tools such as the formatter.
+type Comment struct {
+	Pos  token.Position
+	Text string // including the leading //
 }
 
 func New(input string) *Lexer {
-	l := &Lexer{input: input}
+	return NewFile("", input)
+}
+
+// NewFile returns a lexer for input read from file, whose name the
+// positions of its tokens carry.
+func NewFile(file, input string) *Lexer {
+	l := &Lexer{input: input, file: file, line: 1}
 	l.readChar()
 	return l
 }
 
 func (l *Lexer) readChar() {
+	if l.ch == '\n' {
+		l.line += 1
+		l.column = 1
+	} else {
+		l.column += 1
+	}
+
 	if l.readPosition >= len(l.input) {
 		l.ch = 0
+		l.width = 1
 	} else {
-		l.ch = l.input[l.readPosition]
+		l.ch, l.width = utf8.DecodeRuneInString(l.input[l.readPosition:])
+		if l.ch == utf8.RuneError && l.width == 1 {
+			l.addError(l.pos(), "invalid UTF-8 encoding")
+		}
 	}
 	l.position = l.readPosition
-	l.readPosition += 1
+	l.readPosition += l.width
 }
 
-func (l *Lexer) peakChar() byte {
+func (l *Lexer) peakChar() rune {
 	if l.readPosition >= len(l.input) {
 		return 0
 	} else {
-		return l.input[l.readPosition]
+		r, _ := utf8.DecodeRuneInString(l.input[l.readPosition:])
+		return r
 	}
 }
 
-func newToken(tokenType token.TokenType, ch byte) token.Token {
+func newToken(tokenType token.TokenType, ch rune) token.Token {
 	return token.Token{Type: tokenType, Literal: string(ch)}
 }
 
 func (l *Lexer) NextToken() token.Token {
 	var tok token.Token
 
-	l.skipWhitespace()
+	l.skipWhitespaceAndComments()
+
+	pos := l.pos()
 
 	switch l.ch {
 	case '"':
@@ -53,6 +93,10 @@ func (l *Lexer) NextToken() token.Token {
 			ch := l.ch
 			l.readChar()
 			tok = token.Token{Type: token.EQ, Literal: string(ch) + string(l.ch)}
+		} else if l.peakChar() == '>' {
+			ch := l.ch
+			l.readChar()
+			tok = token.Token{Type: token.ARROW, Literal: string(ch) + string(l.ch)}
 		} else {
 			tok = newToken(token.ASSIGN, l.ch)
 		}
@@ -94,6 +138,34 @@ func (l *Lexer) NextToken() token.Token {
 		tok = newToken(token.LT, l.ch)
 	case '>':
 		tok = newToken(token.GT, l.ch)
+	case '|':
+		tok = newToken(token.PIPE, l.ch)
+	case '&':
+		tok = newToken(token.AMPERSAND, l.ch)
+	case '#':
+		if l.peakChar() == '{' {
+			ch := l.ch
+			l.readChar()
+			tok = token.Token{Type: token.SET_LBRACE, Literal: string(ch) + string(l.ch)}
+		} else {
+			l.addError(pos, "unexpected character %q", l.ch)
+			tok = newToken(token.ILLEGAL, l.ch)
+		}
+	case '.':
+		if strings.HasPrefix(l.input[l.position:], "...") {
+			l.readChar()
+			l.readChar()
+			tok = token.Token{Type: token.ELLIPSIS, Literal: "..."}
+		} else if strings.HasPrefix(l.input[l.position:], "..=") {
+			l.readChar()
+			l.readChar()
+			tok = token.Token{Type: token.DOTDOT_EQ, Literal: "..="}
+		} else if l.peakChar() == '.' {
+			l.readChar()
+			tok = token.Token{Type: token.DOTDOT, Literal: ".."}
+		} else {
+			tok = newToken(token.DOT, l.ch)
+		}
 	case 0:
 		tok.Type = token.EOF
 		tok.Literal = ""
@@ -101,38 +173,165 @@ func (l *Lexer) NextToken() token.Token {
 		if isLetter(l.ch) {
 			tok.Literal = l.readIdentifier()
 			tok.Type = token.LookupIdent(tok.Literal)
+			tok.Pos = pos
 			return tok
 		} else if isDigit(l.ch) {
-			tok.Literal = l.readNumber()
-			tok.Type = token.INT
+			tok.Type, tok.Literal = l.readNumber()
+			tok.Pos = pos
 			return tok
 		} else {
-			tok = newToken(token.ILLEGAL, l.ch)
+			if !l.invalidChar() {
+				l.addError(pos, "unexpected character %q", l.ch)
+			}
+			tok = token.Token{Type: token.ILLEGAL, Literal: l.input[l.position:l.readPosition]}
 		}
 	}
 
+	tok.Pos = pos
 	l.readChar()
 	return tok
 }
 
+// readIdentifier follows Go's rules: a letter or underscore followed by any
+// number of letters, underscores and Unicode digits.
 func (l *Lexer) readIdentifier() string {
 	startPos := l.position
-	for isLetter(l.ch) {
+	for isLetter(l.ch) || unicode.IsDigit(l.ch) {
 		l.readChar()
 	}
 	return l.input[startPos:l.position]
 }
 
-func isLetter(ch byte) bool {
-	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_'
+func isLetter(ch rune) bool {
+	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_' ||
+		ch >= utf8.RuneSelf && unicode.IsLetter(ch)
 }
 
-func (l *Lexer) readNumber() string {
+// readNumber reads an integer or float literal. Integers may be written in
+// hex (0xFF), octal (0o17) or binary (0b1010), and in every form digits may
+// be separated by single underscores (1_000_000). A malformed literal is
+// reported and returned as token.ILLEGAL.
+func (l *Lexer) readNumber() (token.TokenType, string) {
 	startPos := l.position
-	for isDigit(l.ch) {
+	pos := l.pos()
+	tokenType := token.TokenType(token.INT)
+
+	base, prefix := 10, rune(0)
+	if l.ch == '0' {
+		switch lower(l.peakChar()) {
+		case 'x':
+			base, prefix = 16, 'x'
+		case 'o':
+			base, prefix = 8, 'o'
+		case 'b':
+			base, prefix = 2, 'b'
+		}
+	}
+
+	var msg string
+	if prefix != 0 {
+		l.readChar()
 		l.readChar()
+		digits, invalid := l.readDigits(base)
+		switch {
+		case digits == 0:
+			msg = fmt.Sprintf("%s literal has no digits", litName(prefix))
+		case invalid != 0:
+			msg = fmt.Sprintf("invalid digit %q in %s literal", invalid, litName(prefix))
+		case l.ch == '.' && isDigit(l.peakChar()):
+			msg = fmt.Sprintf("invalid radix point in %s literal", litName(prefix))
+		}
+	} else {
+		l.readDigits(10)
+		if l.ch == '.' && isDigit(l.peakChar()) {
+			tokenType = token.FLOAT
+			l.readChar()
+			l.readDigits(10)
+		}
+		if lower(l.ch) == 'e' {
+			tokenType = token.FLOAT
+			l.readChar()
+			if l.ch == '+' || l.ch == '-' {
+				l.readChar()
+			}
+			if digits, _ := l.readDigits(10); digits == 0 {
+				msg = "exponent has no digits"
+			}
+		}
+		literal := l.input[startPos:l.position]
+		if msg == "" && tokenType == token.INT && len(literal) > 1 && literal[0] == '0' {
+			msg = "invalid leading zero in decimal literal (use 0o for octal)"
+		}
 	}
-	return l.input[startPos:l.position]
+
+	literal := l.input[startPos:l.position]
+	if msg == "" {
+		if i := invalidSeparator(literal, base); i >= 0 {
+			msg = "'_' must separate successive digits"
+		}
+	}
+	if msg != "" {
+		l.addError(pos, "%s: %q", msg, literal)
+		return token.ILLEGAL, literal
+	}
+	return tokenType, literal
+}
+
+// readDigits consumes digits and underscores. Every decimal digit is consumed
+// regardless of base so that 0b102 is reported rather than split in two; the
+// first digit that is not valid in base is returned as invalid.
+func (l *Lexer) readDigits(base int) (digits int, invalid rune) {
+	for {
+		switch {
+		case l.ch == '_':
+		case isDigit(l.ch):
+			if int(l.ch-'0') >= base && invalid == 0 {
+				invalid = l.ch
+			}
+			digits += 1
+		case base == 16 && 'a' <= lower(l.ch) && lower(l.ch) <= 'f':
+			digits += 1
+		default:
+			return digits, invalid
+		}
+		l.readChar()
+	}
+}
+
+// invalidSeparator returns the index of the first '_' in literal that does
+// not sit between two digits (a base prefix counts as a digit), or -1.
+func invalidSeparator(literal string, base int) int {
+	isDigitLike := func(i int) bool {
+		if i < 0 || i >= len(literal) {
+			return false
+		}
+		if base != 10 && i == 1 {
+			return true
+		}
+		ch := lower(rune(literal[i]))
+		return isDigit(ch) || base == 16 && 'a' <= ch && ch <= 'f'
+	}
+	for i := 0; i < len(literal); i++ {
+		if literal[i] == '_' && (!isDigitLike(i-1) || !isDigitLike(i+1)) {
+			return i
+		}
+	}
+	return -1
+}
+
+func litName(prefix rune) string {
+	switch prefix {
+	case 'x':
+		return "hexadecimal"
+	case 'o':
+		return "octal"
+	default:
+		return "binary"
+	}
+}
+
+func lower(ch rune) rune {
+	return ('a' - 'A') | ch
 }
 
 func (l *Lexer) readString() string {
@@ -148,7 +347,7 @@ func (l *Lexer) readString() string {
 	return l.input[startPos:l.position]
 }
 
-func isDigit(ch byte) bool {
+func isDigit(ch rune) bool {
 	return '0' <= ch && ch <= '9'
 }
 
@@ -158,6 +357,56 @@ func (l *Lexer) skipWhitespace() {
 	}
 }
 
+func (l *Lexer) skipWhitespaceAndComments() {
+	l.skipWhitespace()
+	for l.ch == '/' && l.peakChar() == '/' {
+		l.readComment()
+		l.skipWhitespace()
+	}
+}
+
+func (l *Lexer) readComment() {
+	pos := l.pos()
+	startPos := l.position
+	for l.ch != '\n' && l.ch != 0 {
+		l.readChar()
+	}
+	text := strings.TrimRight(l.input[startPos:l.position], " \t\r")
+	l.comments = append(l.comments, Comment{Pos: pos, Text: text})
+}
+
 func (l *Lexer) Input() string {
 	return l.input
 }
+
+// Errors returns the problems found while tokenizing so far, each prefixed
+// with the line:column it was found at.
+func (l *Lexer) Errors() []string {
+	return l.errors
+}
+
+// ErrorPositions returns where each of Errors was found.
+func (l *Lexer) ErrorPositions() []token.Position {
+	return l.errorPos
+}
+
+// Comments returns the comments read so far, in source order.
+func (l *Lexer) Comments() []Comment {
+	return l.comments
+}
+
+func (l *Lexer) pos() token.Position {
+	return token.Position{File: l.file, Line: l.line, Column: l.column}
+}
+
+// invalidChar reports whether the current char comes from a byte sequence
+// that is not valid UTF-8.
+func (l *Lexer) invalidChar() bool {
+	return l.ch == utf8.RuneError && l.width == 1
+}
+
+func (l *Lexer) addError(pos token.Position, format string, a ...any) {
+	msg := fmt.Sprintf("%s: %s", pos, fmt.Sprintf(format, a...))
+	l.errors = append(l.errors, msg)
+	l.errorPos = append(l.errorPos, pos)
+}
@@ -147,3 +147,325 @@ null;
 		}
 	}
 }
+
+func TestUnicodeIdentifiers(t *testing.T) {
+	input := `let café = "☕"; x1 + ñandú_2; 😀 日本語`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.LET, "let"},
+		{token.IDENTIFIER, "café"},
+		{token.ASSIGN, "="},
+		{token.STRING, "☕"},
+		{token.SEMICOLON, ";"},
+		{token.IDENTIFIER, "x1"},
+		{token.PLUS, "+"},
+		{token.IDENTIFIER, "ñandú_2"},
+		{token.SEMICOLON, ";"},
+		{token.ILLEGAL, "😀"},
+		{token.IDENTIFIER, "日本語"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+
+	if len(l.Errors()) != 1 || l.Errors()[0] != "1:31: unexpected character '😀'" {
+		t.Fatalf("unexpected lexer errors. got=%q", l.Errors())
+	}
+}
+
+func TestInvalidUTF8(t *testing.T) {
+	input := "let a = 1;\nlet \xff = 2;"
+
+	l := New(input)
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+	}
+
+	errors := l.Errors()
+	if len(errors) != 1 {
+		t.Fatalf("expected 1 error. got=%q", errors)
+	}
+	if errors[0] != "2:5: invalid UTF-8 encoding" {
+		t.Fatalf("wrong error. got=%q", errors[0])
+	}
+}
+
+func TestTokenPositions(t *testing.T) {
+	input := "let ä = 5;\n  ä + 10"
+
+	tests := []struct {
+		expectedLiteral string
+		expectedPos     token.Position
+	}{
+		{"let", token.Position{Line: 1, Column: 1}},
+		{"ä", token.Position{Line: 1, Column: 5}},
+		{"=", token.Position{Line: 1, Column: 7}},
+		{"5", token.Position{Line: 1, Column: 9}},
+		{";", token.Position{Line: 1, Column: 10}},
+		{"ä", token.Position{Line: 2, Column: 3}},
+		{"+", token.Position{Line: 2, Column: 5}},
+		{"10", token.Position{Line: 2, Column: 7}},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+		if tok.Pos != tt.expectedPos {
+			t.Fatalf("tests[%d] - position wrong. expected=%s, got=%s", i, tt.expectedPos, tok.Pos)
+		}
+	}
+}
+
+func TestNumericLiterals(t *testing.T) {
+	input := `0xFF 0Xab_cd 0o17 0b1010 1_000_000 0 3.14 1_000.000_1 2e10 1.5E-3 1..5`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.INT, "0xFF"},
+		{token.INT, "0Xab_cd"},
+		{token.INT, "0o17"},
+		{token.INT, "0b1010"},
+		{token.INT, "1_000_000"},
+		{token.INT, "0"},
+		{token.FLOAT, "3.14"},
+		{token.FLOAT, "1_000.000_1"},
+		{token.FLOAT, "2e10"},
+		{token.FLOAT, "1.5E-3"},
+		{token.INT, "1"},
+		{token.DOTDOT, ".."},
+		{token.INT, "5"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestMalformedNumericLiterals(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{"0x", `1:1: hexadecimal literal has no digits: "0x"`},
+		{"0b", `1:1: binary literal has no digits: "0b"`},
+		{"0b102", `1:1: invalid digit '2' in binary literal: "0b102"`},
+		{"0o78", `1:1: invalid digit '8' in octal literal: "0o78"`},
+		{"0x1.5", `1:1: invalid radix point in hexadecimal literal: "0x1"`},
+		{"1__0", `1:1: '_' must separate successive digits: "1__0"`},
+		{"100_", `1:1: '_' must separate successive digits: "100_"`},
+		{"1_.5", `1:1: '_' must separate successive digits: "1_.5"`},
+		{"  1e+", `1:3: exponent has no digits: "1e+"`},
+		{"017", `1:1: invalid leading zero in decimal literal (use 0o for octal): "017"`},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+		tok := l.NextToken()
+
+		if tok.Type != token.ILLEGAL {
+			t.Errorf("%q - tokentype wrong. expected=ILLEGAL, got=%q", tt.input, tok.Type)
+		}
+		if len(l.Errors()) != 1 || l.Errors()[0] != tt.expectedError {
+			t.Errorf("%q - wrong errors. expected=%q, got=%q", tt.input, tt.expectedError, l.Errors())
+		}
+	}
+}
+
+func TestMatchTokens(t *testing.T) {
+	input := `match x { [a, ...b] => a, _ => 0 }`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.MATCH, "match"},
+		{token.IDENTIFIER, "x"},
+		{token.LBRACE, "{"},
+		{token.LBRACKET, "["},
+		{token.IDENTIFIER, "a"},
+		{token.COMMA, ","},
+		{token.ELLIPSIS, "..."},
+		{token.IDENTIFIER, "b"},
+		{token.RBRACKET, "]"},
+		{token.ARROW, "=>"},
+		{token.IDENTIFIER, "a"},
+		{token.COMMA, ","},
+		{token.IDENTIFIER, "_"},
+		{token.ARROW, "=>"},
+		{token.INT, "0"},
+		{token.RBRACE, "}"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestSetTokens(t *testing.T) {
+	input := `#{1} | #{2} & #{} - (3,)`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.SET_LBRACE, "#{"},
+		{token.INT, "1"},
+		{token.RBRACE, "}"},
+		{token.PIPE, "|"},
+		{token.SET_LBRACE, "#{"},
+		{token.INT, "2"},
+		{token.RBRACE, "}"},
+		{token.AMPERSAND, "&"},
+		{token.SET_LBRACE, "#{"},
+		{token.RBRACE, "}"},
+		{token.MINUS, "-"},
+		{token.LPAREN, "("},
+		{token.INT, "3"},
+		{token.COMMA, ","},
+		{token.RPAREN, ")"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+	if len(l.Errors()) != 0 {
+		t.Errorf("unexpected lexer errors: %v", l.Errors())
+	}
+
+	l = New("# x")
+	if tok := l.NextToken(); tok.Type != token.ILLEGAL {
+		t.Errorf("lone # should be ILLEGAL, got=%q", tok.Type)
+	}
+	if len(l.Errors()) != 1 {
+		t.Errorf("expected one lexer error for lone #, got=%v", l.Errors())
+	}
+}
+
+func TestComments(t *testing.T) {
+	input := "// header  \nlet x = 10 / 2; // half\n//\nx"
+
+	expected := []token.TokenType{
+		token.LET, token.IDENTIFIER, token.ASSIGN, token.INT, token.SLASH,
+		token.INT, token.SEMICOLON, token.IDENTIFIER, token.EOF,
+	}
+
+	l := New(input)
+
+	for i, tt := range expected {
+		tok := l.NextToken()
+		if tok.Type != tt {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, tt, tok.Type)
+		}
+	}
+
+	comments := []Comment{
+		{Pos: token.Position{Line: 1, Column: 1}, Text: "// header"},
+		{Pos: token.Position{Line: 2, Column: 17}, Text: "// half"},
+		{Pos: token.Position{Line: 3, Column: 1}, Text: "//"},
+	}
+	if len(l.Comments()) != len(comments) {
+		t.Fatalf("wrong number of comments. expected=%d, got=%d", len(comments), len(l.Comments()))
+	}
+	for i, c := range comments {
+		if l.Comments()[i] != c {
+			t.Errorf("comments[%d] wrong. expected=%+v, got=%+v", i, c, l.Comments()[i])
+		}
+	}
+}
+
+func TestRangeTokens(t *testing.T) {
+	input := `for (i in 0..10) { [...xs, 1..=n, 1.5] }`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.FOR, "for"},
+		{token.LPAREN, "("},
+		{token.IDENTIFIER, "i"},
+		{token.IDENTIFIER, "in"},
+		{token.INT, "0"},
+		{token.DOTDOT, ".."},
+		{token.INT, "10"},
+		{token.RPAREN, ")"},
+		{token.LBRACE, "{"},
+		{token.LBRACKET, "["},
+		{token.ELLIPSIS, "..."},
+		{token.IDENTIFIER, "xs"},
+		{token.COMMA, ","},
+		{token.INT, "1"},
+		{token.DOTDOT_EQ, "..="},
+		{token.IDENTIFIER, "n"},
+		{token.COMMA, ","},
+		{token.FLOAT, "1.5"},
+		{token.RBRACKET, "]"},
+		{token.RBRACE, "}"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+	if len(l.Errors()) != 0 {
+		t.Errorf("unexpected lexer errors: %v", l.Errors())
+	}
+}
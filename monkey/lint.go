@@ -0,0 +1,63 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"monkey/lint"
+	"monkey/stdlib"
+	"os"
+	"strings"
+)
+
+// lintFiles reports the findings of the linter in each file and fails if
+// there are any.
+func lintFiles(args []string) int {
+	flags := flag.NewFlagSet("lint", flag.ContinueOnError)
+	disable := flags.String("disable", "", "comma-separated list of rules not to report")
+	flags.SetOutput(os.Stderr)
+	flags.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	if err := flags.Parse(args); err != nil || flags.NArg() == 0 {
+		if err == nil {
+			fmt.Fprint(os.Stderr, usage)
+		}
+		return 2
+	}
+
+	config := lint.Config{Env: stdlib.Prelude()}
+	if *disable != "" {
+		for _, rule := range strings.Split(*disable, ",") {
+			if !knownRule(rule) {
+				fmt.Fprintf(os.Stderr, "unknown rule %s, expected one of %s\n", rule, strings.Join(lint.Rules, ", "))
+				return 2
+			}
+			config.Disabled = append(config.Disabled, rule)
+		}
+	}
+
+	status := 0
+	for _, file := range flags.Args() {
+		source, err := os.ReadFile(file)
+		if err == nil {
+			var findings []lint.Finding
+			findings, err = lint.Source(source, config)
+			for _, finding := range findings {
+				fmt.Printf("%s:%s\n", file, finding)
+				status = 1
+			}
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", file, err)
+			status = 1
+		}
+	}
+	return status
+}
+
+func knownRule(rule string) bool {
+	for _, known := range lint.Rules {
+		if rule == known {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,593 @@
+// Package lint reports likely mistakes in Monkey programs without running
+// them.
+//
+// Scopes follow the evaluator: function and macro bodies, match arms and
+// catch blocks have their own, while the blocks of if and try expressions
+// share the scope around them. A name used directly in a scope must be bound
+// before it is used. A name used inside a function only has to be bound
+// somewhere around it, since the function may run after the binding is made.
+package lint
+
+import (
+	"fmt"
+	"monkey/ast"
+	"monkey/evaluator"
+	"monkey/lexer"
+	"monkey/object"
+	"monkey/parser"
+	"monkey/token"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The IDs of the rules a Finding can come from.
+const (
+	Undefined       = "undefined"
+	UnusedVariable  = "unused-variable"
+	UnusedParameter = "unused-parameter"
+	ShadowedBuiltin = "shadowed-builtin"
+	Unreachable     = "unreachable"
+	ArgumentCount   = "argument-count"
+	DuplicateKey    = "duplicate-key"
+)
+
+// Rules lists every rule ID.
+var Rules = []string{
+	Undefined,
+	UnusedVariable,
+	UnusedParameter,
+	ShadowedBuiltin,
+	Unreachable,
+	ArgumentCount,
+	DuplicateKey,
+}
+
+// Finding is a problem found in a program.
+type Finding struct {
+	Pos     token.Position
+	Rule    string
+	Message string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s: %s (%s)", f.Pos, f.Message, f.Rule)
+}
+
+type Config struct {
+	// Env holds the names bound before the program runs, such as those of
+	// the standard library prelude. Builtin functions are always known.
+	Env *object.Environment
+
+	// Disabled lists the IDs of rules that are not reported.
+	Disabled []string
+}
+
+// Source lints a Monkey program. It fails if src does not parse.
+//
+// A comment of the form `// lint:ignore rule1,rule2` suppresses the findings
+// of the listed rules on its own line or, when the comment is alone on its
+// line, on the next one. Without a list it suppresses every rule.
+func Source(src []byte, config Config) ([]Finding, error) {
+	l := lexer.New(string(src))
+	p := parser.New(l)
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, fmt.Errorf("%s", strings.Join(p.Errors(), "\n"))
+	}
+
+	ignored := ignoreDirectives(src, l.Comments())
+	findings := []Finding{}
+	for _, finding := range Program(program, config) {
+		if !ignored.covers(finding) {
+			findings = append(findings, finding)
+		}
+	}
+	return findings, nil
+}
+
+// Program lints a parsed program. The findings are sorted by position.
+func Program(program *ast.Program, config Config) []Finding {
+	l := &linter{config: config}
+	l.scope = l.openScope(false)
+	l.scope.top = true
+	l.statements(program.Statements)
+	l.finish()
+
+	disabled := make(map[string]bool)
+	for _, rule := range config.Disabled {
+		disabled[rule] = true
+	}
+	findings := []Finding{}
+	for _, finding := range l.findings {
+		if !disabled[finding.Rule] {
+			findings = append(findings, finding)
+		}
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i].Pos, findings[j].Pos
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	return findings
+}
+
+type linter struct {
+	config   Config
+	scope    *scope
+	scopes   []*scope
+	pending  []reference
+	findings []Finding
+}
+
+type scope struct {
+	outer    *scope
+	function bool // the scope of a function or macro body
+	top      bool // the scope of the program, whose bindings may be used elsewhere
+	bindings []*binding
+}
+
+type binding struct {
+	name   *ast.Identifier
+	param  bool
+	params []ast.Expression // the parameters of the function in `let name = fn...`
+	used   bool
+}
+
+// reference is a use of a name inside a function that was not bound in the
+// function itself. It is resolved once the whole program has been seen.
+type reference struct {
+	name  *ast.Identifier
+	call  *ast.CallExpression // the call name is the callee of, if any
+	scope *scope              // where to continue looking
+}
+
+// lookup returns the last binding of name made so far in s.
+func (s *scope) lookup(name string) *binding {
+	for i := len(s.bindings) - 1; i >= 0; i-- {
+		if s.bindings[i].name.Value == name {
+			return s.bindings[i]
+		}
+	}
+	return nil
+}
+
+func (l *linter) report(pos token.Position, rule, format string, a ...any) {
+	l.findings = append(l.findings, Finding{Pos: pos, Rule: rule, Message: fmt.Sprintf(format, a...)})
+}
+
+func (l *linter) openScope(function bool) *scope {
+	s := &scope{outer: l.scope, function: function}
+	l.scopes = append(l.scopes, s)
+	return s
+}
+
+func (l *linter) declare(name *ast.Identifier, param bool) *binding {
+	if name.Value == "_" {
+		return nil
+	}
+	if evaluator.IsBuiltin(name.Value) {
+		l.report(name.Pos(), ShadowedBuiltin, "%s shadows the builtin function %s", name.Value, name.Value)
+	}
+	b := &binding{name: name, param: param}
+	l.scope.bindings = append(l.scope.bindings, b)
+	return b
+}
+
+// declarePattern binds the names in a let, parameter, match or catch
+// pattern and checks the expressions it compares against.
+func (l *linter) declarePattern(pattern ast.Expression, param bool) {
+	switch pattern := pattern.(type) {
+	case *ast.Identifier:
+		l.declare(pattern, param)
+	case *ast.ArrayPattern:
+		for _, element := range pattern.Elements {
+			l.declarePattern(element, param)
+		}
+		if pattern.Rest != nil {
+			l.declare(pattern.Rest, param)
+		}
+	case *ast.HashPattern:
+		for _, pair := range pattern.Pairs {
+			if _, ok := pair.Key.(*ast.Identifier); !ok {
+				l.expr(pair.Key)
+			}
+			l.declarePattern(pair.Value, param)
+		}
+		if pattern.Rest != nil {
+			l.declare(pattern.Rest, param)
+		}
+	default:
+		l.expr(pattern)
+	}
+}
+
+// use resolves a name as far as the innermost function around it, and
+// leaves the rest for finish.
+func (l *linter) use(name *ast.Identifier, call *ast.CallExpression) {
+	for s := l.scope; s != nil; s = s.outer {
+		if b := s.lookup(name.Value); b != nil {
+			b.used = true
+			l.checkCall(call, b.params, name.Value)
+			return
+		}
+		if s.function {
+			l.pending = append(l.pending, reference{name: name, call: call, scope: s.outer})
+			return
+		}
+	}
+	l.useGlobal(name, call)
+}
+
+func (l *linter) useGlobal(name *ast.Identifier, call *ast.CallExpression) {
+	if l.config.Env != nil {
+		if val, ok := l.config.Env.Get(name.Value); ok {
+			if fn, ok := val.(*object.Function); ok {
+				l.checkCall(call, fn.Parameters, name.Value)
+			}
+			return
+		}
+	}
+	if !evaluator.IsBuiltin(name.Value) {
+		l.report(name.Pos(), Undefined, "undefined: %s", name.Value)
+	}
+}
+
+// checkCall reports a call to name that passes a different number of
+// arguments than params. It does nothing if call or params is nil.
+func (l *linter) checkCall(call *ast.CallExpression, params []ast.Expression, name string) {
+	if call == nil || params == nil {
+		return
+	}
+	for _, arg := range call.Arguments {
+		if _, ok := arg.(*ast.SpreadExpression); ok {
+			// The number of arguments is only known at run time.
+			return
+		}
+	}
+	if len(call.Arguments) != len(params) {
+		l.report(call.Function.Pos(), ArgumentCount, "wrong number of arguments to %s. got=%d, want=%d", name, len(call.Arguments), len(params))
+	}
+}
+
+// finish resolves the names used inside functions against every binding
+// around them, then reports the bindings that were never used.
+func (l *linter) finish() {
+	for _, ref := range l.pending {
+		l.resolve(ref)
+	}
+
+	for _, s := range l.scopes {
+		if s.top {
+			continue
+		}
+		for _, b := range s.bindings {
+			if b.used || strings.HasPrefix(b.name.Value, "_") {
+				continue
+			}
+			if b.param {
+				l.report(b.name.Pos(), UnusedParameter, "unused parameter %s", b.name.Value)
+			} else {
+				l.report(b.name.Pos(), UnusedVariable, "unused variable %s", b.name.Value)
+			}
+		}
+	}
+}
+
+func (l *linter) resolve(ref reference) {
+	for s := ref.scope; s != nil; s = s.outer {
+		var found []*binding
+		for _, b := range s.bindings {
+			if b.name.Value == ref.name.Value {
+				b.used = true
+				found = append(found, b)
+			}
+		}
+		if len(found) == 1 {
+			l.checkCall(ref.call, found[0].params, ref.name.Value)
+		}
+		if len(found) > 0 {
+			return
+		}
+	}
+	l.useGlobal(ref.name, ref.call)
+}
+
+// ================================================
+// ===================STATEMENTS===================
+// ================================================
+
+func (l *linter) statements(statements []ast.Statement) {
+	jumped, reported := false, false
+	for _, statement := range statements {
+		if jumped && !reported {
+			l.report(statement.Pos(), Unreachable, "unreachable code")
+			reported = true
+		}
+		l.statement(statement)
+
+		switch statement.(type) {
+		case *ast.ReturnStatement, *ast.ThrowStatement:
+			jumped = true
+		}
+	}
+}
+
+func (l *linter) statement(statement ast.Statement) {
+	switch statement := statement.(type) {
+	case *ast.LetStatement:
+		l.let(statement)
+	case *ast.ExportStatement:
+		l.let(statement.Statement)
+	case *ast.ReturnStatement:
+		if statement.ReturnValue != nil {
+			l.expr(statement.ReturnValue)
+		}
+	case *ast.ThrowStatement:
+		l.expr(statement.Value)
+	case *ast.YieldStatement:
+		l.expr(statement.Value)
+	case *ast.ImportStatement:
+		name := statement.Alias
+		if name == nil {
+			module := strings.TrimSuffix(path.Base(statement.Path.Value), evaluator.ModuleExtension)
+			name = &ast.Identifier{Token: statement.Token, Value: module}
+		}
+		l.declare(name, false)
+	case *ast.ExpressionStatement:
+		l.expr(statement.Expression)
+	case *ast.BlockStatement:
+		l.block(statement)
+	}
+}
+
+func (l *linter) let(ls *ast.LetStatement) {
+	l.expr(ls.Value)
+	if ls.Pattern != nil {
+		l.declarePattern(ls.Pattern, false)
+		return
+	}
+	if b := l.declare(ls.Name, false); b != nil {
+		if fn, ok := ls.Value.(*ast.FunctionLiteral); ok {
+			b.params = fn.Parameters
+		}
+	}
+}
+
+func (l *linter) block(block *ast.BlockStatement) {
+	if block != nil {
+		l.statements(block.Statements)
+	}
+}
+
+// ================================================
+// ==================EXPRESSIONS===================
+// ================================================
+
+func (l *linter) expr(expr ast.Expression) {
+	switch expr := expr.(type) {
+	case *ast.Identifier:
+		l.use(expr, nil)
+	case *ast.PrefixExpression:
+		l.expr(expr.Right)
+	case *ast.InfixExpression:
+		l.expr(expr.Left)
+		l.expr(expr.Right)
+	case *ast.IfExpression:
+		l.expr(expr.Condition)
+		l.block(expr.Consequence)
+		l.block(expr.Alternative)
+	case *ast.TryExpression:
+		l.block(expr.Block)
+		if expr.Catch != nil {
+			l.inScope(false, func() {
+				if expr.CatchParam != nil {
+					l.declarePattern(expr.CatchParam, false)
+				}
+				l.block(expr.Catch)
+			})
+		}
+		l.block(expr.Finally)
+	case *ast.MatchExpression:
+		l.expr(expr.Subject)
+		for _, arm := range expr.Arms {
+			l.inScope(false, func() {
+				l.declarePattern(arm.Pattern, false)
+				if arm.Guard != nil {
+					l.expr(arm.Guard)
+				}
+				l.expr(arm.Body)
+			})
+		}
+	case *ast.ForExpression:
+		l.expr(expr.Iterable)
+		l.inScope(false, func() {
+			l.declarePattern(expr.Pattern, false)
+			l.block(expr.Body)
+		})
+	case *ast.FunctionLiteral:
+		l.inScope(true, func() {
+			for _, param := range expr.Parameters {
+				l.declarePattern(param, true)
+			}
+			l.block(expr.Body)
+		})
+	case *ast.MacroLiteral:
+		l.inScope(true, func() {
+			for _, param := range expr.Parameters {
+				l.declare(param, true)
+			}
+			l.block(expr.Body)
+		})
+	case *ast.CallExpression:
+		l.call(expr)
+	case *ast.ArrayLiteral:
+		for _, element := range expr.Elements {
+			l.expr(element)
+		}
+	case *ast.SetLiteral:
+		for _, element := range expr.Elements {
+			l.expr(element)
+		}
+	case *ast.SpreadExpression:
+		l.expr(expr.Value)
+	case *ast.SpawnExpression:
+		l.expr(expr.Call)
+	case *ast.AwaitExpression:
+		l.expr(expr.Value)
+	case *ast.TupleLiteral:
+		for _, element := range expr.Elements {
+			l.expr(element)
+		}
+	case *ast.HashLiteral:
+		l.hash(expr)
+	case *ast.IndexExpression:
+		l.expr(expr.Left)
+		l.expr(expr.Index)
+	case *ast.SliceExpression:
+		l.expr(expr.Left)
+		l.expr(expr.Start)
+		l.expr(expr.End)
+		l.expr(expr.Step)
+	case *ast.MemberExpression:
+		l.expr(expr.Object)
+	case *ast.AssignExpression:
+		l.expr(expr.Target)
+		l.expr(expr.Value)
+	}
+}
+
+func (l *linter) inScope(function bool, f func()) {
+	outer := l.scope
+	l.scope = l.openScope(function)
+	f()
+	l.scope = outer
+}
+
+func (l *linter) call(call *ast.CallExpression) {
+	if ident, ok := call.Function.(*ast.Identifier); ok {
+		if ident.Value == "quote" {
+			l.quoted(call)
+			return
+		}
+		l.use(ident, call)
+	} else {
+		l.expr(call.Function)
+	}
+	for _, arg := range call.Arguments {
+		l.expr(arg)
+	}
+}
+
+// quoted checks the code inside quote(...), where only the arguments of
+// unquote calls are evaluated.
+func (l *linter) quoted(call *ast.CallExpression) {
+	for _, arg := range call.Arguments {
+		ast.Inspect(arg, func(node ast.Node) bool {
+			unquote, ok := node.(*ast.CallExpression)
+			if !ok {
+				return true
+			}
+			if ident, ok := unquote.Function.(*ast.Identifier); !ok || ident.Value != "unquote" {
+				return true
+			}
+			for _, arg := range unquote.Arguments {
+				l.expr(arg)
+			}
+			return false
+		})
+	}
+}
+
+// hash checks a hash literal for keys that are certain to be equal.
+func (l *linter) hash(hl *ast.HashLiteral) {
+	seen := make(map[string]bool)
+	for _, key := range hl.Keys() {
+		l.expr(key)
+		l.expr(hl.Pairs[key])
+
+		id, display := constantKey(key)
+		if id == "" {
+			continue
+		}
+		if seen[id] {
+			l.report(key.Pos(), DuplicateKey, "duplicate key %s in hash literal", display)
+		}
+		seen[id] = true
+	}
+}
+
+// constantKey identifies keys whose value is known before the program runs.
+// It returns "" for any other key.
+func constantKey(key ast.Expression) (id, display string) {
+	switch key := key.(type) {
+	case *ast.StringLiteral:
+		return "string:" + key.Value, strconv.Quote(key.Value)
+	case *ast.IntegerLiteral:
+		return "integer:" + strconv.FormatInt(key.Value, 10), key.String()
+	case *ast.Boolean:
+		return "boolean:" + key.String(), key.String()
+	}
+	return "", ""
+}
+
+// ================================================
+// ==================SUPPRESSION===================
+// ================================================
+
+const ignoreDirective = "lint:ignore"
+
+// ignores maps a source line to the rules suppressed on it, where "*"
+// stands for all of them.
+type ignores map[int][]string
+
+func ignoreDirectives(src []byte, comments []lexer.Comment) ignores {
+	lines := strings.Split(string(src), "\n")
+	ignored := make(ignores)
+	for _, comment := range comments {
+		text := strings.TrimSpace(strings.TrimPrefix(comment.Text, "//"))
+		if !strings.HasPrefix(text, ignoreDirective) {
+			continue
+		}
+		text = strings.TrimPrefix(text, ignoreDirective)
+		if text != "" && text[0] != ' ' && text[0] != '\t' {
+			continue
+		}
+
+		rules := []string{}
+		for _, rule := range strings.Split(text, ",") {
+			if rule = strings.TrimSpace(rule); rule != "" {
+				rules = append(rules, rule)
+			}
+		}
+
+		line := comment.Pos.Line
+		if alone(lines[line-1], comment.Pos.Column) {
+			line++
+		}
+		if len(rules) == 0 {
+			rules = []string{"*"}
+		}
+		ignored[line] = append(ignored[line], rules...)
+	}
+	return ignored
+}
+
+// alone reports whether nothing but whitespace comes before the column of a
+// comment on its line.
+func alone(line string, column int) bool {
+	runes := []rune(line)
+	return strings.TrimSpace(string(runes[:column-1])) == ""
+}
+
+func (ig ignores) covers(f Finding) bool {
+	for _, rule := range ig[f.Pos.Line] {
+		if rule == "*" || rule == f.Rule {
+			return true
+		}
+	}
+	return false
+}
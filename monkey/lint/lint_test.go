@@ -0,0 +1,152 @@
+package lint
+
+import (
+	"monkey/stdlib"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSource(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		// undefined
+		{"let a = 1; a + b", []string{"1:16: undefined: b (undefined)"}},
+		{"x; let x = 1;", []string{"1:1: undefined: x (undefined)"}},
+		{"let x = x;", []string{"1:9: undefined: x (undefined)"}},
+		{"let f = fn() { g() }; let g = fn() { 1 }; f()", nil},
+		{"let fact = fn(n) { if (n < 2) { 1 } else { n * fact(n - 1) } };", nil},
+		{"if (true) { let y = 1; }; y", nil},
+		{"len([1]); print(push([], 1))", nil},
+		{"let f = fn() { missing }", []string{"1:16: undefined: missing (undefined)"}},
+		{"match 1 { x => x }; x", []string{"1:21: undefined: x (undefined)"}},
+		{"try { 1 } catch (e) { e }; e", []string{"1:28: undefined: e (undefined)"}},
+		{"for (x in 0..3) { print(x) }; x", []string{"1:31: undefined: x (undefined)"}},
+		{"let g = fn(n) { yield n; yield missing }; g(1)", []string{"1:32: undefined: missing (undefined)"}},
+		{"let f = fn(a) { a }; await spawn f(missing)", []string{"1:36: undefined: missing (undefined)"}},
+		{"import \"lib/strings\"; import \"other.mk\" as o; strings; o", nil},
+		{"let m = macro(a) { quote(unquote(a) + later) }; m(1)", nil},
+		{"let m = macro(a) { quote(unquote(b)) }", []string{
+			"1:15: unused parameter a (unused-parameter)",
+			"1:34: undefined: b (undefined)",
+		}},
+
+		// unused
+		{"let f = fn(a, b) { let c = a; a }; f(1, 2)", []string{
+			"1:15: unused parameter b (unused-parameter)",
+			"1:24: unused variable c (unused-variable)",
+		}},
+		{"let f = fn(_, _b) { let [x, ...rest_] = [1]; rest_ }; f(1, 2)", []string{
+			"1:26: unused variable x (unused-variable)",
+		}},
+		{"let f = fn(a) { fn() { a } }; f(1)", nil},
+		{"let f = fn() { let a = 1; let a = 2; a }; f()", []string{"1:20: unused variable a (unused-variable)"}},
+		{"let unused = 1;", nil},
+		{"match [1] { [h, ...t] if h > 0 => 1, _ => 0 }", []string{"1:20: unused variable t (unused-variable)"}},
+		{"for ([i, x] in [[0, 1]]) { print(x) }; for (_ in 0..3) {}", []string{"1:7: unused variable i (unused-variable)"}},
+		{"let f = fn({name, age: years}) { name }; f({})", []string{"1:24: unused parameter years (unused-parameter)"}},
+
+		// shadowed builtins
+		{"let len = fn(x) { x };", []string{"1:5: len shadows the builtin function len (shadowed-builtin)"}},
+		{"let f = fn(first) { first }; f(1)", []string{"1:12: first shadows the builtin function first (shadowed-builtin)"}},
+
+		// unreachable code
+		{"let f = fn() { return 1; print(2); 3 }; f()", []string{"1:26: unreachable code (unreachable)"}},
+		{"let f = fn() { throw 1; return 2; }; f()", []string{"1:25: unreachable code (unreachable)"}},
+		{"let f = fn(x) { if (x) { return 1; } 2 }; f(1)", nil},
+
+		// argument counts
+		{"let add = fn(a, b) { a + b }; add(1)", []string{"1:31: wrong number of arguments to add. got=1, want=2 (argument-count)"}},
+		{"let f = fn() { add(1, 2, 3) }; let add = fn(a, b) { a + b };", []string{
+			"1:16: wrong number of arguments to add. got=3, want=2 (argument-count)",
+		}},
+		{"let add = fn(a, b) { a + b }; let add = fn(a) { a }; let f = fn() { add(1) };", nil},
+		{"let f = fn(g) { g(1, 2, 3) }; f(fn(a) { a })", nil},
+		{"let add = fn(a, b) { a + b }; add(...[1, 2]); add(1, ...[2])", nil},
+		{"reduce([1], 0)", []string{"1:1: wrong number of arguments to reduce. got=2, want=3 (argument-count)"}},
+
+		// duplicate keys
+		{`{"a": 1, "b": 2, "a": 3}`, []string{`1:18: duplicate key "a" in hash literal (duplicate-key)`}},
+		{"{1: 1, 0x1: 2, true: 3, true: 4}", []string{
+			"1:8: duplicate key 0x1 in hash literal (duplicate-key)",
+			"1:25: duplicate key true in hash literal (duplicate-key)",
+		}},
+		{`let a = "x"; {a: 1, "a": 2, 2: 3}`, nil},
+
+		// suppression
+		{"let len = 1; // lint:ignore shadowed-builtin", nil},
+		{"let len = 1; // lint:ignore unused-variable", []string{"1:5: len shadows the builtin function len (shadowed-builtin)"}},
+		{"// lint:ignore undefined,shadowed-builtin\nlet len = a;", nil},
+		{"// lint:ignore\nlet len = a;\nb", []string{"3:1: undefined: b (undefined)"}},
+		{"a; // lint:ignore\nb", []string{"2:1: undefined: b (undefined)"}},
+		{"a; // lint:ignored", []string{"1:1: undefined: a (undefined)"}},
+	}
+
+	config := Config{Env: stdlib.Prelude()}
+	for _, tt := range tests {
+		findings, err := Source([]byte(tt.input), config)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %s", tt.input, err)
+			continue
+		}
+		got := []string{}
+		for _, finding := range findings {
+			got = append(got, finding.String())
+		}
+		if strings.Join(got, "\n") != strings.Join(tt.expected, "\n") {
+			t.Errorf("%q: wrong findings.\nexpected:\n%s\ngot:\n%s", tt.input, strings.Join(tt.expected, "\n"), strings.Join(got, "\n"))
+		}
+	}
+}
+
+func TestDisabledRules(t *testing.T) {
+	input := "let len = fn(a) { b };"
+
+	findings, err := Source([]byte(input), Config{Disabled: []string{UnusedParameter, Undefined}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(findings) != 1 || findings[0].Rule != ShadowedBuiltin {
+		t.Errorf("expected only a shadowed-builtin finding. got=%v", findings)
+	}
+}
+
+func TestWithoutEnvironment(t *testing.T) {
+	findings, err := Source([]byte("map([1], fn(x) { x })"), Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(findings) != 1 || findings[0].String() != "1:1: undefined: map (undefined)" {
+		t.Errorf("wrong findings. got=%v", findings)
+	}
+}
+
+func TestSourceErrors(t *testing.T) {
+	_, err := Source([]byte("let = 1;"), Config{})
+	if err == nil || !strings.HasPrefix(err.Error(), "expected next token to be IDENT") {
+		t.Errorf("expected a parse error. got=%v", err)
+	}
+}
+
+func TestStandardLibraryIsClean(t *testing.T) {
+	files, err := filepath.Glob("../stdlib/*.mk")
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no standard library sources found: %v", err)
+	}
+	for _, file := range files {
+		source, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		findings, err := Source(source, Config{Env: stdlib.Prelude()})
+		if err != nil {
+			t.Fatalf("%s: %s", file, err)
+		}
+		for _, finding := range findings {
+			t.Errorf("%s:%s", file, finding)
+		}
+	}
+}
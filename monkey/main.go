@@ -2,12 +2,61 @@ package main
 
 import (
 	"fmt"
+	"monkey/evaluator"
 	"monkey/repl"
+	"monkey/stdlib"
 	"os"
 	"os/user"
+	"path/filepath"
 )
 
+const usage = `usage:
+	monkey                 start the REPL
+	monkey [run] [-strict] [-files dir [-readonly]] file.mk
+	                       run a script; -strict makes out-of-range
+	                       indexing an error instead of null, -files
+	                       lets it use the files under dir, and
+	                       -readonly only lets it read them
+	monkey ast [--json] file.mk
+	                       print the syntax tree of a script
+	monkey fmt [-w] files...
+	                       format scripts, or with -w rewrite them in place
+	monkey lint [-disable rule,...] files...
+	                       report likely mistakes in scripts
+
+Modules named in import statements are also looked up in the
+directories listed in the MONKEYPATH environment variable.
+`
+
 func main() {
+	evaluator.Loader.NewEnvironment = stdlib.NewEnvironment
+	if path := os.Getenv("MONKEYPATH"); path != "" {
+		evaluator.Loader.SearchPaths = filepath.SplitList(path)
+	}
+
+	args := os.Args[1:]
+	if len(args) == 0 {
+		startRepl()
+		return
+	}
+
+	switch args[0] {
+	case "run":
+		os.Exit(run(args[1:]))
+	case "ast":
+		os.Exit(dumpAST(args[1:]))
+	case "fmt":
+		os.Exit(formatFiles(args[1:]))
+	case "lint":
+		os.Exit(lintFiles(args[1:]))
+	case "help", "-h", "-help", "--help":
+		fmt.Print(usage)
+	default:
+		os.Exit(run(args))
+	}
+}
+
+func startRepl() {
 	user, err := user.Current()
 	if err != nil {
 		panic(err)
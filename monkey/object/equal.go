@@ -0,0 +1,111 @@
+package object
+
+// Equal reports whether a and b are structurally equal. Numbers compare by
+// value, with integers promoted when compared against floats; strings,
+// booleans and nulls compare by value; arrays and tuples compare
+// element-wise, sets compare by their elements regardless of order, and
+// hashes compare by their set of keys and the values stored under them.
+// Ranges are equal when they hold the same integers.
+// Every other object is only equal to itself.
+//
+// Arrays and hashes may contain themselves, so a pair of containers that is
+// already being compared further up is assumed equal: if no difference is
+// found elsewhere, the two structures unfold into the same infinite value.
+func Equal(a, b Object) bool {
+	return equal(a, b, map[[2]Object]bool{})
+}
+
+func equal(a, b Object, seen map[[2]Object]bool) bool {
+	if a == b {
+		return true
+	}
+
+	switch a := a.(type) {
+	case *Integer:
+		switch b := b.(type) {
+		case *Integer:
+			return a.Value == b.Value
+		case *Float:
+			return float64(a.Value) == b.Value
+		}
+		return false
+	case *Float:
+		switch b := b.(type) {
+		case *Integer:
+			return a.Value == float64(b.Value)
+		case *Float:
+			return a.Value == b.Value
+		}
+		return false
+	case *String:
+		b, ok := b.(*String)
+		return ok && a.Value == b.Value
+	case *Boolean:
+		b, ok := b.(*Boolean)
+		return ok && a.Value == b.Value
+	case *Null:
+		_, ok := b.(*Null)
+		return ok
+	case *Array:
+		b, ok := b.(*Array)
+		if !ok || len(a.Elements) != len(b.Elements) {
+			return false
+		}
+		visit := [2]Object{a, b}
+		if seen[visit] {
+			return true
+		}
+		seen[visit] = true
+		bElements := b.Snapshot()
+		for i, el := range a.Snapshot() {
+			if !equal(el, bElements[i], seen) {
+				return false
+			}
+		}
+		return true
+	case *Tuple:
+		b, ok := b.(*Tuple)
+		if !ok || len(a.Elements) != len(b.Elements) {
+			return false
+		}
+		for i, el := range a.Elements {
+			if !equal(el, b.Elements[i], seen) {
+				return false
+			}
+		}
+		return true
+	case *Set:
+		b, ok := b.(*Set)
+		if !ok || a.Len() != b.Len() {
+			return false
+		}
+		for _, el := range a.Elements() {
+			if !b.Contains(el.(Hashable)) {
+				return false
+			}
+		}
+		return true
+	case *Range:
+		b, ok := b.(*Range)
+		return ok && a.Len() == b.Len() && (a.Len() == 0 || a.Start == b.Start)
+	case *Hash:
+		b, ok := b.(*Hash)
+		if !ok || a.Len() != b.Len() {
+			return false
+		}
+		visit := [2]Object{a, b}
+		if seen[visit] {
+			return true
+		}
+		seen[visit] = true
+		for _, pair := range a.Pairs() {
+			value, ok := b.Get(pair.Key.(Hashable))
+			if !ok || !equal(pair.Value, value, seen) {
+				return false
+			}
+		}
+		return true
+	default:
+		return false
+	}
+}
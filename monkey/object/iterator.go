@@ -0,0 +1,150 @@
+package object
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// Iterable is implemented by the objects a for loop can run over. Iter
+// returns an iterator positioned at the first element.
+type Iterable interface {
+	Object
+	Iter() Iterator
+}
+
+// Iterator produces the elements of an iterable one at a time; Next returns
+// false once there are none left. An iterator is itself iterable, returning
+// itself from Iter, so running over it a second time yields nothing.
+//
+// Iterators that compute their elements, such as a lazy map, produce an
+// *Error as their next element if the computation fails. Whoever consumes
+// them should stop there and report it.
+type Iterator interface {
+	Iterable
+	Next() (Object, bool)
+}
+
+// NewIterator returns an iterator whose elements are produced by next. Once
+// next reports false it is not called again.
+func NewIterator(next func() (Object, bool)) Iterator {
+	return &funcIterator{next: next}
+}
+
+type funcIterator struct {
+	next func() (Object, bool)
+	done bool
+}
+
+func (it *funcIterator) Type() ObjectType { return ITERATOR_OBJ }
+func (it *funcIterator) Inspect() string  { return "<iterator>" }
+func (it *funcIterator) Iter() Iterator   { return it }
+func (it *funcIterator) Next() (Object, bool) {
+	if it.done {
+		return nil, false
+	}
+	obj, ok := it.next()
+	if !ok {
+		it.done = true
+	}
+	return obj, ok
+}
+
+// sliceIterator runs over a fixed list of elements.
+func sliceIterator(elements []Object) Iterator {
+	i := 0
+	return NewIterator(func() (Object, bool) {
+		if i >= len(elements) {
+			return nil, false
+		}
+		i++
+		return elements[i-1], true
+	})
+}
+
+// Iter runs over the elements of the array. Elements replaced while the
+// loop runs are seen if the loop has not passed them yet.
+func (a *Array) Iter() Iterator {
+	i := 0
+	return NewIterator(func() (Object, bool) {
+		if i >= len(a.Elements) {
+			return nil, false
+		}
+		i++
+		return a.Get(i - 1), true
+	})
+}
+
+func (t *Tuple) Iter() Iterator { return sliceIterator(t.Elements) }
+
+func (s *Set) Iter() Iterator { return sliceIterator(s.Elements()) }
+
+// Iter runs over the keys of the hash, in insertion order, as they were
+// when the loop started.
+func (h *Hash) Iter() Iterator {
+	pairs := h.Pairs()
+	keys := make([]Object, len(pairs))
+	for i, pair := range pairs {
+		keys[i] = pair.Key
+	}
+	return sliceIterator(keys)
+}
+
+// Iter runs over the runes of the string, each as a string of its own, like
+// indexing does.
+func (s *String) Iter() Iterator {
+	i := 0
+	return NewIterator(func() (Object, bool) {
+		if i >= len(s.Value) {
+			return nil, false
+		}
+		r, size := utf8.DecodeRuneInString(s.Value[i:])
+		i += size
+		return &String{Value: string(r)}, true
+	})
+}
+
+// Range is the sequence of integers from Start up to End, which is only
+// included when Inclusive is set. It is lazy: its elements are only made as
+// it is iterated, so a range costs the same no matter how long it is.
+type Range struct {
+	Start     int64
+	End       int64
+	Inclusive bool
+}
+
+func (r *Range) Type() ObjectType { return RANGE_OBJ }
+func (r *Range) Inspect() string {
+	if r.Inclusive {
+		return fmt.Sprintf("%d..=%d", r.Start, r.End)
+	}
+	return fmt.Sprintf("%d..%d", r.Start, r.End)
+}
+
+// Len returns the number of integers in the range.
+func (r *Range) Len() int64 {
+	switch {
+	case r.Inclusive && r.End >= r.Start:
+		return r.End - r.Start + 1
+	case !r.Inclusive && r.End > r.Start:
+		return r.End - r.Start
+	default:
+		return 0
+	}
+}
+
+// Contains reports whether n is one of the integers in the range.
+func (r *Range) Contains(n int64) bool {
+	return n >= r.Start && (n < r.End || r.Inclusive && n == r.End)
+}
+
+func (r *Range) Iter() Iterator {
+	next, left := r.Start, r.Len()
+	return NewIterator(func() (Object, bool) {
+		if left <= 0 {
+			return nil, false
+		}
+		left--
+		next++
+		return &Integer{Value: next - 1}, true
+	})
+}
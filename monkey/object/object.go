@@ -2,16 +2,22 @@ package object
 
 import (
 	"bytes"
+	"encoding/binary"
 	"fmt"
+	"hash"
 	"hash/fnv"
 	"monkey/ast"
+	"monkey/token"
+	"strconv"
 	"strings"
+	"sync"
 )
 
 type ObjectType string
 
 const (
 	INTEGER_OBJ      = "INTEGER"
+	FLOAT_OBJ        = "FLOAT"
 	STRING_OBJ       = "STRING"
 	BOOLEAN_OBJ      = "BOOLEAN"
 	NULL_OBJ         = "NULL"
@@ -21,6 +27,16 @@ const (
 	BUILTIN_OBJ      = "BUILTIN"
 	ARRAY_OBJ        = "ARRAY"
 	HASH_OBJ         = "HASH"
+	SET_OBJ          = "SET"
+	TUPLE_OBJ        = "TUPLE"
+	RANGE_OBJ        = "RANGE"
+	ITERATOR_OBJ     = "ITERATOR"
+	GENERATOR_OBJ    = "GENERATOR"
+	CHANNEL_OBJ      = "CHANNEL"
+	FUTURE_OBJ       = "FUTURE"
+	MODULE_OBJ       = "MODULE"
+	QUOTE_OBJ        = "QUOTE"
+	MACRO_OBJ        = "MACRO"
 )
 
 type Object interface {
@@ -33,7 +49,11 @@ type HashKey struct {
 	Value uint64
 }
 
+// Hashable is implemented by objects that can be used as hash keys. Arrays
+// and hashes implement it but are only usable when IsHashable reports true
+// for them.
 type Hashable interface {
+	Object
 	HashKey() HashKey
 }
 
@@ -48,6 +68,19 @@ func (i *Integer) HashKey() HashKey {
 
 }
 
+type Float struct {
+	Value float64
+}
+
+func (f *Float) Type() ObjectType { return FLOAT_OBJ }
+func (f *Float) Inspect() string {
+	s := strconv.FormatFloat(f.Value, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".eIN") {
+		s += ".0"
+	}
+	return s
+}
+
 type String struct {
 	Value string
 }
@@ -89,17 +122,78 @@ type ReturnValue struct {
 func (rv *ReturnValue) Type() ObjectType { return RETURN_VALUE_OBJ }
 func (rv *ReturnValue) Inspect() string  { return rv.Value.Inspect() }
 
+// Kinds of errors raised by the interpreter. Scripts can throw any value;
+// unless it carries its own kind the resulting error is of kind ERROR_KIND.
+const (
+	ERROR_KIND          = "Error"
+	TYPE_ERROR          = "TypeError"
+	NAME_ERROR          = "NameError"
+	ARGUMENT_ERROR      = "ArgumentError"
+	MATCH_ERROR         = "MatchError"
+	ZERO_DIVISION_ERROR = "ZeroDivisionError"
+	INDEX_ERROR         = "IndexError"
+	IMPORT_ERROR        = "ImportError"
+	MACRO_ERROR         = "MacroError"
+	VALUE_ERROR         = "ValueError"
+	PERMISSION_ERROR    = "PermissionError"
+	IO_ERROR            = "IOError"
+	SYNTAX_ERROR        = "SyntaxError"
+)
+
 type Error struct {
 	Messgae string
+	Kind    string
+	Pos     token.Position // where the error was raised, zero until known
+	Value   Object         // the value given to throw, nil for runtime errors
 }
 
 func (e *Error) Type() ObjectType { return ERROR_OBJ }
-func (e *Error) Inspect() string  { return "Error: " + e.Messgae }
+func (e *Error) Inspect() string {
+	if e.Kind == "" {
+		return ERROR_KIND + ": " + e.Messgae
+	}
+	return e.Kind + ": " + e.Messgae
+}
 
 type EnvironmentStore map[string]Object
+
+// Environment is safe for use by several goroutines at once, so that spawned
+// tasks can share the globals they were created with.
 type Environment struct {
+	mu    sync.RWMutex // guards store
 	store EnvironmentStore
 	outer *Environment
+
+	// A frame holds the variables of resolved code in slots and cells
+	// instead of store, and free holds those its function captured. Names
+	// are looked up and set in the environment around it.
+	frame bool
+	slots []Object
+	cells []*Cell
+	free  []*Cell
+
+	// yield hands a value to whoever runs the generator whose call e is.
+	yield func(Object)
+}
+
+// Cell holds a variable that closures capture. Its value is nil until the
+// variable is bound. Closures running in different tasks may share a cell,
+// so it is safe for use by several goroutines at once.
+type Cell struct {
+	mu    sync.RWMutex
+	value Object
+}
+
+func (c *Cell) Get() Object {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.value
+}
+
+func (c *Cell) Set(value Object) {
+	c.mu.Lock()
+	c.value = value
+	c.mu.Unlock()
 }
 
 func NewEnvironment() *Environment {
@@ -112,8 +206,23 @@ func NewEnclosedEnvironment(outer *Environment) *Environment {
 	return env
 }
 
+// NewFrame returns a frame with the given numbers of empty slots and cells
+// and the captured variables free, enclosed by outer.
+func NewFrame(outer *Environment, slots, cells int, free []*Cell) *Environment {
+	env := &Environment{outer: outer, frame: true, slots: make([]Object, slots), free: free}
+	if cells > 0 {
+		env.cells = make([]*Cell, cells)
+		for i := range env.cells {
+			env.cells[i] = &Cell{}
+		}
+	}
+	return env
+}
+
 func (e *Environment) Get(name string) (Object, bool) {
+	e.mu.RLock()
 	obj, ok := e.store[name]
+	e.mu.RUnlock()
 	if !ok && e.outer != nil {
 		obj, ok = e.outer.Get(name)
 	}
@@ -121,14 +230,71 @@ func (e *Environment) Get(name string) (Object, bool) {
 }
 
 func (e *Environment) Set(name string, val Object) Object {
+	if e.frame {
+		return e.outer.Set(name, val)
+	}
+	e.mu.Lock()
 	e.store[name] = val
+	e.mu.Unlock()
 	return val
 }
 
+// IsFrame reports whether e was created by NewFrame.
+func (e *Environment) IsFrame() bool { return e.frame }
+
+// Global returns the innermost environment around e, including e itself,
+// that is not a frame.
+func (e *Environment) Global() *Environment {
+	for e.frame {
+		e = e.outer
+	}
+	return e
+}
+
+// Slot returns the value in slot i of a frame, or nil if it is empty.
+func (e *Environment) Slot(i int) Object { return e.slots[i] }
+
+func (e *Environment) SetSlot(i int, val Object) { e.slots[i] = val }
+
+// Cell returns cell i of a frame.
+func (e *Environment) Cell(i int) *Cell { return e.cells[i] }
+
+// RenewCell replaces cell i of a frame with a new, empty one. Closures
+// that captured the old cell keep it.
+func (e *Environment) RenewCell(i int) { e.cells[i] = &Cell{} }
+
+// SetYield makes yield statements run in e, or in an environment it
+// encloses, call yield.
+func (e *Environment) SetYield(yield func(Object)) { e.yield = yield }
+
+// Yield returns the yield function of the innermost environment around e
+// that has one.
+func (e *Environment) Yield() (func(Object), bool) {
+	for ; e != nil; e = e.outer {
+		if e.yield != nil {
+			return e.yield, true
+		}
+	}
+	return nil, false
+}
+
+// Free returns the captured variable i of a frame.
+func (e *Environment) Free(i int) *Cell { return e.free[i] }
+
 type Function struct {
-	Parameters []*ast.Identifier
+	Parameters []ast.Expression
 	Body       *ast.BlockStatement
 	Env        *Environment
+
+	// For a resolved function, Frame lays out the frame of each call and
+	// Free holds the variables it captured. Env is then the global
+	// environment it was created in.
+	Frame *ast.Frame
+	Free  []*Cell
+
+	// Generator is set for functions whose body yields. Calling one
+	// returns a generator that runs the body as it is iterated.
+	Generator bool
 }
 
 func (f *Function) Type() ObjectType { return FUNCTION_OBJ }
@@ -153,23 +319,41 @@ func (f *Function) Inspect() string {
 type BuiltinFunction func(args ...Object) Object
 
 type Builtin struct {
-	Fn BuiltinFunction
+	Name string
+	Fn   BuiltinFunction
 }
 
 func (b *Builtin) Type() ObjectType { return BUILTIN_OBJ }
-func (b *Builtin) Inspect() string  { return "builting function" }
-
+func (b *Builtin) Inspect() string  { return "<builtin " + b.Name + ">" }
+
+// Array is a mutable sequence, unless it is Frozen: arrays used as hash
+// keys are stored as frozen copies so that the key cannot change after it
+// was inserted.
+//
+// Arrays are safe for use by several goroutines at once, as long as the
+// elements of one that may be shared are only read and written through Get,
+// Set and Snapshot. Elements itself never changes once the array is built.
 type Array struct {
 	Elements []Object
+	Frozen   bool
+
+	mu sync.RWMutex // guards the elements of Elements
 }
 
 func (a *Array) Type() ObjectType { return ARRAY_OBJ }
-func (a *Array) Inspect() string {
+func (a *Array) Inspect() string  { return a.inspect(map[Object]bool{}) }
+func (a *Array) inspect(path map[Object]bool) string {
+	if path[a] {
+		return "[...]"
+	}
+	path[a] = true
+	defer delete(path, a)
+
 	var out bytes.Buffer
 
 	elements := []string{}
-	for _, el := range a.Elements {
-		elements = append(elements, el.Inspect())
+	for _, el := range a.Snapshot() {
+		elements = append(elements, inspect(el, path))
 	}
 
 	out.WriteString("[")
@@ -178,23 +362,71 @@ func (a *Array) Inspect() string {
 
 	return out.String()
 }
+func (a *Array) HashKey() HashKey {
+	h := fnv.New64a()
+	for _, el := range a.Snapshot() {
+		writeHashKey(h, el.(Hashable).HashKey())
+	}
+	return HashKey{Type: a.Type(), Value: h.Sum64()}
+}
+
+// Get returns the element at index i.
+func (a *Array) Get(i int) Object {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.Elements[i]
+}
+
+// Set replaces the element at index i.
+func (a *Array) Set(i int, value Object) {
+	a.mu.Lock()
+	a.Elements[i] = value
+	a.mu.Unlock()
+}
+
+// Snapshot returns a copy of the elements as they are now.
+func (a *Array) Snapshot() []Object {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	elements := make([]Object, len(a.Elements))
+	copy(elements, a.Elements)
+	return elements
+}
 
 type HashPair struct {
 	Key   Object
 	Value Object
 }
 
+// Hash maps hashable keys to values and remembers the order in which keys
+// were first inserted. Pairs are indexed by HashKey, and keys that share a
+// HashKey are told apart with Equal, so colliding keys never overwrite each
+// other. The zero value is an empty hash. Like arrays, hashes used as keys
+// are stored as Frozen copies.
+//
+// A hash is safe for use by several goroutines at once. Keys are frozen and
+// pairs are only ever added, so keys are compared without holding the lock.
 type Hash struct {
-	Pairs map[HashKey]HashPair
+	mu     sync.RWMutex // guards index and pairs
+	index  map[HashKey][]int
+	pairs  []HashPair
+	Frozen bool
 }
 
 func (h *Hash) Type() ObjectType { return HASH_OBJ }
-func (h *Hash) Inspect() string {
+func (h *Hash) Inspect() string  { return h.inspect(map[Object]bool{}) }
+func (h *Hash) inspect(path map[Object]bool) string {
+	if path[h] {
+		return "{...}"
+	}
+	path[h] = true
+	defer delete(path, h)
+
 	var out bytes.Buffer
 
 	pairs := []string{}
-	for _, pair := range h.Pairs {
-		pairs = append(pairs, fmt.Sprintf("%s: %s", pair.Key.Inspect(), pair.Value.Inspect()))
+	for _, pair := range h.Pairs() {
+		pairs = append(pairs, fmt.Sprintf("%s: %s", pair.Key.Inspect(), inspect(pair.Value, path)))
 	}
 
 	out.WriteString("{")
@@ -203,3 +435,305 @@ func (h *Hash) Inspect() string {
 
 	return out.String()
 }
+
+// HashKey does not depend on the order of the pairs, since equal hashes may
+// have been built in different orders.
+func (h *Hash) HashKey() HashKey {
+	var sum uint64
+	for _, pair := range h.Pairs() {
+		pairHash := fnv.New64a()
+		writeHashKey(pairHash, pair.Key.(Hashable).HashKey())
+		writeHashKey(pairHash, pair.Value.(Hashable).HashKey())
+		sum += pairHash.Sum64()
+	}
+	return HashKey{Type: h.Type(), Value: sum}
+}
+
+// Len returns the number of pairs in the hash.
+func (h *Hash) Len() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.pairs)
+}
+
+// Get returns the value stored under key.
+func (h *Hash) Get(key Hashable) (Object, bool) {
+	if i, ok := h.find(key); ok {
+		h.mu.RLock()
+		defer h.mu.RUnlock()
+		return h.pairs[i].Value, true
+	}
+	return nil, false
+}
+
+// Set stores value under key, replacing the value of an equal key in place.
+// Arrays and hashes are frozen before they are stored as keys.
+func (h *Hash) Set(key Hashable, value Object) {
+	frozen := Freeze(key)
+	hashKey := key.HashKey()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	// Look again with the lock held, so that tasks adding the same key at
+	// once do not both add a pair.
+	for _, i := range h.index[hashKey] {
+		if Equal(h.pairs[i].Key, frozen) {
+			h.pairs[i].Value = value
+			return
+		}
+	}
+	if h.index == nil {
+		h.index = make(map[HashKey][]int)
+	}
+	h.index[hashKey] = append(h.index[hashKey], len(h.pairs))
+	h.pairs = append(h.pairs, HashPair{Key: frozen, Value: value})
+}
+
+// Pairs returns the pairs of the hash in insertion order.
+func (h *Hash) Pairs() []HashPair {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	pairs := make([]HashPair, len(h.pairs))
+	copy(pairs, h.pairs)
+	return pairs
+}
+
+func (h *Hash) find(key Hashable) (int, bool) {
+	hashKey := key.HashKey()
+	h.mu.RLock()
+	candidates, pairs := h.index[hashKey], h.pairs
+	h.mu.RUnlock()
+	for _, i := range candidates {
+		if Equal(pairs[i].Key, key) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// Set is an unordered collection of distinct hashable values, although it
+// iterates and prints in insertion order. Elements are frozen like hash
+// keys, and sets are never modified once built, so a set can itself be an
+// element or a hash key.
+type Set struct {
+	members Hash
+}
+
+func (s *Set) Type() ObjectType { return SET_OBJ }
+func (s *Set) Inspect() string {
+	elements := []string{}
+	for _, el := range s.Elements() {
+		elements = append(elements, el.Inspect())
+	}
+	return "#{" + strings.Join(elements, ", ") + "}"
+}
+
+// HashKey does not depend on the order of the elements.
+func (s *Set) HashKey() HashKey {
+	var sum uint64
+	for _, el := range s.Elements() {
+		sum += el.(Hashable).HashKey().Value
+	}
+	return HashKey{Type: s.Type(), Value: sum}
+}
+
+// NewSet builds a set of the given elements, which must all be hashable.
+func NewSet(elements ...Hashable) *Set {
+	set := &Set{}
+	for _, el := range elements {
+		set.members.Set(el, nil)
+	}
+	return set
+}
+
+// Len returns the number of elements in the set.
+func (s *Set) Len() int { return s.members.Len() }
+
+// Contains reports whether the set holds an element equal to el.
+func (s *Set) Contains(el Hashable) bool {
+	_, ok := s.members.find(el)
+	return ok
+}
+
+// Elements returns the elements of the set in insertion order.
+func (s *Set) Elements() []Object {
+	pairs := s.members.Pairs()
+	elements := make([]Object, len(pairs))
+	for i, pair := range pairs {
+		elements[i] = pair.Key
+	}
+	return elements
+}
+
+// Tuple is an immutable sequence. It is hashable when its elements are.
+type Tuple struct {
+	Elements []Object
+}
+
+func (t *Tuple) Type() ObjectType { return TUPLE_OBJ }
+func (t *Tuple) Inspect() string  { return t.inspect(map[Object]bool{}) }
+func (t *Tuple) inspect(path map[Object]bool) string {
+	elements := []string{}
+	for _, el := range t.Elements {
+		elements = append(elements, inspect(el, path))
+	}
+	if len(elements) == 1 {
+		return "(" + elements[0] + ",)"
+	}
+	return "(" + strings.Join(elements, ", ") + ")"
+}
+func (t *Tuple) HashKey() HashKey {
+	h := fnv.New64a()
+	for _, el := range t.Elements {
+		writeHashKey(h, el.(Hashable).HashKey())
+	}
+	return HashKey{Type: t.Type(), Value: h.Sum64()}
+}
+
+// inspect is obj.Inspect() for an object inside the arrays and hashes on
+// path, which are shown as [...] and {...} if obj leads back to them.
+func inspect(obj Object, path map[Object]bool) string {
+	switch obj := obj.(type) {
+	case *Array:
+		return obj.inspect(path)
+	case *Hash:
+		return obj.inspect(path)
+	case *Tuple:
+		return obj.inspect(path)
+	default:
+		return obj.Inspect()
+	}
+}
+
+// IsHashable reports whether obj can be used as a hash key: it must be
+// Hashable and, for arrays and hashes, contain only hashable values and not
+// contain itself.
+func IsHashable(obj Object) bool {
+	return isHashable(obj, map[Object]bool{})
+}
+
+func isHashable(obj Object, path map[Object]bool) bool {
+	switch obj := obj.(type) {
+	case *Array:
+		if path[obj] {
+			return false
+		}
+		path[obj] = true
+		defer delete(path, obj)
+		for _, el := range obj.Snapshot() {
+			if !isHashable(el, path) {
+				return false
+			}
+		}
+		return true
+	case *Hash:
+		if path[obj] {
+			return false
+		}
+		path[obj] = true
+		defer delete(path, obj)
+		for _, pair := range obj.Pairs() {
+			if !isHashable(pair.Value, path) {
+				return false
+			}
+		}
+		return true
+	case *Tuple:
+		for _, el := range obj.Elements {
+			if !isHashable(el, path) {
+				return false
+			}
+		}
+		return true
+	case Hashable:
+		return true
+	default:
+		return false
+	}
+}
+
+// Freeze returns a frozen deep copy of an array or hash, a tuple of frozen
+// elements, and any other object unchanged. Already frozen values are shared
+// rather than copied.
+func Freeze(obj Object) Object {
+	switch obj := obj.(type) {
+	case *Array:
+		if obj.Frozen {
+			return obj
+		}
+		elements := obj.Snapshot()
+		for i, el := range elements {
+			elements[i] = Freeze(el)
+		}
+		return &Array{Elements: elements, Frozen: true}
+	case *Hash:
+		if obj.Frozen {
+			return obj
+		}
+		frozen := &Hash{}
+		for _, pair := range obj.Pairs() {
+			frozen.Set(pair.Key.(Hashable), Freeze(pair.Value))
+		}
+		frozen.Frozen = true
+		return frozen
+	case *Tuple:
+		elements := make([]Object, len(obj.Elements))
+		for i, el := range obj.Elements {
+			elements[i] = Freeze(el)
+		}
+		return &Tuple{Elements: elements}
+	default:
+		return obj
+	}
+}
+
+func writeHashKey(h hash.Hash64, key HashKey) {
+	h.Write([]byte(key.Type))
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], key.Value)
+	h.Write(buf[:])
+}
+
+// Module is an imported script. Only the names it exports are reachable
+// through member access.
+type Module struct {
+	Name    string
+	Path    string
+	Exports map[string]Object
+}
+
+func (m *Module) Type() ObjectType { return MODULE_OBJ }
+func (m *Module) Inspect() string  { return fmt.Sprintf("<module %s>", m.Name) }
+
+// Quote is unevaluated code, produced by quote() and passed to macros.
+type Quote struct {
+	Node ast.Node
+}
+
+func (q *Quote) Type() ObjectType { return QUOTE_OBJ }
+func (q *Quote) Inspect() string  { return "QUOTE(" + q.Node.String() + ")" }
+
+type Macro struct {
+	Parameters []*ast.Identifier
+	Body       *ast.BlockStatement
+	Env        *Environment
+}
+
+func (m *Macro) Type() ObjectType { return MACRO_OBJ }
+func (m *Macro) Inspect() string {
+	var out bytes.Buffer
+
+	params := []string{}
+	for _, p := range m.Parameters {
+		params = append(params, p.String())
+	}
+
+	out.WriteString("macro")
+	out.WriteString("(")
+	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(") {\n")
+	out.WriteString(m.Body.String())
+	out.WriteString("\n}")
+
+	return out.String()
+}
@@ -1,6 +1,10 @@
 package object
 
-import "testing"
+import (
+	"fmt"
+	"math"
+	"testing"
+)
 
 func TestStringHashKey(t *testing.T) {
 	hello1 := &String{Value: "Hello World"}
@@ -52,3 +56,195 @@ func TestBooleanHashKey(t *testing.T) {
 		t.Errorf("booleans with different content have same hash keys")
 	}
 }
+
+func TestEqualIsCycleSafe(t *testing.T) {
+	a := &Array{Elements: []Object{&Integer{Value: 1}, nil}}
+	a.Elements[1] = a
+	b := &Array{Elements: []Object{&Integer{Value: 1}, nil}}
+	b.Elements[1] = &Array{Elements: []Object{&Integer{Value: 1}, b}}
+
+	if !Equal(a, b) {
+		t.Errorf("equal cyclic arrays compared unequal")
+	}
+
+	c := &Array{Elements: []Object{&Integer{Value: 2}, nil}}
+	c.Elements[1] = c
+	if Equal(a, c) {
+		t.Errorf("different cyclic arrays compared equal")
+	}
+}
+
+func TestInspectIsCycleSafe(t *testing.T) {
+	a := &Array{Elements: []Object{&Integer{Value: 1}, nil}}
+	a.Elements[1] = a
+	h := &Hash{}
+	h.Set(&String{Value: "self"}, h)
+	h.Set(&String{Value: "list"}, &Array{Elements: []Object{h, &Tuple{Elements: []Object{a, a}}}})
+
+	tests := []struct {
+		obj      Object
+		expected string
+	}{
+		{a, "[1, [...]]"},
+		{h, "{self: {...}, list: [{...}, ([1, [...]], [1, [...]])]}"},
+	}
+	for _, tt := range tests {
+		if got := tt.obj.Inspect(); got != tt.expected {
+			t.Errorf("wrong Inspect. expected=%q, got=%q", tt.expected, got)
+		}
+	}
+}
+
+// collidingKey always hashes to the same HashKey, standing in for two
+// values whose 64-bit hashes happen to collide.
+type collidingKey struct{ name string }
+
+func (k *collidingKey) Type() ObjectType { return "COLLIDING" }
+func (k *collidingKey) Inspect() string  { return k.name }
+func (k *collidingKey) HashKey() HashKey { return HashKey{Type: k.Type(), Value: 42} }
+
+func TestHashKeepsCollidingKeysApart(t *testing.T) {
+	a, b := &collidingKey{"a"}, &collidingKey{"b"}
+	hash := &Hash{}
+	hash.Set(a, &Integer{Value: 1})
+	hash.Set(b, &Integer{Value: 2})
+	hash.Set(a, &Integer{Value: 3})
+
+	if hash.Len() != 2 {
+		t.Fatalf("hash has wrong num of pairs. got=%d", hash.Len())
+	}
+	for key, want := range map[Hashable]int64{a: 3, b: 2} {
+		value, ok := hash.Get(key)
+		if !ok {
+			t.Errorf("no pair for key %s", key.Inspect())
+			continue
+		}
+		if got := value.(*Integer).Value; got != want {
+			t.Errorf("wrong value for key %s. expected=%d, got=%d", key.Inspect(), want, got)
+		}
+	}
+}
+
+func TestCompositeHashKey(t *testing.T) {
+	array := func(elements ...Object) *Array { return &Array{Elements: elements} }
+	one, two := &Integer{Value: 1}, &Integer{Value: 2}
+
+	if array(one, two).HashKey() != array(one, two).HashKey() {
+		t.Errorf("arrays with same content have different hash keys")
+	}
+	if array(one, two).HashKey() == array(two, one).HashKey() {
+		t.Errorf("arrays with different content have same hash keys")
+	}
+
+	ab, ba := &Hash{}, &Hash{}
+	ab.Set(&String{Value: "a"}, one)
+	ab.Set(&String{Value: "b"}, two)
+	ba.Set(&String{Value: "b"}, two)
+	ba.Set(&String{Value: "a"}, one)
+	if ab.HashKey() != ba.HashKey() {
+		t.Errorf("hashes with same content have different hash keys")
+	}
+
+	if IsHashable(array(one, &Float{Value: 1.5})) {
+		t.Errorf("array containing a float is hashable")
+	}
+	cyclic := array(one, nil)
+	cyclic.Elements[1] = cyclic
+	if IsHashable(cyclic) {
+		t.Errorf("array containing itself is hashable")
+	}
+	shared := array(one)
+	if !IsHashable(array(shared, shared)) {
+		t.Errorf("array sharing an element is not hashable")
+	}
+}
+
+func TestRangeIteration(t *testing.T) {
+	tests := []struct {
+		r        *Range
+		expected []int64
+	}{
+		{&Range{Start: 0, End: 3}, []int64{0, 1, 2}},
+		{&Range{Start: 0, End: 3, Inclusive: true}, []int64{0, 1, 2, 3}},
+		{&Range{Start: 3, End: 3}, nil},
+		{&Range{Start: 3, End: 0, Inclusive: true}, nil},
+		{&Range{Start: math.MaxInt64 - 1, End: math.MaxInt64, Inclusive: true}, []int64{math.MaxInt64 - 1, math.MaxInt64}},
+	}
+
+	for _, tt := range tests {
+		var got []int64
+		it := tt.r.Iter()
+		for obj, ok := it.Next(); ok; obj, ok = it.Next() {
+			got = append(got, obj.(*Integer).Value)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tt.expected) {
+			t.Errorf("%s: wrong elements. expected=%v, got=%v", tt.r.Inspect(), tt.expected, got)
+		}
+		if tt.r.Len() != int64(len(tt.expected)) {
+			t.Errorf("%s: wrong length. expected=%d, got=%d", tt.r.Inspect(), len(tt.expected), tt.r.Len())
+		}
+		if _, ok := it.Next(); ok {
+			t.Errorf("%s: iterator restarted after it was exhausted", tt.r.Inspect())
+		}
+	}
+}
+
+func TestEnvironmentConcurrentAccess(t *testing.T) {
+	env := NewEnclosedEnvironment(NewEnvironment())
+	done := make(chan bool)
+	for i := 0; i < 8; i++ {
+		go func(i int) {
+			for j := 0; j < 100; j++ {
+				name := fmt.Sprintf("v%d_%d", i, j)
+				env.Set(name, &Integer{Value: int64(j)})
+				if _, ok := env.Get(name); !ok {
+					t.Errorf("%s not set", name)
+				}
+				env.Get("missing")
+			}
+			done <- true
+		}(i)
+	}
+	for i := 0; i < 8; i++ {
+		<-done
+	}
+}
+
+func TestChannel(t *testing.T) {
+	ch := NewChannel(2)
+	if !ch.Send(&Integer{Value: 1}) || !ch.Send(&Integer{Value: 2}) {
+		t.Fatalf("send on open channel failed")
+	}
+	if !ch.Close() {
+		t.Fatalf("close of open channel failed")
+	}
+	if ch.Close() {
+		t.Errorf("closing a closed channel succeeded")
+	}
+	if ch.Send(&Integer{Value: 3}) {
+		t.Errorf("send on closed channel succeeded")
+	}
+
+	got := []string{}
+	it := ch.Iter()
+	for v, ok := it.Next(); ok; v, ok = it.Next() {
+		got = append(got, v.Inspect())
+	}
+	if fmt.Sprint(got) != "[1 2]" {
+		t.Errorf("wrong values received. expected=[1 2], got=%v", got)
+	}
+}
+
+func TestFuture(t *testing.T) {
+	f := NewFuture()
+	if f.Done() || f.Inspect() != "<future pending>" {
+		t.Errorf("new future is resolved: %s", f.Inspect())
+	}
+	go f.Resolve(&String{Value: "ok"})
+	if got := f.Wait().Inspect(); got != "ok" {
+		t.Errorf("wrong result. expected=ok, got=%s", got)
+	}
+	if !f.Done() || f.Inspect() != "<future ok>" {
+		t.Errorf("awaited future is not resolved: %s", f.Inspect())
+	}
+}
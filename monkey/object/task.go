@@ -0,0 +1,99 @@
+package object
+
+import "fmt"
+
+// Future is the result of a spawned task, available once the task has
+// finished. A task that fails resolves its future to the *Error.
+type Future struct {
+	done  chan struct{}
+	value Object
+}
+
+func NewFuture() *Future {
+	return &Future{done: make(chan struct{})}
+}
+
+func (f *Future) Type() ObjectType { return FUTURE_OBJ }
+func (f *Future) Inspect() string {
+	if f.Done() {
+		return fmt.Sprintf("<future %s>", f.value.Inspect())
+	}
+	return "<future pending>"
+}
+
+// Resolve sets the result of the future and wakes everyone waiting for it.
+// It must be called exactly once.
+func (f *Future) Resolve(value Object) {
+	f.value = value
+	close(f.done)
+}
+
+// Wait blocks until the future is resolved and returns its result.
+func (f *Future) Wait() Object {
+	<-f.done
+	return f.value
+}
+
+// Done reports whether the future has been resolved.
+func (f *Future) Done() bool {
+	select {
+	case <-f.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Channel passes values between tasks. An unbuffered channel hands each
+// value straight from sender to receiver; a buffered one holds up to its
+// capacity of values that have not been received yet.
+type Channel struct {
+	ch chan Object
+}
+
+func NewChannel(capacity int) *Channel {
+	return &Channel{ch: make(chan Object, capacity)}
+}
+
+func (c *Channel) Type() ObjectType { return CHANNEL_OBJ }
+func (c *Channel) Inspect() string  { return fmt.Sprintf("<channel %d/%d>", len(c.ch), cap(c.ch)) }
+
+// Chan returns the Go channel underneath c, for selecting on it.
+func (c *Channel) Chan() chan Object { return c.ch }
+
+// Send blocks until value is sent. It returns false, without sending, if
+// the channel is closed.
+func (c *Channel) Send(value Object) (sent bool) {
+	defer func() {
+		// sending on a closed channel panics
+		if recover() != nil {
+			sent = false
+		}
+	}()
+	c.ch <- value
+	return true
+}
+
+// Recv blocks until a value arrives. It returns false once the channel is
+// closed and every value sent before has been received.
+func (c *Channel) Recv() (Object, bool) {
+	value, ok := <-c.ch
+	return value, ok
+}
+
+// Close stops further sends. It returns false if the channel was already
+// closed.
+func (c *Channel) Close() (closed bool) {
+	defer func() {
+		if recover() != nil {
+			closed = false
+		}
+	}()
+	close(c.ch)
+	return true
+}
+
+// Iter receives from the channel until it is closed.
+func (c *Channel) Iter() Iterator {
+	return NewIterator(c.Recv)
+}
@@ -0,0 +1,119 @@
+package parser
+
+import (
+	"bytes"
+	goast "go/ast"
+	goparser "go/parser"
+	gotoken "go/token"
+	"monkey/ast"
+	"monkey/lexer"
+	"strconv"
+	"testing"
+)
+
+// parserFixtures returns every string literal in parser_test.go that parses
+// without errors into a non-empty program.
+func parserFixtures(t *testing.T) []string {
+	t.Helper()
+	file, err := goparser.ParseFile(gotoken.NewFileSet(), "parser_test.go", nil, 0)
+	if err != nil {
+		t.Fatalf("could not read fixtures: %s", err)
+	}
+
+	var fixtures []string
+	goast.Inspect(file, func(node goast.Node) bool {
+		lit, ok := node.(*goast.BasicLit)
+		if !ok || lit.Kind != gotoken.STRING {
+			return true
+		}
+		input, err := strconv.Unquote(lit.Value)
+		if err != nil {
+			return true
+		}
+		p := New(lexer.New(input))
+		program := p.ParseProgram()
+		if len(p.Errors()) == 0 && len(program.Statements) > 0 {
+			fixtures = append(fixtures, input)
+		}
+		return true
+	})
+	return fixtures
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	fixtures := parserFixtures(t)
+	if len(fixtures) < 50 {
+		t.Fatalf("too few fixtures found in parser_test.go. got=%d", len(fixtures))
+	}
+
+	for _, input := range fixtures {
+		program := New(lexer.New(input)).ParseProgram()
+
+		encoded, err := ast.EncodeJSON(program)
+		if err != nil {
+			t.Errorf("%q: EncodeJSON failed: %s", input, err)
+			continue
+		}
+		decoded, err := ast.DecodeJSON(encoded)
+		if err != nil {
+			t.Errorf("%q: DecodeJSON failed: %s", input, err)
+			continue
+		}
+
+		if decoded.String() != program.String() {
+			t.Errorf("%q: decoded program differs. expected=%q, got=%q", input, program.String(), decoded.String())
+		}
+		reencoded, err := ast.EncodeJSON(decoded)
+		if err != nil {
+			t.Errorf("%q: EncodeJSON of decoded program failed: %s", input, err)
+			continue
+		}
+		if !bytes.Equal(encoded, reencoded) {
+			t.Errorf("%q: encoding changed after a round trip.\nfirst= %s\nsecond=%s", input, encoded, reencoded)
+		}
+	}
+}
+
+func TestJSONEncoding(t *testing.T) {
+	program := New(lexer.New("-x")).ParseProgram()
+
+	encoded, err := ast.EncodeJSON(program)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"kind":"Program","statements":[` +
+		`{"kind":"ExpressionStatement","pos":{"line":1,"column":1},"token":{"type":"-","literal":"-"},"expression":` +
+		`{"kind":"PrefixExpression","pos":{"line":1,"column":1},"token":{"type":"-","literal":"-"},"operator":"-","right":` +
+		`{"kind":"Identifier","pos":{"line":1,"column":2},"token":{"type":"IDENT","literal":"x"},"value":"x"}}}]}`
+	if string(encoded) != expected {
+		t.Errorf("wrong encoding.\nexpected=%s\ngot=     %s", expected, encoded)
+	}
+}
+
+func TestJSONDecodeErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`null`, "ast: no node to decode"},
+		{`[1]`, "ast: json: cannot unmarshal array into Go value of type ast.fields"},
+		{`{"kind":"Nope","pos":{"line":1,"column":1},"token":{"type":"","literal":""}}`, `ast: unknown node kind "Nope"`},
+		{`{"kind":"Program"}`, `ast: missing field "statements"`},
+		{`{"kind":"Program","statements":[{"kind":"Identifier","pos":{"line":1,"column":1},"token":{"type":"IDENT","literal":"x"},"value":"x"}]}`,
+			"ast: statements: expected a statement, got *ast.Identifier"},
+		{`{"kind":"MemberExpression","pos":{"line":1,"column":1},"token":{"type":".","literal":"."},"object":null,` +
+			`"property":{"kind":"NullExpression","pos":{"line":1,"column":1},"token":{"type":"NULL","literal":"null"}}}`,
+			"ast: expected Identifier, got NullExpression"},
+	}
+
+	for _, tt := range tests {
+		_, err := ast.DecodeJSON([]byte(tt.input))
+		if err == nil {
+			t.Errorf("%s: expected an error", tt.input)
+			continue
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("wrong error.\nexpected=%q\ngot=     %q", tt.expected, err.Error())
+		}
+	}
+}
@@ -11,8 +11,12 @@ import (
 const (
 	_int = iota
 	LOWEST
+	ASSIGN
 	EQUALS
 	LESSGREATER
+	RANGE
+	UNION
+	INTERSECTION
 	SUM
 	PRODUCT
 	PREFIX
@@ -21,16 +25,22 @@ const (
 )
 
 var precedences = map[token.TokenType]int{
-	token.EQ:       EQUALS,
-	token.NOT_EQ:   EQUALS,
-	token.LT:       LESSGREATER,
-	token.GT:       LESSGREATER,
-	token.PLUS:     SUM,
-	token.MINUS:    SUM,
-	token.SLASH:    PRODUCT,
-	token.ASTERISK: PRODUCT,
-	token.LPAREN:   CALL,
-	token.LBRACKET: INDEX,
+	token.ASSIGN:    ASSIGN,
+	token.EQ:        EQUALS,
+	token.NOT_EQ:    EQUALS,
+	token.LT:        LESSGREATER,
+	token.GT:        LESSGREATER,
+	token.DOTDOT:    RANGE,
+	token.DOTDOT_EQ: RANGE,
+	token.PIPE:      UNION,
+	token.AMPERSAND: INTERSECTION,
+	token.PLUS:      SUM,
+	token.MINUS:     SUM,
+	token.SLASH:     PRODUCT,
+	token.ASTERISK:  PRODUCT,
+	token.LPAREN:    CALL,
+	token.LBRACKET:  INDEX,
+	token.DOT:       INDEX,
 }
 
 type (
@@ -44,7 +54,13 @@ type Parser struct {
 	curToken  token.Token
 	peekToken token.Token
 
-	errors []string
+	errors    []string
+	errorPos  []token.Position // where each of errors was found
+	lexErrors int              // how many of l.Errors() have been copied into errors
+
+	// function is the function literal being parsed, which a yield
+	// statement makes a generator; nil outside of functions.
+	function *ast.FunctionLiteral
 
 	prefixParseFns map[token.TokenType]prefixParseFn
 	infixParseFns  map[token.TokenType]infixParseFn
@@ -61,6 +77,7 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerPrefix(token.IDENTIFIER, p.parseIdentifier)
 	p.registerPrefix(token.NULL, p.parseNull)
 	p.registerPrefix(token.INT, p.parseIntegerLiteral)
+	p.registerPrefix(token.FLOAT, p.parseFloatLiteral)
 	p.registerPrefix(token.STRING, p.parseStringLiteral)
 	p.registerPrefix(token.BANG, p.parsePrefixOperatorExpression)
 	p.registerPrefix(token.MINUS, p.parsePrefixOperatorExpression)
@@ -69,8 +86,15 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerPrefix(token.LPAREN, p.parseGroupedExpression)
 	p.registerPrefix(token.LBRACKET, p.parseArrayLiteral)
 	p.registerPrefix(token.LBRACE, p.parseHashLiteral)
+	p.registerPrefix(token.SET_LBRACE, p.parseSetLiteral)
 	p.registerPrefix(token.IF, p.parseIfExpression)
 	p.registerPrefix(token.FUNCTION, p.parseFunctionLiteral)
+	p.registerPrefix(token.MATCH, p.parseMatchExpression)
+	p.registerPrefix(token.FOR, p.parseForExpression)
+	p.registerPrefix(token.TRY, p.parseTryExpression)
+	p.registerPrefix(token.MACRO, p.parseMacroLiteral)
+	p.registerPrefix(token.SPAWN, p.parseSpawnExpression)
+	p.registerPrefix(token.AWAIT, p.parseAwaitExpression)
 
 	// INFIX
 	p.infixParseFns = make(map[token.TokenType]infixParseFn)
@@ -82,8 +106,14 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerInfix(token.NOT_EQ, p.parseInfixExpression)
 	p.registerInfix(token.LT, p.parseInfixExpression)
 	p.registerInfix(token.GT, p.parseInfixExpression)
+	p.registerInfix(token.DOTDOT, p.parseInfixExpression)
+	p.registerInfix(token.DOTDOT_EQ, p.parseInfixExpression)
+	p.registerInfix(token.PIPE, p.parseInfixExpression)
+	p.registerInfix(token.AMPERSAND, p.parseInfixExpression)
 	p.registerInfix(token.LPAREN, p.parseCallExpression)
 	p.registerInfix(token.LBRACKET, p.parseIndexExpression)
+	p.registerInfix(token.DOT, p.parseMemberExpression)
+	p.registerInfix(token.ASSIGN, p.parseAssignExpression)
 
 	return p
 }
@@ -107,6 +137,14 @@ func (p *Parser) parseStatement() ast.Statement {
 		return p.parseLetStatement()
 	case token.RETURN:
 		return p.parseReturnStatement()
+	case token.THROW:
+		return p.parseThrowStatement()
+	case token.YIELD:
+		return p.parseYieldStatement()
+	case token.IMPORT:
+		return p.parseImportStatement()
+	case token.EXPORT:
+		return p.parseExportStatement()
 	default:
 		return p.parseExpressionStatement()
 	}
@@ -115,12 +153,19 @@ func (p *Parser) parseStatement() ast.Statement {
 func (p *Parser) parseLetStatement() ast.Statement {
 	statement := &ast.LetStatement{Token: p.curToken}
 
-	if !p.expectPeek(token.IDENTIFIER) {
-		return nil
+	if p.peekTokenIs(token.LBRACKET) || p.peekTokenIs(token.LBRACE) {
+		p.nextToken()
+		statement.Pattern = p.parsePattern()
+		if statement.Pattern == nil {
+			return nil
+		}
+	} else {
+		if !p.expectPeek(token.IDENTIFIER) {
+			return nil
+		}
+		statement.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 	}
 
-	statement.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
-
 	if !p.expectPeek(token.ASSIGN) {
 		return nil
 	}
@@ -128,9 +173,7 @@ func (p *Parser) parseLetStatement() ast.Statement {
 
 	statement.Value = p.parseExpression(LOWEST)
 
-	for !p.curTokenIs(token.SEMICOLON) {
-		p.nextToken()
-	}
+	p.skipPeek(token.SEMICOLON)
 
 	return statement
 }
@@ -139,9 +182,71 @@ func (p *Parser) parseReturnStatement() ast.Statement {
 	statement := &ast.ReturnStatement{Token: p.curToken}
 	p.nextToken()
 	statement.ReturnValue = p.parseExpression(LOWEST)
-	for !p.curTokenIs(token.SEMICOLON) {
+
+	p.skipPeek(token.SEMICOLON)
+
+	return statement
+}
+
+func (p *Parser) parseYieldStatement() ast.Statement {
+	statement := &ast.YieldStatement{Token: p.curToken}
+	if p.function == nil {
+		p.addError(p.curToken.Pos, "yield outside of a function")
+	} else {
+		p.function.Generator = true
+	}
+	p.nextToken()
+	statement.Value = p.parseExpression(LOWEST)
+
+	p.skipPeek(token.SEMICOLON)
+
+	return statement
+}
+
+func (p *Parser) parseThrowStatement() ast.Statement {
+	statement := &ast.ThrowStatement{Token: p.curToken}
+	p.nextToken()
+	statement.Value = p.parseExpression(LOWEST)
+
+	p.skipPeek(token.SEMICOLON)
+
+	return statement
+}
+
+// parseImportStatement parses `import "path" as name;`. The `as name` part
+// is optional; as is contextual and stays usable as an identifier.
+func (p *Parser) parseImportStatement() ast.Statement {
+	statement := &ast.ImportStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.STRING) {
+		return nil
+	}
+	statement.Path = &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
+
+	if p.peekTokenIs(token.IDENTIFIER) && p.peekToken.Literal == "as" {
 		p.nextToken()
+		if !p.expectPeek(token.IDENTIFIER) {
+			return nil
+		}
+		statement.Alias = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	}
+
+	p.skipPeek(token.SEMICOLON)
+
+	return statement
+}
+
+func (p *Parser) parseExportStatement() ast.Statement {
+	statement := &ast.ExportStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.LET) {
+		return nil
+	}
+	let, ok := p.parseLetStatement().(*ast.LetStatement)
+	if !ok {
+		return nil
 	}
+	statement.Statement = let
 
 	return statement
 }
@@ -159,6 +264,10 @@ func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
 func (p *Parser) parseExpression(precedence int) ast.Expression {
 	prefix := p.prefixParseFns[p.curToken.Type]
 	if prefix == nil {
+		// the lexer has already reported why the token is illegal
+		if p.curTokenIs(token.ILLEGAL) {
+			return nil
+		}
 		p.noPrefixParseFnError(p.curToken.Type)
 		return nil
 	}
@@ -191,7 +300,20 @@ func (p *Parser) parseIntegerLiteral() ast.Expression {
 	value, err := strconv.ParseInt(p.curToken.Literal, 0, 64)
 	if err != nil {
 		msg := fmt.Sprintf("could not parse %q as integer", p.curToken.Literal)
-		p.errors = append(p.errors, msg)
+		p.addError(p.curToken.Pos, msg)
+		return nil
+	}
+	literal.Value = value
+	return literal
+}
+
+func (p *Parser) parseFloatLiteral() ast.Expression {
+	literal := &ast.FloatLiteral{Token: p.curToken}
+
+	value, err := strconv.ParseFloat(p.curToken.Literal, 64)
+	if err != nil {
+		msg := fmt.Sprintf("could not parse %q as float", p.curToken.Literal)
+		p.addError(p.curToken.Pos, msg)
 		return nil
 	}
 	literal.Value = value
@@ -210,6 +332,22 @@ func (p *Parser) parsePrefixOperatorExpression() ast.Expression {
 	return pe
 }
 
+// parseSpawnExpression parses `spawn f(x)`. The call binds tighter than
+// spawn, as the operand of a prefix operator does.
+func (p *Parser) parseSpawnExpression() ast.Expression {
+	se := &ast.SpawnExpression{Token: p.curToken}
+	p.nextToken()
+	se.Call = p.parseExpression(PREFIX)
+	return se
+}
+
+func (p *Parser) parseAwaitExpression() ast.Expression {
+	ae := &ast.AwaitExpression{Token: p.curToken}
+	p.nextToken()
+	ae.Value = p.parseExpression(PREFIX)
+	return ae
+}
+
 func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 	expression := &ast.InfixExpression{
 		Token:    p.curToken,
@@ -229,14 +367,37 @@ func (p *Parser) parseBoolean() ast.Expression {
 	return &ast.Boolean{Token: p.curToken, Value: p.curTokenIs(token.TRUE)}
 }
 
+// parseGroupedExpression parses `(x)`, or a tuple when the parentheses
+// are empty or hold a comma: `()`, `(x,)` and `(x, y)`.
 func (p *Parser) parseGroupedExpression() ast.Expression {
+	tuple := &ast.TupleLiteral{Token: p.curToken, Elements: []ast.Expression{}}
+	if p.peekTokenIs(token.RPAREN) {
+		p.nextToken()
+		return tuple
+	}
 	p.nextToken()
 
 	exp := p.parseExpression(LOWEST)
+	if !p.peekTokenIs(token.COMMA) {
+		if !p.expectPeek(token.RPAREN) {
+			return nil
+		}
+		return exp
+	}
+
+	tuple.Elements = append(tuple.Elements, exp)
+	for p.peekTokenIs(token.COMMA) {
+		p.nextToken()
+		if p.peekTokenIs(token.RPAREN) {
+			break
+		}
+		p.nextToken()
+		tuple.Elements = append(tuple.Elements, p.parseExpression(LOWEST))
+	}
 	if !p.expectPeek(token.RPAREN) {
 		return nil
 	}
-	return exp
+	return tuple
 }
 
 func (p *Parser) parseArrayLiteral() ast.Expression {
@@ -245,6 +406,12 @@ func (p *Parser) parseArrayLiteral() ast.Expression {
 	return arr
 }
 
+func (p *Parser) parseSetLiteral() ast.Expression {
+	set := &ast.SetLiteral{Token: p.curToken}
+	set.Elements = p.parseExpressionList(token.RBRACE)
+	return set
+}
+
 func (p *Parser) parseHashLiteral() ast.Expression {
 	hash := &ast.HashLiteral{Token: p.curToken}
 	hash.Pairs = make(map[ast.Expression]ast.Expression)
@@ -282,12 +449,12 @@ func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
 	}
 
 	p.nextToken()
-	list = append(list, p.parseExpression(LOWEST))
+	list = append(list, p.parseListElement())
 
 	for p.peekTokenIs(token.COMMA) {
 		p.nextToken()
 		p.nextToken()
-		list = append(list, p.parseExpression(LOWEST))
+		list = append(list, p.parseListElement())
 	}
 
 	if !p.expectPeek(end) {
@@ -297,6 +464,18 @@ func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
 	return list
 }
 
+// parseListElement parses an element of an array or set literal or an
+// argument of a call, which may be spread with `...`.
+func (p *Parser) parseListElement() ast.Expression {
+	if !p.curTokenIs(token.ELLIPSIS) {
+		return p.parseExpression(LOWEST)
+	}
+	spread := &ast.SpreadExpression{Token: p.curToken}
+	p.nextToken()
+	spread.Value = p.parseExpression(LOWEST)
+	return spread
+}
+
 func (p *Parser) parseIfExpression() ast.Expression {
 	ifExpression := &ast.IfExpression{Token: p.curToken}
 
@@ -318,6 +497,23 @@ func (p *Parser) parseIfExpression() ast.Expression {
 
 	if p.peekTokenIs(token.ELSE) {
 		p.nextToken()
+
+		// `else if` is sugar for an else block holding a single if expression
+		if p.peekTokenIs(token.IF) {
+			p.nextToken()
+			ifToken := p.curToken
+			nested := p.parseIfExpression()
+			if nested == nil {
+				return nil
+			}
+			ifExpression.Alternative = &ast.BlockStatement{
+				Token:      ifToken,
+				Statements: []ast.Statement{&ast.ExpressionStatement{Token: ifToken, Expression: nested}},
+				Rbrace:     p.curToken.Pos,
+			}
+			return ifExpression
+		}
+
 		if !p.expectPeek(token.LBRACE) {
 			return nil
 		}
@@ -327,6 +523,242 @@ func (p *Parser) parseIfExpression() ast.Expression {
 	return ifExpression
 }
 
+func (p *Parser) parseTryExpression() ast.Expression {
+	tryExpression := &ast.TryExpression{Token: p.curToken}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	tryExpression.Block = p.parseBlockStatement()
+
+	if p.peekTokenIs(token.CATCH) {
+		p.nextToken()
+
+		if p.peekTokenIs(token.LPAREN) {
+			p.nextToken()
+			p.nextToken()
+			tryExpression.CatchParam = p.parseParameter()
+			if tryExpression.CatchParam == nil || !p.expectPeek(token.RPAREN) {
+				return nil
+			}
+		}
+
+		if !p.expectPeek(token.LBRACE) {
+			return nil
+		}
+		tryExpression.Catch = p.parseBlockStatement()
+	}
+
+	if p.peekTokenIs(token.FINALLY) {
+		p.nextToken()
+		if !p.expectPeek(token.LBRACE) {
+			return nil
+		}
+		tryExpression.Finally = p.parseBlockStatement()
+	}
+
+	if tryExpression.Catch == nil && tryExpression.Finally == nil {
+		msg := fmt.Sprintf("expected catch or finally after try block, got %s instead", p.peekToken.Type)
+		p.addError(p.peekToken.Pos, msg)
+		return nil
+	}
+
+	return tryExpression
+}
+
+func (p *Parser) parseMatchExpression() ast.Expression {
+	match := &ast.MatchExpression{Token: p.curToken}
+	p.nextToken()
+
+	match.Subject = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+		arm := &ast.MatchArm{Token: p.curToken}
+
+		arm.Pattern = p.parsePattern()
+		if arm.Pattern == nil {
+			return nil
+		}
+
+		if p.peekTokenIs(token.IF) {
+			p.nextToken()
+			p.nextToken()
+			arm.Guard = p.parseExpression(LOWEST)
+		}
+
+		if !p.expectPeek(token.ARROW) {
+			return nil
+		}
+		p.nextToken()
+		arm.Body = p.parseExpression(LOWEST)
+
+		match.Arms = append(match.Arms, arm)
+
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+	return match
+}
+
+// parseForExpression parses `for (pattern in iterable) { body }`. Like `as`
+// in imports, `in` is not a keyword but an identifier the loop expects.
+func (p *Parser) parseForExpression() ast.Expression {
+	loop := &ast.ForExpression{Token: p.curToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+	p.nextToken()
+	loop.Pattern = p.parsePattern()
+	if loop.Pattern == nil {
+		return nil
+	}
+
+	if !p.peekTokenIs(token.IDENTIFIER) || p.peekToken.Literal != "in" {
+		msg := fmt.Sprintf("expected in after for loop pattern, got %s instead", p.peekToken.Type)
+		p.addError(p.peekToken.Pos, msg)
+		return nil
+	}
+	p.nextToken()
+	p.nextToken()
+	loop.Iterable = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RPAREN) || !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	loop.Body = p.parseBlockStatement()
+
+	return loop
+}
+
+// parsePattern parses the left hand side of a binding: an identifier
+// (`_` matches anything without binding), a literal, or an array or hash
+// pattern whose elements are themselves patterns.
+func (p *Parser) parsePattern() ast.Expression {
+	switch p.curToken.Type {
+	case token.IDENTIFIER:
+		return p.parseIdentifier()
+	case token.LBRACKET:
+		return p.parseArrayPattern()
+	case token.LBRACE:
+		return p.parseHashPattern()
+	case token.INT, token.FLOAT, token.STRING, token.TRUE, token.FALSE, token.NULL:
+		return p.prefixParseFns[p.curToken.Type]()
+	case token.MINUS:
+		if !p.peekTokenIs(token.INT) && !p.peekTokenIs(token.FLOAT) {
+			break
+		}
+		return p.parsePrefixOperatorExpression()
+	}
+
+	msg := fmt.Sprintf("invalid pattern: unexpected %s", p.curToken.Type)
+	p.addError(p.curToken.Pos, msg)
+	return nil
+}
+
+func (p *Parser) parseArrayPattern() ast.Expression {
+	pattern := &ast.ArrayPattern{Token: p.curToken}
+
+	for !p.peekTokenIs(token.RBRACKET) {
+		p.nextToken()
+
+		if p.curTokenIs(token.ELLIPSIS) {
+			pattern.Rest = p.parseRestPattern()
+			if pattern.Rest == nil {
+				return nil
+			}
+			break
+		}
+
+		element := p.parsePattern()
+		if element == nil {
+			return nil
+		}
+		pattern.Elements = append(pattern.Elements, element)
+
+		if !p.peekTokenIs(token.RBRACKET) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.RBRACKET) {
+		return nil
+	}
+	return pattern
+}
+
+func (p *Parser) parseHashPattern() ast.Expression {
+	pattern := &ast.HashPattern{Token: p.curToken}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+
+		if p.curTokenIs(token.ELLIPSIS) {
+			pattern.Rest = p.parseRestPattern()
+			if pattern.Rest == nil {
+				return nil
+			}
+			break
+		}
+
+		pair := &ast.HashPatternPair{}
+		switch p.curToken.Type {
+		case token.IDENTIFIER, token.STRING, token.INT, token.TRUE, token.FALSE:
+			pair.Key = p.prefixParseFns[p.curToken.Type]()
+		default:
+			msg := fmt.Sprintf("invalid hash pattern key: unexpected %s", p.curToken.Type)
+			p.addError(p.curToken.Pos, msg)
+			return nil
+		}
+
+		if p.peekTokenIs(token.COLON) {
+			p.nextToken()
+			p.nextToken()
+			pair.Value = p.parsePattern()
+			if pair.Value == nil {
+				return nil
+			}
+		} else if _, ok := pair.Key.(*ast.Identifier); ok {
+			pair.Value = pair.Key
+		} else {
+			p.addPeekError(token.COLON)
+			return nil
+		}
+		pattern.Pairs = append(pattern.Pairs, pair)
+
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+	return pattern
+}
+
+// parseRestPattern parses `...name`, which must be the last element of an
+// array or hash pattern.
+func (p *Parser) parseRestPattern() *ast.Identifier {
+	if !p.expectPeek(token.IDENTIFIER) {
+		return nil
+	}
+	rest := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	p.skipPeek(token.COMMA)
+	return rest
+}
+
 func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 	block := &ast.BlockStatement{Token: p.curToken}
 	block.Statements = []ast.Statement{}
@@ -340,6 +772,7 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 		}
 		p.nextToken()
 	}
+	block.Rbrace = p.curToken.Pos
 
 	return block
 }
@@ -356,36 +789,89 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
 		return nil
 	}
 
+	outer := p.function
+	p.function = functionLiteral
 	functionLiteral.Body = p.parseBlockStatement()
+	p.function = outer
 
 	return functionLiteral
 }
 
-func (p *Parser) parseFunctionParameters() []*ast.Identifier {
-	identifiers := []*ast.Identifier{}
+func (p *Parser) parseMacroLiteral() ast.Expression {
+	macro := &ast.MacroLiteral{Token: p.curToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	params := p.parseFunctionParameters()
+	if params == nil {
+		return nil
+	}
+	for _, param := range params {
+		ident, ok := param.(*ast.Identifier)
+		if !ok {
+			msg := fmt.Sprintf("macro parameters must be identifiers, got %s", param.String())
+			p.addError(param.Pos(), msg)
+			return nil
+		}
+		macro.Parameters = append(macro.Parameters, ident)
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	// A macro body runs at expansion time, not as a function.
+	outer := p.function
+	p.function = nil
+	macro.Body = p.parseBlockStatement()
+	p.function = outer
+
+	return macro
+}
+
+func (p *Parser) parseFunctionParameters() []ast.Expression {
+	parameters := []ast.Expression{}
 
 	if p.peekTokenIs(token.RPAREN) {
 		p.nextToken()
-		return identifiers
+		return parameters
 	}
 
 	p.nextToken()
-
-	ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
-	identifiers = append(identifiers, ident)
+	parameters = append(parameters, p.parseParameter())
 
 	for p.peekTokenIs(token.COMMA) {
 		p.nextToken()
 		p.nextToken()
-		ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
-		identifiers = append(identifiers, ident)
+		parameters = append(parameters, p.parseParameter())
 	}
 
 	if !p.expectPeek(token.RPAREN) {
 		return nil
 	}
 
-	return identifiers
+	for _, param := range parameters {
+		if param == nil {
+			return nil
+		}
+	}
+
+	return parameters
+}
+
+// parseParameter parses a function parameter: a plain identifier or an array
+// or hash pattern that destructures the argument.
+func (p *Parser) parseParameter() ast.Expression {
+	switch p.curToken.Type {
+	case token.IDENTIFIER, token.LBRACKET, token.LBRACE:
+		return p.parsePattern()
+	default:
+		msg := fmt.Sprintf("invalid parameter: unexpected %s", p.curToken.Type)
+		p.addError(p.curToken.Pos, msg)
+		return nil
+	}
 }
 
 func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
@@ -394,15 +880,73 @@ func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
 	return exp
 }
 
+// parseIndexExpression parses `left[index]` as well as the slices
+// `left[start:end]` and `left[start:end:step]`, any part of which may be
+// omitted.
 func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
-	exp := &ast.IndexExpression{Token: p.curToken, Left: left}
-	p.nextToken()
+	tok := p.curToken
 
-	exp.Index = p.parseExpression(LOWEST)
+	var start ast.Expression
+	if !p.peekTokenIs(token.COLON) {
+		p.nextToken()
+		start = p.parseExpression(LOWEST)
+		if !p.peekTokenIs(token.COLON) {
+			if !p.expectPeek(token.RBRACKET) {
+				return nil
+			}
+			return &ast.IndexExpression{Token: tok, Left: left, Index: start}
+		}
+	}
 
+	slice := &ast.SliceExpression{Token: tok, Left: left, Start: start}
+	p.nextToken()
+	slice.End = p.parseSliceBound()
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken()
+		slice.Step = p.parseSliceBound()
+	}
 	if !p.expectPeek(token.RBRACKET) {
 		return nil
 	}
+	return slice
+}
+
+// parseSliceBound parses the part of a slice after a colon, which is nil
+// when it is omitted.
+func (p *Parser) parseSliceBound() ast.Expression {
+	if p.peekTokenIs(token.COLON) || p.peekTokenIs(token.RBRACKET) {
+		return nil
+	}
+	p.nextToken()
+	return p.parseExpression(LOWEST)
+}
+
+func (p *Parser) parseMemberExpression(left ast.Expression) ast.Expression {
+	exp := &ast.MemberExpression{Token: p.curToken, Object: left}
+
+	if !p.expectPeek(token.IDENTIFIER) {
+		return nil
+	}
+	exp.Property = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	return exp
+}
+
+// parseAssignExpression parses `target = value` where target is a member or
+// index expression. Assignment is right associative: a.x = b.x = 1.
+func (p *Parser) parseAssignExpression(target ast.Expression) ast.Expression {
+	exp := &ast.AssignExpression{Token: p.curToken, Target: target}
+
+	switch target.(type) {
+	case *ast.MemberExpression, *ast.IndexExpression:
+	default:
+		msg := fmt.Sprintf("cannot assign to %s", target.String())
+		p.addError(target.Pos(), msg)
+		return nil
+	}
+
+	p.nextToken()
+	exp.Value = p.parseExpression(ASSIGN - 1)
 
 	return exp
 }
@@ -432,6 +976,13 @@ func (p *Parser) expectPeek(t token.TokenType) bool {
 	}
 }
 
+// skipPeek advances past the next token if it has type t
+func (p *Parser) skipPeek(t token.TokenType) {
+	if p.peekTokenIs(t) {
+		p.nextToken()
+	}
+}
+
 func (p *Parser) registerPrefix(tokenType token.TokenType, fn prefixParseFn) {
 	p.prefixParseFns[tokenType] = fn
 }
@@ -439,33 +990,52 @@ func (p *Parser) registerInfix(tokenType token.TokenType, fn infixParseFn) {
 	p.infixParseFns[tokenType] = fn
 }
 
-func (p *Parser) peekPrecedence() int {
-	if p, ok := precedences[p.peekToken.Type]; ok {
+// Precedence returns how tightly an infix operator of the given token type
+// binds, or LOWEST for tokens that are not infix operators.
+func Precedence(tokenType token.TokenType) int {
+	if p, ok := precedences[tokenType]; ok {
 		return p
 	}
 	return LOWEST
 }
+
+func (p *Parser) peekPrecedence() int {
+	return Precedence(p.peekToken.Type)
+}
 func (p *Parser) curPrecedence() int {
-	if p, ok := precedences[p.curToken.Type]; ok {
-		return p
-	}
-	return LOWEST
+	return Precedence(p.curToken.Type)
 }
 func (p *Parser) noPrefixParseFnError(t token.TokenType) {
 	msg := fmt.Sprintf("no prefix parse function for %s found", t)
-	p.errors = append(p.errors, msg)
+	p.addError(p.curToken.Pos, msg)
 }
 
 func (p *Parser) Errors() []string {
 	return p.errors
 }
 
+// ErrorPositions returns where in the source each of Errors was found.
+func (p *Parser) ErrorPositions() []token.Position {
+	return p.errorPos
+}
+
+func (p *Parser) addError(pos token.Position, msg string) {
+	p.errors = append(p.errors, msg)
+	p.errorPos = append(p.errorPos, pos)
+}
+
 func (p *Parser) addPeekError(t token.TokenType) {
 	msg := fmt.Sprintf("expected next token to be %s, got %s instead", t, p.peekToken.Type)
-	p.errors = append(p.errors, msg)
+	p.addError(p.peekToken.Pos, msg)
 }
 
 func (p *Parser) nextToken() {
 	p.curToken = p.peekToken
 	p.peekToken = p.l.NextToken()
+
+	if lexErrors := p.l.Errors(); len(lexErrors) > p.lexErrors {
+		p.errors = append(p.errors, lexErrors[p.lexErrors:]...)
+		p.errorPos = append(p.errorPos, p.l.ErrorPositions()[p.lexErrors:]...)
+		p.lexErrors = len(lexErrors)
+	}
 }
@@ -44,6 +44,53 @@ func TestLetStatements(t *testing.T) {
 	}
 }
 
+func TestDestructuringLetStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let [a, b] = arr;", "let [a, b] = arr;"},
+		{"let [a, b, ...rest] = arr;", "let [a, b, ...rest] = arr;"},
+		{"let {name, age: years} = person;", "let {name, age: years} = person;"},
+		{"let {name, address: {city}, tags: [first, ...others]} = person;", "let {name, address: {city}, tags: [first, ...others]} = person;"},
+		{"let [{x}, [y, _]] = points", "let [{x}, [y, _]] = points;"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statements, got= %d", len(program.Statements))
+		}
+		statement, ok := program.Statements[0].(*ast.LetStatement)
+		if !ok {
+			t.Fatalf("statement is not *ast.LetStatement. got=%T", program.Statements[0])
+		}
+		if statement.Pattern == nil {
+			t.Fatalf("statement.Pattern is nil")
+		}
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+}
+
+func TestStatementsWithoutSemicolons(t *testing.T) {
+	input := "let x = 5\nreturn x\nx"
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 3 {
+		t.Fatalf("program.Statements does not contain 3 statements, got= %d", len(program.Statements))
+	}
+}
+
 func TestReturnStatements(t *testing.T) {
 	tests := []struct {
 		input         string
@@ -82,6 +129,52 @@ func TestReturnStatements(t *testing.T) {
 
 }
 
+func TestImportExportStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`import "path/to/lib.mk" as lib;`, `import "path/to/lib.mk" as lib;`},
+		{`import "lib"`, `import "lib";`},
+		{`export let x = 5;`, `export let x = 5;`},
+		{`export let [a, b] = pair;`, `export let [a, b] = pair;`},
+		{`let as = 1; as`, `let as = 1;as`},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+
+	p := New(lexer.New("export 5;"))
+	p.ParseProgram()
+	if len(p.Errors()) == 0 || p.Errors()[0] != "expected next token to be LET, got INT instead" {
+		t.Errorf("expected export error. got=%q", p.Errors())
+	}
+}
+
+func TestErrorPositions(t *testing.T) {
+	p := New(lexer.NewFile("a.mk", "let x = 1;\nlet = 2;\nlet y = @;"))
+	p.ParseProgram()
+
+	positions := p.ErrorPositions()
+	if len(positions) != len(p.Errors()) {
+		t.Fatalf("expected a position for each of %q. got=%v", p.Errors(), positions)
+	}
+	expected := []string{"2:5", "2:5", "3:9"}
+	for i, pos := range expected {
+		if i >= len(positions) || positions[i].String() != pos || positions[i].File != "a.mk" {
+			t.Errorf("wrong position of error %d. expected=a.mk:%s, got=%+v", i, pos, positions)
+		}
+	}
+}
+
 func TestIndentifierExpression(t *testing.T) {
 	input := "foobar;"
 
@@ -155,6 +248,51 @@ func TestIntegerLiteralExpression(t *testing.T) {
 		t.Fatalf("failed testIntegerLiteral. got=%q", statement.Expression)
 	}
 }
+func TestNumericLiteralSpellings(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{"0xFF", int64(255)},
+		{"0o17", int64(15)},
+		{"0b1010", int64(10)},
+		{"1_000_000", int64(1000000)},
+		{"3.25", 3.25},
+		{"1_000.5", 1000.5},
+		{"2e3", 2000.0},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		statement := program.Statements[0].(*ast.ExpressionStatement)
+		switch expected := tt.expected.(type) {
+		case int64:
+			literal, ok := statement.Expression.(*ast.IntegerLiteral)
+			if !ok {
+				t.Fatalf("statement.Expression not *ast.IntegerLiteral. got=%T", statement.Expression)
+			}
+			if literal.Value != expected {
+				t.Errorf("literal.Value not %d. got=%d", expected, literal.Value)
+			}
+		case float64:
+			literal, ok := statement.Expression.(*ast.FloatLiteral)
+			if !ok {
+				t.Fatalf("statement.Expression not *ast.FloatLiteral. got=%T", statement.Expression)
+			}
+			if literal.Value != expected {
+				t.Errorf("literal.Value not %g. got=%g", expected, literal.Value)
+			}
+		}
+		if program.String() != tt.input {
+			t.Errorf("program.String() does not preserve spelling. expected=%q, got=%q", tt.input, program.String())
+		}
+	}
+}
+
 func TestStringLiteral(t *testing.T) {
 	input := `"foobar"`
 
@@ -270,6 +408,10 @@ func TestParsingInfixExpressions(t *testing.T) {
 		{"true == true;", true, "==", true},
 		{"true != false;", true, "!=", false},
 		{"false == false;", false, "==", false},
+		{"a | b;", "a", "|", "b"},
+		{"a & b;", "a", "&", "b"},
+		{"0..n;", 0, "..", "n"},
+		{"1..=n;", 1, "..=", "n"},
 	}
 
 	for _, tt := range tests {
@@ -323,6 +465,31 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 		{"add(( a + b ) * c)", "add(((a + b) * c))"},
 		{"a * [1, 2, 3, 4][b * c] * d", "((a * ([1, 2, 3, 4][(b * c)])) * d)"},
 		{"add(a * b[2], b[1], 2 * [1, 2][1])", "add((a * (b[2])), (b[1]), (2 * ([1, 2][1])))"},
+		{"lib.square(a.b.c) + -d.e", "((lib.square)(((a.b).c)) + (-(d.e)))"},
+		{"a.b[1].c", "(((a.b)[1]).c)"},
+		{"a.b = c + d", "((a.b) = (c + d))"},
+		{"a.b = c.d = 1", "((a.b) = ((c.d) = 1))"},
+		{"a[i] = b == c", "((a[i]) = (b == c))"},
+		{"a | b & c", "(a | (b & c))"},
+		{"a & b | c", "((a & b) | c)"},
+		{"a | b - c", "(a | (b - c))"},
+		{"a & b == c | d", "((a & b) == (c | d))"},
+		{"(a, b + c)", "(a, (b + c))"},
+		{"(a,)", "(a,)"},
+		{"()", "()"},
+		{"(a)", "a"},
+		{"#{a, b | c}", "#{a, (b | c)}"},
+		{"a[1:2]", "(a[1:2])"},
+		{"a[b + 1:][0]", "((a[(b + 1):])[0])"},
+		{"a[:]", "(a[:])"},
+		{"a[::-1]", "(a[::(-1)])"},
+		{"-a[1:2:3]", "(-(a[1:2:3]))"},
+		{"0..n + 1", "(0 .. (n + 1))"},
+		{"a..b == c..=d", "((a .. b) == (c ..= d))"},
+		{"a < b..c", "(a < (b .. c))"},
+		{"a..b | c", "(a .. (b | c))"},
+		{"[...a, b..c]", "[...a, (b .. c)]"},
+		{"f(...a + b, c)", "f(...(a + b), c)"},
 	}
 	for _, tt := range tests {
 		l := lexer.New(tt.input)
@@ -434,6 +601,276 @@ func TestIfElseExpression(t *testing.T) {
 	}
 }
 
+func TestElseIfExpression(t *testing.T) {
+	input := `if (x < y) { x } else if (x > y) { y } else { 0 }`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	exp, ok := stmt.Expression.(*ast.IfExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.IfExpression. got=%T", stmt.Expression)
+	}
+	if len(exp.Alternative.Statements) != 1 {
+		t.Fatalf("exp.Alternative.Statements does not contain 1 statements. got=%d", len(exp.Alternative.Statements))
+	}
+	alternative := exp.Alternative.Statements[0].(*ast.ExpressionStatement)
+	nested, ok := alternative.Expression.(*ast.IfExpression)
+	if !ok {
+		t.Fatalf("alternative is not ast.IfExpression. got=%T", alternative.Expression)
+	}
+	if !testInfixExpression(t, nested.Condition, "x", ">", "y") {
+		return
+	}
+	if nested.Alternative == nil || len(nested.Alternative.Statements) != 1 {
+		t.Fatalf("nested.Alternative was not parsed. got=%+v", nested.Alternative)
+	}
+}
+
+func TestMatchExpressionParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"match x { 1 => a, _ => b }", "match x {1 => a, _ => b}"},
+		{"match x { -1 => a, \"s\" => b, true => c, null => d, 1.5 => e, }", "match x {(-1) => a, s => b, true => c, null => d, 1.5 => e}"},
+		{"match x { n if n > 1 => n * 2 }", "match x {n if (n > 1) => (n * 2)}"},
+		{"match x { [a, [b, _], ...rest] => a }", "match x {[a, [b, _], ...rest] => a}"},
+		{"match x { [] => 0, [...all] => 1 }", "match x {[] => 0, [...all] => 1}"},
+		{"match x { {name, \"age\": [a], 1: one, ...rest} => name }", "match x {{name, age: [a], 1: one, ...rest} => name}"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		if _, ok := stmt.Expression.(*ast.MatchExpression); !ok {
+			t.Fatalf("stmt.Expression is not ast.MatchExpression. got=%T", stmt.Expression)
+		}
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+}
+
+func TestForExpressionParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"for (x in xs) { print(x) }", "for(x in xs) print(x)"},
+		{"for ([i, x] in enumerate(xs)) { x }", "for([i, x] in enumerate(xs)) x"},
+		{"for ({name} in people) { name }", "for({name} in people) name"},
+		{"for (_ in 0..=3) {}", "for(_ in (0 ..= 3)) "},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		if _, ok := stmt.Expression.(*ast.ForExpression); !ok {
+			t.Fatalf("stmt.Expression is not ast.ForExpression. got=%T", stmt.Expression)
+		}
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+
+	errors := []struct {
+		input         string
+		expectedError string
+	}{
+		{"for (x of xs) {}", "expected in after for loop pattern, got IDENT instead"},
+		{"for (x xs) {}", "expected in after for loop pattern, got IDENT instead"},
+		{"for x in xs {}", "expected next token to be (, got IDENT instead"},
+		{"for (x in xs) x", "expected next token to be {, got IDENT instead"},
+	}
+	for _, tt := range errors {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 || p.Errors()[0] != tt.expectedError {
+			t.Errorf("%q - wrong error. expected=%q, got=%q", tt.input, tt.expectedError, p.Errors())
+		}
+	}
+}
+
+func TestYieldStatements(t *testing.T) {
+	tests := []struct {
+		input     string
+		expected  string
+		generator []bool // the Generator flag of each function literal
+	}{
+		{"fn() { yield 1; }", "{() yield 1;}", []bool{true}},
+		{"fn(n) { for (i in 0..n) { yield i * i } }", "{(n) for(i in (0 .. n)) yield (i * i);}", []bool{true}},
+		{"fn() { fn() { yield 1 } }", "{() {() yield 1;}}", []bool{false, true}},
+		{"fn() { yield fn() { 1 } }", "{() yield {() 1};}", []bool{true, false}},
+		{"fn() { return 1 }", "{() return 1;}", []bool{false}},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+		generator := []bool{}
+		ast.Inspect(program, func(node ast.Node) bool {
+			if fl, ok := node.(*ast.FunctionLiteral); ok {
+				generator = append(generator, fl.Generator)
+			}
+			return true
+		})
+		if fmt.Sprint(generator) != fmt.Sprint(tt.generator) {
+			t.Errorf("%q - wrong generator flags. expected=%v, got=%v", tt.input, tt.generator, generator)
+		}
+	}
+
+	errors := []struct {
+		input         string
+		expectedError string
+	}{
+		{"yield 1;", "yield outside of a function"},
+		{"if (x) { yield 1 }", "yield outside of a function"},
+		{"fn() { macro() { yield 1 } }", "yield outside of a function"},
+	}
+	for _, tt := range errors {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 || p.Errors()[0] != tt.expectedError {
+			t.Errorf("%q - wrong error. expected=%q, got=%q", tt.input, tt.expectedError, p.Errors())
+		}
+	}
+}
+
+func TestSpawnAndAwaitParsing(t *testing.T) {
+	input := "await spawn worker.run(job, 2) + 1"
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	sum, ok := stmt.Expression.(*ast.InfixExpression)
+	if !ok || sum.Operator != "+" {
+		t.Fatalf("stmt.Expression is not a + expression. got=%T (%s)", stmt.Expression, stmt.Expression)
+	}
+	await, ok := sum.Left.(*ast.AwaitExpression)
+	if !ok {
+		t.Fatalf("sum.Left is not ast.AwaitExpression. got=%T", sum.Left)
+	}
+	spawn, ok := await.Value.(*ast.SpawnExpression)
+	if !ok {
+		t.Fatalf("await.Value is not ast.SpawnExpression. got=%T", await.Value)
+	}
+	call, ok := spawn.Call.(*ast.CallExpression)
+	if !ok {
+		t.Fatalf("spawn.Call is not ast.CallExpression. got=%T", spawn.Call)
+	}
+	if _, ok := call.Function.(*ast.MemberExpression); !ok {
+		t.Errorf("call.Function is not ast.MemberExpression. got=%T", call.Function)
+	}
+	if program.String() != "(await spawn (worker.run)(job, 2) + 1)" {
+		t.Errorf("wrong program. got=%q", program.String())
+	}
+}
+
+func TestInvalidPatterns(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{"match x { a + 1 => 1 }", "expected next token to be =>, got + instead"},
+		{"match x { fn() {} => 1 }", "invalid pattern: unexpected FUNCTION"},
+		{"match x { [...rest, a] => 1 }", "expected next token to be ], got IDENT instead"},
+		{"match x { {\"a\"} => 1 }", "expected next token to be :, got } instead"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf("%q - expected parser errors", tt.input)
+			continue
+		}
+		if errors[0] != tt.expectedError {
+			t.Errorf("%q - wrong error. expected=%q, got=%q", tt.input, tt.expectedError, errors[0])
+		}
+	}
+}
+
+func TestInvalidAssignmentTargets(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{"a = 1", "cannot assign to a"},
+		{"a + b = 1", "cannot assign to (a + b)"},
+		{"f() = 1", "cannot assign to f()"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf("%q - expected parser errors", tt.input)
+			continue
+		}
+		if errors[0] != tt.expectedError {
+			t.Errorf("%q - wrong error. expected=%q, got=%q", tt.input, tt.expectedError, errors[0])
+		}
+	}
+}
+
+func TestTryExpressionParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"try { a } catch (e) { b }", "try a catch (e) b"},
+		{"try { a } catch { b }", "try a catch b"},
+		{"try { a } finally { c }", "try a finally c"},
+		{"try { a } catch ({message}) { b } finally { c }", "try a catch ({message}) b finally c"},
+		{"throw 1 + 2;", "throw (1 + 2);"},
+		{`throw "boom"`, "throw boom;"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+
+	p := New(lexer.New("try { a }"))
+	p.ParseProgram()
+	if len(p.Errors()) == 0 || p.Errors()[0] != "expected catch or finally after try block, got EOF instead" {
+		t.Errorf("expected missing catch error. got=%q", p.Errors())
+	}
+}
+
 func TestFunctionLiteralParsing(t *testing.T) {
 	input := `fn(x, y) { x + y; }`
 
@@ -474,6 +911,49 @@ func TestFunctionLiteralParsing(t *testing.T) {
 	testInfixExpression(t, bodyStatement.Expression, "x", "+", "y")
 }
 
+func TestMacroLiteralParsing(t *testing.T) {
+	input := `macro(x, y) { x + y; }`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("unexpected number of statements. expected 1. got=%d", len(program.Statements))
+	}
+
+	statement, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] not ast.ExpressionStatement. got=%T", program.Statements[0])
+	}
+	macro, ok := statement.Expression.(*ast.MacroLiteral)
+	if !ok {
+		t.Fatalf("statement.Expression not ast.MacroLiteral. got=%T", statement.Expression)
+	}
+	if len(macro.Parameters) != 2 {
+		t.Fatalf("unexpected macro.Parameters length. expected 2. got=%d", len(macro.Parameters))
+	}
+
+	testLiteralExpression(t, macro.Parameters[0], "x")
+	testLiteralExpression(t, macro.Parameters[1], "y")
+
+	if len(macro.Body.Statements) != 1 {
+		t.Fatalf("unexpected length of macro.Body.Statements. expected 1. got=%d", len(macro.Body.Statements))
+	}
+	bodyStatement, ok := macro.Body.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("macro.Body.Statements[0] not ast.ExpressionStatement. got=%T", macro.Body.Statements[0])
+	}
+	testInfixExpression(t, bodyStatement.Expression, "x", "+", "y")
+
+	p = New(lexer.New(`macro([a]) { a }`))
+	p.ParseProgram()
+	if len(p.Errors()) == 0 || p.Errors()[0] != "macro parameters must be identifiers, got [a]" {
+		t.Errorf("expected macro parameter error. got=%q", p.Errors())
+	}
+}
+
 func TestFunctionParameterParsing(t *testing.T) {
 	tests := []struct {
 		input          string
@@ -499,6 +979,34 @@ func TestFunctionParameterParsing(t *testing.T) {
 	}
 }
 
+func TestDestructuringParameterParsing(t *testing.T) {
+	input := "fn([a, b], {name}, c) { a }"
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	function := stmt.Expression.(*ast.FunctionLiteral)
+	if len(function.Parameters) != 3 {
+		t.Fatalf("length parameters wrong. want 3, got=%d", len(function.Parameters))
+	}
+	if _, ok := function.Parameters[0].(*ast.ArrayPattern); !ok {
+		t.Errorf("function.Parameters[0] is not *ast.ArrayPattern. got=%T", function.Parameters[0])
+	}
+	if _, ok := function.Parameters[1].(*ast.HashPattern); !ok {
+		t.Errorf("function.Parameters[1] is not *ast.HashPattern. got=%T", function.Parameters[1])
+	}
+	testIdentifier(t, function.Parameters[2], "c")
+
+	p = New(lexer.New("fn(1) { 1 }"))
+	p.ParseProgram()
+	if len(p.Errors()) == 0 || p.Errors()[0] != "invalid parameter: unexpected INT" {
+		t.Errorf("expected invalid parameter error. got=%q", p.Errors())
+	}
+}
+
 func TestCallExpressionParsing(t *testing.T) {
 	input := "add(1, 2 * 3, 4 + 5);"
 
@@ -555,6 +1063,103 @@ func TestArrayLiterals(t *testing.T) {
 	testInfixExpression(t, array.Elements[2], 2, "*", 2)
 }
 
+func TestParsingSliceExpression(t *testing.T) {
+	tests := []struct {
+		input            string
+		start, end, step any
+	}{
+		{"xs[1:2]", 1, 2, nil},
+		{"xs[1:]", 1, nil, nil},
+		{"xs[:2]", nil, 2, nil},
+		{"xs[:]", nil, nil, nil},
+		{"xs[::3]", nil, nil, 3},
+		{"xs[1:2:3]", 1, 2, 3},
+		{"xs[1::3]", 1, nil, 3},
+		{"xs[:2:]", nil, 2, nil},
+	}
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		statement := program.Statements[0].(*ast.ExpressionStatement)
+		slice, ok := statement.Expression.(*ast.SliceExpression)
+		if !ok {
+			t.Fatalf("%s: statement.Expression not ast.SliceExpression. got=%T", tt.input, statement.Expression)
+		}
+		if !testIdentifier(t, slice.Left, "xs") {
+			return
+		}
+		parts := []struct {
+			name     string
+			got      ast.Expression
+			expected any
+		}{{"start", slice.Start, tt.start}, {"end", slice.End, tt.end}, {"step", slice.Step, tt.step}}
+		for _, part := range parts {
+			if part.expected == nil {
+				if part.got != nil {
+					t.Errorf("%s: %s should be omitted. got=%s", tt.input, part.name, part.got)
+				}
+				continue
+			}
+			testIntegerLiteral(t, part.got, int64(part.expected.(int)))
+		}
+	}
+}
+
+func TestSetLiterals(t *testing.T) {
+	tests := []struct {
+		input    string
+		elements int
+	}{
+		{`#{1, "a", 2 * 2}`, 3},
+		{`#{}`, 0},
+	}
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		statement := program.Statements[0].(*ast.ExpressionStatement)
+		set, ok := statement.Expression.(*ast.SetLiteral)
+		if !ok {
+			t.Fatalf("statement.Expression not ast.SetLiteral. got=%T", statement.Expression)
+		}
+		if len(set.Elements) != tt.elements {
+			t.Fatalf("len(set.Elements) not %d. got=%d", tt.elements, len(set.Elements))
+		}
+	}
+}
+
+func TestTupleLiterals(t *testing.T) {
+	tests := []struct {
+		input    string
+		elements int
+	}{
+		{`()`, 0},
+		{`(1,)`, 1},
+		{`(1, "a")`, 2},
+		{`(1, "a",)`, 2},
+	}
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		statement := program.Statements[0].(*ast.ExpressionStatement)
+		tuple, ok := statement.Expression.(*ast.TupleLiteral)
+		if !ok {
+			t.Fatalf("%s: statement.Expression not ast.TupleLiteral. got=%T", tt.input, statement.Expression)
+		}
+		if len(tuple.Elements) != tt.elements {
+			t.Fatalf("%s: len(tuple.Elements) not %d. got=%d", tt.input, tt.elements, len(tuple.Elements))
+		}
+	}
+}
+
 func TestParsingIndexExpression(t *testing.T) {
 	input := "myArray[1+1]"
 
@@ -809,3 +1414,17 @@ func checkParserErrors(t *testing.T, p *Parser) {
 	}
 	t.FailNow()
 }
+
+func TestLexerErrorsAreReported(t *testing.T) {
+	l := lexer.New("let x = 5 + \xff;")
+	p := New(l)
+	p.ParseProgram()
+
+	errors := p.Errors()
+	if len(errors) != 1 {
+		t.Fatalf("expected 1 error. got=%q", errors)
+	}
+	if errors[0] != "1:13: invalid UTF-8 encoding" {
+		t.Fatalf("wrong error. got=%q", errors[0])
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"monkey/evaluator"
+	"monkey/object"
+	"monkey/stdlib"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func run(args []string) int {
+	flags := flag.NewFlagSet("run", flag.ContinueOnError)
+	strict := flags.Bool("strict", false, "make out-of-range indexing an error")
+	files := flags.String("files", "", "let the script use the files under this directory")
+	readOnly := flags.Bool("readonly", false, "only let the script read files")
+	flags.SetOutput(os.Stderr)
+	flags.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	if err := flags.Parse(args); err != nil || flags.NArg() != 1 {
+		if err == nil {
+			fmt.Fprint(os.Stderr, usage)
+		}
+		return 2
+	}
+	evaluator.StrictIndexing = *strict
+	if *files != "" {
+		evaluator.Files = &evaluator.FilePolicy{
+			FS:       evaluator.DirFS(*files),
+			Roots:    []string{"."},
+			ReadOnly: *readOnly,
+		}
+	}
+
+	file := flags.Arg(0)
+	result := evaluator.Loader.RunFile(file, stdlib.NewEnvironment())
+	if err, ok := result.(*object.Error); ok {
+		switch {
+		case err.Pos.Line == 0:
+			fmt.Fprintf(os.Stderr, "%s: %s\n", file, err.Inspect())
+		case err.Pos.File == "":
+			fmt.Fprintf(os.Stderr, "%s:%s: %s\n", file, err.Pos, err.Inspect())
+		default:
+			fmt.Fprintf(os.Stderr, "%s:%s: %s\n", relPath(err.Pos.File), err.Pos, err.Inspect())
+		}
+		return 1
+	}
+	return 0
+}
+
+// relPath shows file relative to the working directory if it is under it.
+func relPath(file string) string {
+	wd, err := os.Getwd()
+	if err != nil {
+		return file
+	}
+	if rel, err := filepath.Rel(wd, file); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	return file
+}
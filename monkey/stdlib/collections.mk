@@ -0,0 +1,112 @@
+let reduce = fn(arr, initial, f) {
+  let iter = fn(i, acc) {
+    if (i < len(arr)) {
+      iter(i + 1, f(acc, arr[i]))
+    } else {
+      acc
+    }
+  };
+  iter(0, initial)
+};
+
+let map = fn(arr, f) {
+  reduce(arr, [], fn(acc, x) { push(acc, f(x)) })
+};
+
+let filter = fn(arr, pred) {
+  reduce(arr, [], fn(acc, x) {
+    if (pred(x)) {
+      push(acc, x)
+    } else {
+      acc
+    }
+  })
+};
+
+let each = fn(arr, f) {
+  reduce(arr, null, fn(_, x) {
+    f(x);
+    null
+  })
+};
+
+let sum = fn(arr) {
+  reduce(arr, 0, fn(acc, x) { acc + x })
+};
+
+let find = fn(arr, pred) {
+  let iter = fn(i) {
+    if (i < len(arr)) {
+      if (pred(arr[i])) {
+        some(arr[i])
+      } else {
+        iter(i + 1)
+      }
+    } else {
+      none()
+    }
+  };
+  iter(0)
+};
+
+let any = fn(arr, pred) {
+  is_some(find(arr, pred))
+};
+
+let all = fn(arr, pred) {
+  is_none(find(arr, fn(x) { !pred(x) }))
+};
+
+let reverse = fn(arr) {
+  let iter = fn(i, acc) {
+    if (i < 0) {
+      acc
+    } else {
+      iter(i - 1, push(acc, arr[i]))
+    }
+  };
+  iter(len(arr) - 1, [])
+};
+
+let slice = fn(arr, start, end) {
+  let iter = fn(i, acc) {
+    if (i < end) {
+      iter(i + 1, push(acc, arr[i]))
+    } else {
+      acc
+    }
+  };
+  iter(start, [])
+};
+
+let take = fn(arr, n) {
+  if (n > len(arr)) {
+    arr
+  } else {
+    slice(arr, 0, n)
+  }
+};
+
+let drop = fn(arr, n) {
+  if (n > len(arr)) {
+    []
+  } else {
+    slice(arr, n, len(arr))
+  }
+};
+
+let zip = fn(a, b) {
+  let n = if (len(a) < len(b)) { len(a) } else { len(b) };
+  let iter = fn(i, acc) {
+    if (i < n) {
+      iter(i + 1, push(acc, [a[i], b[i]]))
+    } else {
+      acc
+    }
+  };
+  iter(0, [])
+};
+
+let flatten = fn(arrs) {
+  reduce(arrs, [], fn(acc, arr) { reduce(arr, acc, push) })
+};
@@ -0,0 +1,61 @@
+let some = fn(value) { {"kind": "some", "value": value} };
+
+let none = fn() { {"kind": "none"} };
+
+let ok = fn(value) { {"kind": "ok", "value": value} };
+
+let err = fn(error) { {"kind": "err", "error": error} };
+
+let is_some = fn(opt) { opt.kind == "some" };
+
+let is_none = fn(opt) { opt.kind == "none" };
+
+let is_ok = fn(res) { res.kind == "ok" };
+
+let is_err = fn(res) { res.kind == "err" };
+
+let unwrap = fn(wrapped) {
+  if (wrapped.kind == "none") {
+    throw {"kind": "ValueError", "message": "unwrap called on none"};
+  }
+  if (wrapped.kind == "err") {
+    throw wrapped.error;
+  }
+  wrapped.value
+};
+
+let unwrap_or = fn(wrapped, default) {
+  if (wrapped.kind == "some") {
+    wrapped.value
+  } else {
+    if (wrapped.kind == "ok") {
+      wrapped.value
+    } else {
+      default
+    }
+  }
+};
+
+let map_value = fn(wrapped, f) {
+  match wrapped.kind {
+    "some" => some(f(wrapped.value)),
+    "ok" => ok(f(wrapped.value)),
+    _ => wrapped,
+  }
+};
+
+let and_then = fn(wrapped, f) {
+  match wrapped.kind {
+    "some" => f(wrapped.value),
+    "ok" => f(wrapped.value),
+    _ => wrapped,
+  }
+};
+
+let try_call = fn(f) {
+  try {
+    ok(f())
+  } catch (e) {
+    err(e)
+  }
+};
@@ -0,0 +1,89 @@
+// Package stdlib is Monkey's standard library: a prelude of functions written
+// in Monkey itself, embedded in the binary and evaluated once into an
+// environment that encloses every script's global environment.
+//
+// The prelude provides collection utilities (map, filter, reduce, each, sum,
+// find, any, all, reverse, slice, take, drop, zip, flatten),
+// option and result helpers (some, none, ok, err, is_some, is_none, is_ok,
+// is_err, unwrap, unwrap_or, map_value, and_then, try_call) and string
+// formatting (format, repeat, pad_left, pad_right).
+package stdlib
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"monkey/ast"
+	"monkey/evaluator"
+	"monkey/lexer"
+	"monkey/object"
+	"monkey/parser"
+	"strings"
+	"sync"
+)
+
+//go:embed *.mk
+var sources embed.FS
+
+var (
+	preludeOnce sync.Once
+	prelude     *object.Environment
+)
+
+// Prelude returns the environment holding the standard library, evaluating
+// the embedded sources on first use. Functions in it refer to each other
+// through this environment, so user code that redefines one of them does not
+// change the behavior of the others.
+func Prelude() *object.Environment {
+	preludeOnce.Do(func() {
+		env, err := load(sources)
+		if err != nil {
+			panic(err)
+		}
+		prelude = env
+	})
+	return prelude
+}
+
+// NewEnvironment returns an empty global environment enclosed by the
+// prelude. Definitions made in it shadow standard library functions of the
+// same name.
+func NewEnvironment() *object.Environment {
+	return object.NewEnclosedEnvironment(Prelude())
+}
+
+// load evaluates every .mk file in fsys, in lexical order, into one
+// environment. Functions may call those of files loaded after theirs.
+func load(fsys fs.FS) (*object.Environment, error) {
+	files, err := fs.Glob(fsys, "*.mk")
+	if err != nil {
+		return nil, err
+	}
+
+	programs := make([]*ast.Program, len(files))
+	for i, file := range files {
+		source, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+
+		p := parser.New(lexer.NewFile("stdlib/"+file, string(source)))
+		programs[i] = p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			return nil, fmt.Errorf("stdlib: could not parse %s:\n\t%s", file, strings.Join(p.Errors(), "\n\t"))
+		}
+	}
+
+	env := object.NewEnvironment()
+	for i, file := range files {
+		program := programs[i]
+		if errs := evaluator.Resolve(program, env, programs[i+1:]...); len(errs) != 0 {
+			return nil, fmt.Errorf("stdlib: %s:%s: %s", file, errs[0].Pos, errs[0].Inspect())
+		}
+		result := evaluator.Eval(program, env)
+		if errObj, ok := result.(*object.Error); ok {
+			return nil, fmt.Errorf("stdlib: %s:%s: %s", file, errObj.Pos, errObj.Inspect())
+		}
+	}
+	return env, nil
+}
@@ -0,0 +1,180 @@
+package stdlib
+
+import (
+	"monkey/evaluator"
+	"monkey/lexer"
+	"monkey/object"
+	"monkey/parser"
+	"testing"
+	"testing/fstest"
+)
+
+func testEval(t *testing.T, input string) object.Object {
+	t.Helper()
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors for %q: %v", input, p.Errors())
+	}
+	env := NewEnvironment()
+	if errs := evaluator.Resolve(program, env); len(errs) != 0 {
+		return errs[0]
+	}
+	return evaluator.Eval(program, env)
+}
+
+func runTests(t *testing.T, tests []struct{ input, expected string }) {
+	t.Helper()
+	for _, tt := range tests {
+		evaluated := testEval(t, tt.input)
+		if evaluated == nil {
+			t.Errorf("%s: evaluated to nil", tt.input)
+			continue
+		}
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%s: expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestPreludeLoads(t *testing.T) {
+	if _, err := load(sources); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestLargeCollections checks that the recursive helpers of the prelude
+// handle long arrays, which relies on tail calls.
+func TestLargeCollections(t *testing.T) {
+	elements := make([]object.Object, 100000)
+	for i := range elements {
+		elements[i] = &object.Integer{Value: int64(i)}
+	}
+
+	tests := []struct{ input, expected string }{
+		{"sum(xs)", "4999950000"},
+		{"len(take(xs, 3))", "3"},
+		{"all(xs, fn(x) { x > -1 })", "true"},
+		{"unwrap(find(xs, fn(x) { x == 99999 }))", "99999"},
+	}
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			t.Fatalf("%s: %s", tt.input, p.Errors()[0])
+		}
+		env := NewEnvironment()
+		env.Set("xs", &object.Array{Elements: elements})
+		if errs := evaluator.Resolve(program, env); len(errs) != 0 {
+			t.Fatalf("%s: %s", tt.input, errs[0].Inspect())
+		}
+		if got := evaluator.Eval(program, env).Inspect(); got != tt.expected {
+			t.Errorf("%s: expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestCollections(t *testing.T) {
+	runTests(t, []struct{ input, expected string }{
+		{`reduce([1, 2, 3], 10, fn(acc, x) { acc + x })`, "16"},
+		{`map([1, 2, 3], fn(x) { x * 2 })`, "[2, 4, 6]"},
+		{`map([], fn(x) { x * 2 })`, "[]"},
+		{`filter([1, 2, 3, 4], fn(x) { x > 2 })`, "[3, 4]"},
+		{`sum([1, 2, 3, 4])`, "10"},
+		{`sum([])`, "0"},
+		{`let seen = {"n": 0}; each([1, 2, 3], fn(x) { seen.n = seen.n + x }); seen.n`, "6"},
+		{`unwrap(find([1, 2, 3], fn(x) { x > 1 }))`, "2"},
+		{`is_none(find([1, 2, 3], fn(x) { x > 5 }))`, "true"},
+		{`any([1, 2, 3], fn(x) { x == 2 })`, "true"},
+		{`any([], fn(x) { true })`, "false"},
+		{`all([1, 2, 3], fn(x) { x > 0 })`, "true"},
+		{`all([1, 2, 3], fn(x) { x > 1 })`, "false"},
+		{`contains(["a", "b"], "b")`, "true"},
+		{`contains(["a", "b"], "c")`, "false"},
+		{`map((1, 2, 3), fn(x) { x * 2 })`, "[2, 4, 6]"},
+		{`sum(array(#{1, 2, 2}))`, "3"},
+		{`filter(array(#{1, 2, 3}), fn(x) { x > 1 })`, "[2, 3]"},
+		{`reverse([1, 2, 3])`, "[3, 2, 1]"},
+		{`slice([1, 2, 3, 4], 1, 3)`, "[2, 3]"},
+		{`take([1, 2, 3], 2)`, "[1, 2]"},
+		{`take([1, 2, 3], 5)`, "[1, 2, 3]"},
+		{`drop([1, 2, 3], 1)`, "[2, 3]"},
+		{`drop([1, 2, 3], 5)`, "[]"},
+		{`zip([1, 2, 3], ["a", "b"])`, "[[1, a], [2, b]]"},
+		{`flatten([[1, 2], [], [3]])`, "[1, 2, 3]"},
+		{`sum(array(1..=100))`, "5050"},
+		{`map([...0..3], fn(x) { x * x })`, "[0, 1, 4]"},
+		{`sum((1..1000000).filter(fn(x) { x / 7 * 7 == x }).take(3).collect())`, "42"},
+	})
+}
+
+func TestOptionAndResult(t *testing.T) {
+	runTests(t, []struct{ input, expected string }{
+		{`unwrap(some(5))`, "5"},
+		{`unwrap(ok(5))`, "5"},
+		{`is_some(some(1))`, "true"},
+		{`is_none(none())`, "true"},
+		{`is_ok(ok(1))`, "true"},
+		{`is_err(err("bad"))`, "true"},
+		{`unwrap_or(none(), 7)`, "7"},
+		{`unwrap_or(err("bad"), 7)`, "7"},
+		{`unwrap_or(some(1), 7)`, "1"},
+		{`unwrap(map_value(some(2), fn(x) { x * 10 }))`, "20"},
+		{`is_none(map_value(none(), fn(x) { x * 10 }))`, "true"},
+		{`unwrap(and_then(ok(2), fn(x) { ok(x + 1) }))`, "3"},
+		{`is_err(and_then(ok(2), fn(x) { err("no") }))`, "true"},
+		{`unwrap(try_call(fn() { 1 + 1 }))`, "2"},
+		{`try_call(fn() { throw "boom" }).error.message`, "boom"},
+		{`unwrap(none())`, "ValueError: unwrap called on none"},
+		{`unwrap(err("bad"))`, "Error: bad"},
+		{`unwrap(try_call(fn() { 1 / 0 }))`, "ZeroDivisionError: division by zero"},
+	})
+}
+
+func TestStrings(t *testing.T) {
+	runTests(t, []struct{ input, expected string }{
+		{`format("{} is {} years old", ["Ada", 36])`, "Ada is 36 years old"},
+		{`format("no placeholders", [])`, "no placeholders"},
+		{`format("{}{}", [1, 2])`, "12"},
+		{`format("{} and {}", [1])`, "ArgumentError: format: template has 2 placeholders, got 1 arguments"},
+		{`repeat("ab", 3)`, "ababab"},
+		{`repeat("ab", 0)`, ""},
+		{`pad_left("7", 3)`, "  7"},
+		{`pad_right("7", 3)`, "7  "},
+		{`pad_left("long", 2)`, "long"},
+	})
+}
+
+func TestUserCodeOverridesPrelude(t *testing.T) {
+	runTests(t, []struct{ input, expected string }{
+		{`let map = fn(arr, f) { "mine" }; map([1], fn(x) { x })`, "mine"},
+		// Other prelude functions keep using the prelude's definitions.
+		{`let some = fn(x) { "mine" }; unwrap(find([1], fn(x) { true }))`, "1"},
+		{`let sum = 5; sum`, "5"},
+	})
+
+	if got := testEval(t, `map([1, 2], fn(x) { x + 1 })`).Inspect(); got != "[2, 3]" {
+		t.Errorf("override leaked into another environment. got=%q", got)
+	}
+}
+
+func TestLoadReportsErrors(t *testing.T) {
+	tests := []struct {
+		source   string
+		expected string
+	}{
+		{"let = 1;", "stdlib: could not parse bad.mk:\n\texpected next token to be IDENT, got = instead\n\tno prefix parse function for = found"},
+		{"let x = missing;", "stdlib: bad.mk:1:9: NameError: identifier not found: missing"},
+	}
+
+	for _, tt := range tests {
+		_, err := load(fstest.MapFS{"bad.mk": {Data: []byte(tt.source)}})
+		if err == nil {
+			t.Errorf("%q: expected an error", tt.source)
+			continue
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("%q: expected=%q, got=%q", tt.source, tt.expected, err.Error())
+		}
+	}
+}
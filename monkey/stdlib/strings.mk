@@ -0,0 +1,42 @@
+let format = fn(template, args) {
+  let pieces = split(template, "{}");
+  if (len(pieces) - 1 != len(args)) {
+    throw {
+      "kind": "ArgumentError",
+      "message": join(
+        [
+          "format: template has ",
+          len(pieces) - 1,
+          " placeholders, got ",
+          len(args),
+          " arguments"
+        ],
+        ""
+      )
+    };
+  }
+  let iter = fn(i, acc) {
+    if (i < len(args)) {
+      iter(i + 1, push(push(acc, pieces[i]), args[i]))
+    } else {
+      push(acc, pieces[i])
+    }
+  };
+  join(iter(0, []), "")
+};
+
+let repeat = fn(s, n) {
+  if (n < 1) {
+    ""
+  } else {
+    s + repeat(s, n - 1)
+  }
+};
+
+let pad_left = fn(s, width) {
+  repeat(" ", width - len(s)) + s
+};
+
+let pad_right = fn(s, width) {
+  s + repeat(" ", width - len(s))
+};
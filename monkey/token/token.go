@@ -1,5 +1,7 @@
 package token
 
+import "fmt"
+
 type TokenType string
 
 const (
@@ -9,6 +11,7 @@ const (
 	// Identifiers + literals
 	IDENTIFIER = "IDENT"  // variable names
 	INT        = "INT"    // integers
+	FLOAT      = "FLOAT"  // floating point numbers
 	STRING     = "STRING" // strings
 
 	// Operators
@@ -26,7 +29,17 @@ const (
 	LT = "<"
 	GT = ">"
 
+	PIPE      = "|"
+	AMPERSAND = "&"
+
+	ARROW    = "=>"
+	ELLIPSIS = "..."
+
+	DOTDOT    = ".."
+	DOTDOT_EQ = "..="
+
 	// Delimiters
+	DOT       = "."
 	COMMA     = ","
 	SEMICOLON = ";"
 	COLON     = ":"
@@ -38,6 +51,8 @@ const (
 	LBRACKET = "["
 	RBRACKET = "]"
 
+	SET_LBRACE = "#{"
+
 	// Keywords
 	FUNCTION = "FUNCTION"
 	LET      = "LET"
@@ -47,22 +62,60 @@ const (
 	IF       = "IF"
 	ELSE     = "ELSE"
 	RETURN   = "RETURN"
+	MATCH    = "MATCH"
+	TRY      = "TRY"
+	CATCH    = "CATCH"
+	FINALLY  = "FINALLY"
+	THROW    = "THROW"
+	IMPORT   = "IMPORT"
+	EXPORT   = "EXPORT"
+	MACRO    = "MACRO"
+	FOR      = "FOR"
+	YIELD    = "YIELD"
+	SPAWN    = "SPAWN"
+	AWAIT    = "AWAIT"
 )
 
 type Token struct {
 	Type    TokenType
 	Literal string
+	Pos     Position
+}
+
+// Position is the location of a token in the source. Line and Column are
+// 1-based and Column counts runes, not bytes. File is empty unless the
+// source was read from a file.
+type Position struct {
+	File   string
+	Line   int
+	Column int
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
 }
 
 var keywords = map[string]TokenType{
-	"fn":     FUNCTION,
-	"let":    LET,
-	"true":   TRUE,
-	"false":  FALSE,
-	"null":   NULL,
-	"if":     IF,
-	"else":   ELSE,
-	"return": RETURN,
+	"fn":      FUNCTION,
+	"let":     LET,
+	"true":    TRUE,
+	"false":   FALSE,
+	"null":    NULL,
+	"if":      IF,
+	"else":    ELSE,
+	"return":  RETURN,
+	"match":   MATCH,
+	"try":     TRY,
+	"catch":   CATCH,
+	"finally": FINALLY,
+	"throw":   THROW,
+	"import":  IMPORT,
+	"export":  EXPORT,
+	"macro":   MACRO,
+	"for":     FOR,
+	"yield":   YIELD,
+	"spawn":   SPAWN,
+	"await":   AWAIT,
 }
 
 func LookupIdent(ident string) TokenType {